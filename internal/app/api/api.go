@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 
 	userhandler "dating/internal/app/api/handler/user"
@@ -8,19 +9,68 @@ import (
 	userService "dating/internal/app/api/services/user"
 
 	matchhandler "dating/internal/app/api/handler/match"
+	lock "dating/internal/app/api/repositories/lock"
 	match "dating/internal/app/api/repositories/match"
+	quota "dating/internal/app/api/repositories/quota"
 	matchService "dating/internal/app/api/services/match"
 
+	adminhandler "dating/internal/app/api/handler/admin"
+	admin "dating/internal/app/api/repositories/admin"
+	adminService "dating/internal/app/api/services/admin"
+
+	blockhandler "dating/internal/app/api/handler/block"
+	block "dating/internal/app/api/repositories/block"
+	blockService "dating/internal/app/api/services/block"
+
+	notificationhandler "dating/internal/app/api/handler/notification"
+	notification "dating/internal/app/api/repositories/notification"
+	notificationService "dating/internal/app/api/services/notification"
+
+	interesthandler "dating/internal/app/api/handler/interest"
+	interestService "dating/internal/app/api/services/interest"
+
+	recommendationhandler "dating/internal/app/api/handler/recommendation"
+	recommendation "dating/internal/app/api/repositories/recommendation"
+	recommendationService "dating/internal/app/api/services/recommendation"
+
+	identityhandler "dating/internal/app/api/handler/identity"
+	identity "dating/internal/app/api/repositories/identity"
+	identityService "dating/internal/app/api/services/identity"
+
+	twofactorhandler "dating/internal/app/api/handler/twofactor"
+	twofactor "dating/internal/app/api/repositories/twofactor"
+	twofactorService "dating/internal/app/api/services/twofactor"
+
+	phonehandler "dating/internal/app/api/handler/phone"
+	phone "dating/internal/app/api/repositories/phone"
+	phoneService "dating/internal/app/api/services/phone"
+
+	verificationhandler "dating/internal/app/api/handler/verification"
+	verification "dating/internal/app/api/repositories/verification"
+	verificationService "dating/internal/app/api/services/verification"
+
+	accounthandler "dating/internal/app/api/handler/account"
+	account "dating/internal/app/api/repositories/account"
+	accountService "dating/internal/app/api/services/account"
+
 	messagehandler "dating/internal/app/api/handler/message"
 	message "dating/internal/app/api/repositories/message"
 	messageService "dating/internal/app/api/services/message"
 
 	"dating/internal/app/config"
 	"dating/internal/app/db"
+	"dating/internal/pkg/chatfilter"
 	"dating/internal/pkg/glog"
 	"dating/internal/pkg/health"
+	"dating/internal/pkg/i18n"
 	"dating/internal/pkg/middleware"
+	"dating/internal/pkg/notify"
+	"dating/internal/pkg/oidc"
+	"dating/internal/pkg/ratelimit"
+	"dating/internal/pkg/sms"
+	"dating/internal/pkg/socket"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 )
@@ -32,7 +82,8 @@ type (
 	}
 
 	middlewareFunc = func(http.HandlerFunc, *config.ErrorMessage) http.HandlerFunc
-	route          struct {
+	// route middlewares run from the last to the first, e.g. Auth last runs first
+	route struct {
 		path        string
 		method      string
 		handler     http.HandlerFunc
@@ -49,13 +100,27 @@ const (
 )
 
 // Init init all handlers
-func Init(conns *config.Configs, em config.ErrorMessage) (http.Handler, error) {
+func Init(conns *config.Configs, em config.ErrorMessage, templates *i18n.Catalog) (http.Handler, error) {
 	logger := glog.New()
 
 	var userRepo userService.Repository
 	var matchRepo matchService.Repository
+	var quotaRepo matchService.QuotaStore
+	var lockRepo matchService.Locker
 
 	var messageRepo messageService.Repository
+	var messageCounter chatfilter.MessageCounter
+	var messageFlagger chatfilter.Flagger
+	var notificationRepo notificationService.Repository
+	var blockRepo blockService.Repository
+	var adminRepo adminService.Repository
+	var recommendationRepo recommendationService.Repository
+	var accountRepo accountService.Repository
+	var statusRepo middleware.AccountStatuses
+	var identityRepo identityService.Repository
+	var twoFactorRepo twofactorService.Repository
+	var phoneRepo phoneService.Repository
+	var verificationRepo verificationService.Repository
 
 	switch conns.Database.Type {
 	case db.TypeMongoDB:
@@ -63,26 +128,156 @@ func Init(conns *config.Configs, em config.ErrorMessage) (http.Handler, error) {
 		if err != nil {
 			logger.Panicf("failed to dial to target server, err: %v", err)
 		}
-		userRepo = user.NewMongoRepository(s)
-		matchRepo = match.NewMongoRepository(s)
+		userMongo := user.NewMongoRepository(s)
+		if err := userMongo.EnsureStatus(context.Background()); err != nil {
+			logger.Errorf("failed to ensure user statuses, err: %v", err)
+		}
+		userRepo = userMongo
+		statusRepo = userMongo
+		matchMongo := match.NewMongoRepository(s)
+		if err := matchMongo.EnsurePairKeys(context.Background()); err != nil {
+			logger.Errorf("failed to ensure match pair keys, err: %v", err)
+		}
+		if err := matchMongo.EnsureMessageArchive(context.Background()); err != nil {
+			logger.Errorf("failed to ensure message archive, err: %v", err)
+		}
+		matchRepo = matchMongo
+		quotaRepo = quota.NewMongoRepository(s)
+		lockRepo = lock.NewMongoRepository(s)
 
 		messageRepo = message.NewMongoRepository(s)
+		messageCounter = message.NewMongoRepository(s)
+		messageFlagger = message.NewMongoRepository(s)
+		notificationRepo = notification.NewMongoRepository(s)
+		blockRepo = block.NewMongoRepository(s)
+		adminRepo = admin.NewMongoRepository(s)
+		recommendationRepo = recommendation.NewMongoRepository(s)
+		accountRepo = account.NewMongoRepository(s)
+		identityMongo := identity.NewMongoRepository(s)
+		if err := identityMongo.EnsureIndexes(context.Background()); err != nil {
+			logger.Errorf("failed to ensure identity indexes, err: %v", err)
+		}
+		identityRepo = identityMongo
+		twoFactorRepo = twofactor.NewMongoRepository(s)
+		phoneMongo := phone.NewMongoRepository(s)
+		if err := phoneMongo.EnsureIndexes(context.Background()); err != nil {
+			logger.Errorf("failed to ensure phone indexes, err: %v", err)
+		}
+		phoneRepo = phoneMongo
+		verificationMongo := verification.NewMongoRepository(s)
+		if err := verificationMongo.EnsureIndexes(context.Background()); err != nil {
+			logger.Errorf("failed to ensure verification indexes, err: %v", err)
+		}
+		verificationRepo = verificationMongo
 
 	default:
 		panic("database type not supported: " + conns.Database.Type)
 	}
 
+	var rateLimitStore ratelimit.Store = ratelimit.NewMemory()
+	if conns.RateLimit.Backend == "redis" {
+		rateLimitStore = ratelimit.NewRedis(redis.NewClient(&redis.Options{
+			Addr:     conns.RateLimit.Redis.Address,
+			Password: conns.RateLimit.Redis.Password,
+			DB:       conns.RateLimit.Redis.DB,
+		}), "ratelimit:")
+	}
+	authMW := middleware.Auth(statusRepo, conns.Account.StatusCacheTTL)
+	twoFactorMW := middleware.TwoFactor(conns.TwoFactor)
+	rateLimit := func(name string) middlewareFunc {
+		return middleware.RateLimit(rateLimitStore, name, conns.RateLimit.Routes[name], conns.RateLimit.TrustProxy)
+	}
+
+	chatFilter := conns.ChatFilter
+	messageFilter := chatfilter.NewChain(messageFlagger,
+		chatfilter.NewRateLimit(rateLimitStore, conns.RateLimit.Routes["send_message"]),
+		chatfilter.NewBannedWords(chatFilter.BannedWords, chatfilter.ParseVerdict(chatFilter.BannedWordsAction)),
+		chatfilter.NewContactInfo(messageCounter, chatFilter.ContactInfo.EarlyMessages, chatfilter.ParseVerdict(chatFilter.ContactInfo.Action)),
+	)
+	wsServer := socket.NewWebsocketServer(messageFilter)
+	go wsServer.Run()
+
+	notificationLogger := logger.WithField("package", "notification")
+	channels := []notify.Channel{notify.NewSocket(wsServer)}
+	if conns.Notification.Webhook.URL != "" {
+		channels = append(channels, notify.NewWebhook(conns.Notification.Webhook.URL, conns.Notification.Webhook.Timeout))
+	}
+	if conns.Notification.Push.Enabled {
+		channels = append(channels, notify.NewPush(notificationLogger))
+	}
+	notificationSrv := notificationService.NewService(conns, &em, notificationRepo, templates, notificationLogger, channels...)
+	notificationHandler := notificationhandler.New(conns, &em, notificationSrv, notificationLogger)
+
+	interestLogger := logger.WithField("package", "interest")
+	interestSrv := interestService.NewService(conns, &em, interestLogger)
+	interestHandler := interesthandler.New(conns, &em, interestSrv, interestLogger)
+
 	userLogger := logger.WithField("package", "user")
-	userSrv := userService.NewService(conns, &em, userRepo, userLogger)
+	userSrv := userService.NewService(conns, &em, userRepo, interestSrv, userLogger)
 	userHandler := userhandler.New(conns, &em, userSrv, userLogger)
 
+	identityLogger := logger.WithField("package", "identity")
+	providers := map[string]identityService.Provider{}
+	oidcClient := &http.Client{Timeout: conns.OIDC.Timeout}
+	for name, p := range conns.OIDC.Providers {
+		if p.ClientID == "" {
+			continue
+		}
+		providers[name] = oidc.New(oidc.Config{
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+			ResponseMode: p.ResponseMode,
+		}, oidcClient)
+	}
+	identitySrv := identityService.NewService(conns, &em, identityRepo, providers, identityLogger)
+	identityHandler := identityhandler.New(conns, &em, identitySrv, identityLogger)
+
+	twoFactorLogger := logger.WithField("package", "twofactor")
+	twoFactorSrv := twofactorService.NewService(conns, &em, twoFactorRepo, twoFactorLogger)
+	twoFactorHandler := twofactorhandler.New(conns, &em, twoFactorSrv, twoFactorLogger)
+
+	phoneLogger := logger.WithField("package", "phone")
+	var smsProvider phoneService.SMSProvider = sms.NewLog(phoneLogger)
+	if conns.Phone.Provider == "file" {
+		smsProvider = sms.NewFile(conns.Phone.File)
+	}
+	phoneSrv := phoneService.NewService(conns, &em, phoneRepo, smsProvider, templates, phoneLogger)
+	phoneHandler := phonehandler.New(conns, &em, phoneSrv, phoneLogger)
+
+	verificationLogger := logger.WithField("package", "verification")
+	verificationSrv := verificationService.NewService(conns, &em, verificationRepo, verificationLogger)
+	verificationHandler := verificationhandler.New(conns, &em, verificationSrv, verificationLogger)
+
+	recommendationLogger := logger.WithField("package", "recommendation")
+	recommendationSrv := recommendationService.NewService(conns, &em, recommendationRepo, recommendationLogger)
+	recommendationHandler := recommendationhandler.New(conns, &em, recommendationSrv, recommendationLogger)
+
 	matchLogger := logger.WithField("package", "match")
-	matchSrv := matchService.NewService(conns, &em, matchRepo, matchLogger)
+	matchSrv := matchService.NewService(conns, &em, matchRepo, quotaRepo, notificationSrv, wsServer, matchLogger)
 	matchHandler := matchhandler.New(conns, &em, matchSrv, matchLogger)
+	if conns.Match.Expiry.Enabled {
+		go matchSrv.RunExpiry(context.Background(), lockRepo)
+	}
 
 	messageLogger := logger.WithField("package", "chat")
-	messageSrv := messageService.NewService(conns, &em, messageRepo, messageLogger)
-	messageHandler := messagehandler.New(conns, &em, messageSrv, messageLogger)
+	messageSrv := messageService.NewService(conns, &em, messageRepo, notificationSrv, messageLogger)
+	messageHandler := messagehandler.New(conns, &em, messageSrv, wsServer, messageLogger)
+
+	blockLogger := logger.WithField("package", "block")
+	blockSrv := blockService.NewService(conns, &em, blockRepo, wsServer, blockLogger)
+	blockHandler := blockhandler.New(conns, &em, blockSrv, blockLogger)
+
+	accountLogger := logger.WithField("package", "account")
+	accountSrv := accountService.NewService(conns, &em, accountRepo, wsServer, accountLogger)
+	accountHandler := accounthandler.New(conns, &em, accountSrv, accountLogger)
+	go accountSrv.RunErasure(context.Background(), lockRepo)
+
+	adminLogger := logger.WithField("package", "admin")
+	adminSrv := adminService.NewService(conns, &em, adminRepo, wsServer, notificationSrv, adminLogger)
+	adminHandler := adminhandler.New(conns, &em, adminSrv, adminLogger)
 
 	routes := []route{
 		// infra
@@ -93,56 +288,280 @@ func Init(conns *config.Configs, em config.ErrorMessage) (http.Handler, error) {
 		},
 		// services
 		route{
-			path:    "/signup",
-			method:  post,
-			handler: userHandler.SignUp,
+			path:        "/signup",
+			method:      post,
+			middlewares: []middlewareFunc{rateLimit("signup")},
+			handler:     userHandler.SignUp,
 		},
 		route{
-			path:    "/login",
-			method:  post,
-			handler: userHandler.Login,
+			path:        "/login",
+			method:      post,
+			middlewares: []middlewareFunc{rateLimit("login")},
+			handler:     userHandler.Login,
+		},
+		route{
+			path:        "/login/2fa",
+			method:      post,
+			middlewares: []middlewareFunc{rateLimit("two_factor")},
+			handler:     twoFactorHandler.Login,
+		},
+		route{
+			path:        "/auth/{provider:[a-z0-9_]+}/login",
+			method:      get,
+			middlewares: []middlewareFunc{rateLimit("login")},
+			handler:     identityHandler.Login,
+		},
+		route{
+			path:        "/auth/{provider:[a-z0-9_]+}/callback",
+			method:      get,
+			middlewares: []middlewareFunc{rateLimit("login")},
+			handler:     identityHandler.Callback,
+		},
+		route{
+			path:        "/auth/{provider:[a-z0-9_]+}/callback",
+			method:      post,
+			middlewares: []middlewareFunc{rateLimit("login")},
+			handler:     identityHandler.Callback,
+		},
+		route{
+			path:        "/interests",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     interestHandler.GetInterests,
+		},
+		route{
+			path:        "/recommendations",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     recommendationHandler.GetRecommendations,
+		},
+		route{
+			path:        "/users/me",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     userHandler.GetMe,
+		},
+		route{
+			path:        "/users/me/preferences",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     userHandler.GetPreferences,
+		},
+		route{
+			path:        "/users/me/preferences",
+			method:      put,
+			middlewares: []middlewareFunc{authMW},
+			handler:     userHandler.UpdatePreferences,
+		},
+		route{
+			path:        "/users/me/matches",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     matchHandler.GetMatches,
+		},
+		route{
+			path:        "/users/me/likes",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     matchHandler.GetLikes,
+		},
+		route{
+			path:        "/users/me/export",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     accountHandler.GetExport,
+		},
+		route{
+			path:        "/users/me/export/{id:[a-z0-9-\\-]+}",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     accountHandler.DownloadExport,
+		},
+		route{
+			path:        "/users/me",
+			method:      delete,
+			middlewares: []middlewareFunc{authMW},
+			handler:     accountHandler.DeleteMe,
+		},
+		route{
+			path:        "/users/me/restore",
+			method:      post,
+			middlewares: []middlewareFunc{authMW},
+			handler:     accountHandler.RestoreMe,
+		},
+		route{
+			path:        "/users/me/pause",
+			method:      post,
+			middlewares: []middlewareFunc{authMW},
+			handler:     accountHandler.PauseMe,
+		},
+		route{
+			path:        "/users/me/resume",
+			method:      post,
+			middlewares: []middlewareFunc{authMW},
+			handler:     accountHandler.ResumeMe,
+		},
+		route{
+			path:        "/users/me/2fa",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     twoFactorHandler.GetMe,
+		},
+		route{
+			path:        "/users/me/2fa",
+			method:      post,
+			middlewares: []middlewareFunc{authMW},
+			handler:     twoFactorHandler.Enroll,
+		},
+		route{
+			path:        "/users/me/2fa",
+			method:      delete,
+			middlewares: []middlewareFunc{rateLimit("two_factor"), authMW},
+			handler:     twoFactorHandler.Disable,
+		},
+		route{
+			path:        "/users/me/2fa/confirm",
+			method:      post,
+			middlewares: []middlewareFunc{rateLimit("two_factor"), authMW},
+			handler:     twoFactorHandler.Confirm,
+		},
+		route{
+			path:        "/users/me/2fa/recovery-codes",
+			method:      post,
+			middlewares: []middlewareFunc{rateLimit("two_factor"), authMW},
+			handler:     twoFactorHandler.RegenerateRecoveryCodes,
+		},
+		route{
+			path:        "/users/me/phone",
+			method:      post,
+			middlewares: []middlewareFunc{rateLimit("phone"), authMW},
+			handler:     phoneHandler.SendCode,
+		},
+		route{
+			path:        "/users/me/phone/verify",
+			method:      post,
+			middlewares: []middlewareFunc{authMW},
+			handler:     phoneHandler.Verify,
+		},
+		route{
+			path:        "/users/me/phone",
+			method:      delete,
+			middlewares: []middlewareFunc{authMW},
+			handler:     phoneHandler.RemovePhone,
+		},
+		route{
+			path:        "/users/me/verification",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     verificationHandler.GetMe,
+		},
+		route{
+			path:        "/users/me/verification/prompt",
+			method:      post,
+			middlewares: []middlewareFunc{rateLimit("verification"), authMW},
+			handler:     verificationHandler.Prompt,
+		},
+		route{
+			path:        "/users/me/verification",
+			method:      post,
+			middlewares: []middlewareFunc{authMW},
+			handler:     verificationHandler.Submit,
+		},
+		route{
+			path:        "/users/me/identities",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     identityHandler.GetIdentities,
+		},
+		route{
+			path:        "/users/me/identities/{provider:[a-z0-9_]+}",
+			method:      post,
+			middlewares: []middlewareFunc{authMW},
+			handler:     identityHandler.Link,
+		},
+		route{
+			path:        "/users/me/identities/{provider:[a-z0-9_]+}",
+			method:      delete,
+			middlewares: []middlewareFunc{authMW},
+			handler:     identityHandler.Unlink,
+		},
+		route{
+			path:        "/users/me",
+			method:      patch,
+			middlewares: []middlewareFunc{authMW},
+			handler:     userHandler.PatchMe,
 		},
 		route{
 			path:        "/users/{id:[a-z0-9-\\-]+}",
 			method:      get,
-			middlewares: []middlewareFunc{middleware.Auth},
+			middlewares: []middlewareFunc{authMW},
 			handler:     userHandler.GetUserByID,
 		},
 		route{
 			path:        "/users",
 			method:      put,
-			middlewares: []middlewareFunc{middleware.Auth},
+			middlewares: []middlewareFunc{authMW},
 			handler:     userHandler.UpdateUserByID,
 		},
 		route{
 			path:        "/users",
 			method:      get,
-			middlewares: []middlewareFunc{middleware.Auth},
+			middlewares: []middlewareFunc{authMW},
 			handler:     userHandler.GetListUsers,
 		},
 		route{
 			path:        "/matches",
 			method:      post,
-			middlewares: []middlewareFunc{middleware.Auth},
+			middlewares: []middlewareFunc{rateLimit("like"), authMW},
 			handler:     matchHandler.InsertMatch,
 		},
+		route{
+			path:        "/matches/superlikes",
+			method:      post,
+			middlewares: []middlewareFunc{rateLimit("like"), authMW},
+			handler:     matchHandler.InsertSuperLike,
+		},
 		route{
 			path:        "/matches",
 			method:      delete,
-			middlewares: []middlewareFunc{middleware.Auth},
+			middlewares: []middlewareFunc{authMW},
 			handler:     matchHandler.DeleteMatched,
 		},
+		route{
+			path:        "/matches/{id:[a-z0-9-\\-]+}/extend",
+			method:      post,
+			middlewares: []middlewareFunc{authMW},
+			handler:     matchHandler.ExtendMatch,
+		},
 		route{
 			path:        "/users/{id:[a-z0-9-\\-]+}/matches",
 			method:      get,
-			middlewares: []middlewareFunc{middleware.Auth},
+			middlewares: []middlewareFunc{authMW},
 			handler:     userHandler.GetMatchedUsersByID,
 		},
 		route{
 			path:        "/users/{id:[a-z0-9-\\-]+}/disable",
 			method:      patch,
-			middlewares: []middlewareFunc{middleware.Auth},
-			handler:     userHandler.DisableUsersByID,
+			middlewares: []middlewareFunc{twoFactorMW, middleware.Moderator, authMW},
+			handler:     adminHandler.DisableUsersByID,
+		},
+		route{
+			path:        "/users/{id:[a-z0-9-\\-]+}/block",
+			method:      post,
+			middlewares: []middlewareFunc{authMW},
+			handler:     blockHandler.Block,
+		},
+		route{
+			path:        "/users/{id:[a-z0-9-\\-]+}/block",
+			method:      delete,
+			middlewares: []middlewareFunc{authMW},
+			handler:     blockHandler.Unblock,
+		},
+		route{
+			path:        "/users/{id:[a-z0-9-\\-]+}/report",
+			method:      post,
+			middlewares: []middlewareFunc{authMW},
+			handler:     blockHandler.Report,
 		},
 		route{
 			path:    "/ws",
@@ -152,21 +571,107 @@ func Init(conns *config.Configs, em config.ErrorMessage) (http.Handler, error) {
 		route{
 			path:        "/matches/{id:[a-z0-9-\\-]+}",
 			method:      get,
-			middlewares: []middlewareFunc{middleware.Auth},
+			middlewares: []middlewareFunc{authMW},
 			handler:     matchHandler.GetRoomsByUserId,
 		},
 		route{
 			path:        "/messages/{id:[a-z0-9-\\-]+}",
 			method:      get,
-			middlewares: []middlewareFunc{middleware.Auth},
+			middlewares: []middlewareFunc{authMW},
 			handler:     messageHandler.GetMessagesByIdRoom,
 		},
+		route{
+			path:        "/notifications",
+			method:      get,
+			middlewares: []middlewareFunc{authMW},
+			handler:     notificationHandler.GetNotifications,
+		},
+		route{
+			path:        "/notifications/read",
+			method:      patch,
+			middlewares: []middlewareFunc{authMW},
+			handler:     notificationHandler.MarkAllRead,
+		},
+		route{
+			path:        "/notifications/{id:[a-z0-9-\\-]+}/read",
+			method:      patch,
+			middlewares: []middlewareFunc{authMW},
+			handler:     notificationHandler.MarkRead,
+		},
+	}
+
+	// admin routes are mounted under /admin, they are for moderators and admins only, logged in
+	// with a second factor when their role enforces it
+	adminRoutes := []route{
+		route{
+			path:    "/reports",
+			method:  get,
+			handler: adminHandler.GetReports,
+		},
+		route{
+			path:    "/reports/{id:[a-z0-9-\\-]+}",
+			method:  patch,
+			handler: adminHandler.UpdateReportStatus,
+		},
+		route{
+			path:    "/users/{id:[a-z0-9-\\-]+}",
+			method:  get,
+			handler: adminHandler.GetUser,
+		},
+		route{
+			path:    "/users/{id:[a-z0-9-\\-]+}/disable",
+			method:  post,
+			handler: adminHandler.DisableUser,
+		},
+		route{
+			path:    "/users/{id:[a-z0-9-\\-]+}/enable",
+			method:  post,
+			handler: adminHandler.EnableUser,
+		},
+		route{
+			path:        "/users/{id:[a-z0-9-\\-]+}/ban",
+			method:      post,
+			middlewares: []middlewareFunc{middleware.Admin},
+			handler:     adminHandler.BanUser,
+		},
+		route{
+			path:        "/users/{id:[a-z0-9-\\-]+}/role",
+			method:      patch,
+			middlewares: []middlewareFunc{middleware.Admin},
+			handler:     adminHandler.SetRole,
+		},
+		route{
+			path:    "/users/{id:[a-z0-9-\\-]+}/media",
+			method:  delete,
+			handler: adminHandler.DeleteMedia,
+		},
+		route{
+			path:    "/messages/{id:[a-z0-9-\\-]+}",
+			method:  delete,
+			handler: adminHandler.DeleteMessage,
+		},
+		route{
+			path:    "/audit",
+			method:  get,
+			handler: adminHandler.GetAuditLogs,
+		},
+		route{
+			path:    "/verifications",
+			method:  get,
+			handler: adminHandler.GetVerifications,
+		},
+		route{
+			path:    "/verifications/{id:[a-z0-9-\\-]+}",
+			method:  patch,
+			handler: adminHandler.ReviewVerification,
+		},
 	}
 
 	loggingMW := middleware.Logging(logger.WithField("package", "middleware"))
 	r := mux.NewRouter()
 	r.PathPrefix("/swagger").Handler(http.StripPrefix("/swagger", http.FileServer(http.Dir("./swagger-ui/"))))
 	r.Use(middleware.RequestID)
+	r.Use(middleware.Locale)
 	r.Use(middleware.StatusResponseWriter)
 	r.Use(loggingMW)
 	r.Use(handlers.CompressHandler)
@@ -179,5 +684,16 @@ func Init(conns *config.Configs, em config.ErrorMessage) (http.Handler, error) {
 		r.Path(rt.path).Methods(rt.method).HandlerFunc(h)
 	}
 
+	adminRouter := r.PathPrefix("/admin").Subrouter()
+	for _, rt := range adminRoutes {
+		h := rt.handler
+		mdws := append([]middlewareFunc{}, rt.middlewares...)
+		mdws = append(mdws, twoFactorMW, middleware.Moderator, authMW)
+		for _, mdw := range mdws {
+			h = mdw(h, &em)
+		}
+		adminRouter.Path(rt.path).Methods(rt.method).HandlerFunc(h)
+	}
+
 	return r, nil
 }
@@ -0,0 +1,122 @@
+package accounthandler
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/auth"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/respond"
+
+	"github.com/gorilla/mux"
+)
+
+type (
+	service interface {
+		GetExport(ctx context.Context, idUser string) (*types.Export, error)
+		OpenExport(ctx context.Context, idUser, id string) (io.ReadCloser, error)
+		DeleteAccount(ctx context.Context, idUser string) (*types.DeleteAccountResponse, error)
+		RestoreAccount(ctx context.Context, idUser string) error
+		PauseAccount(ctx context.Context, idUser string) error
+		ResumeAccount(ctx context.Context, idUser string) error
+	}
+	// Handler is account web handler
+	Handler struct {
+		conf   *config.Configs
+		em     *config.ErrorMessage
+		srv    service
+		logger glog.Logger
+	}
+)
+
+// New returns new res api account handler
+func New(c *config.Configs, e *config.ErrorMessage, s service, l glog.Logger) *Handler {
+	return &Handler{
+		conf:   c,
+		em:     e,
+		srv:    s,
+		logger: l,
+	}
+}
+
+// Get handler the export of the data of the caller, started when needed HTTP request
+func (h *Handler) GetExport(w http.ResponseWriter, r *http.Request) {
+
+	export, err := h.srv.GetExport(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+	if export.Status != types.ExportReady {
+		respond.JSON(w, http.StatusAccepted, export)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, export)
+}
+
+// Get handler download the archive of an export HTTP request
+func (h *Handler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+
+	id := mux.Vars(r)["id"]
+	archive, err := h.srv.OpenExport(r.Context(), auth.UserIDFromContext(r.Context()), id)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="export-`+id+`.zip"`)
+	if _, err := io.Copy(w, archive); err != nil {
+		h.logger.Errorf("Failed when send archive of export %s %v", id, err)
+	}
+}
+
+// Delete handler request the deletion of the caller account HTTP request
+func (h *Handler) DeleteMe(w http.ResponseWriter, r *http.Request) {
+
+	res, err := h.srv.DeleteAccount(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusAccepted, res)
+}
+
+// Post handler cancel the requested deletion of the caller account HTTP request
+func (h *Handler) RestoreMe(w http.ResponseWriter, r *http.Request) {
+
+	err := h.srv.RestoreAccount(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Post handler pause the caller account, hidden from discovery but still chatting HTTP request
+func (h *Handler) PauseMe(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, h.srv.PauseAccount)
+}
+
+// Post handler resume the paused caller account HTTP request
+func (h *Handler) ResumeMe(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, h.srv.ResumeAccount)
+}
+
+func (h *Handler) setStatus(w http.ResponseWriter, r *http.Request, set func(ctx context.Context, idUser string) error) {
+
+	err := set(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
@@ -0,0 +1,278 @@
+package adminhandler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/auth"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/respond"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type (
+	service interface {
+		GetReports(ctx context.Context, status, page, size string) ([]types.Report, error)
+		UpdateReportStatus(ctx context.Context, idActor, id string, req types.ReportStatusRequest) error
+		GetUser(ctx context.Context, id string) (*types.AdminUserResponse, error)
+		DisableUser(ctx context.Context, idActor, id string, req types.ModerationRequest) error
+		BanUser(ctx context.Context, idActor, id string, req types.ReasonRequest) error
+		EnableUser(ctx context.Context, idActor, id string, req types.ReasonRequest) error
+		SetRole(ctx context.Context, idActor, id string, req types.RoleRequest) error
+		DeleteMessage(ctx context.Context, idActor, id string, req types.ReasonRequest) error
+		DeleteMedia(ctx context.Context, idActor, id string, req types.DeleteMediaRequest) error
+		GetAuditLogs(ctx context.Context, idTarget, page, size string) ([]types.AuditLog, error)
+		GetVerifications(ctx context.Context, status, page, size string) ([]types.VerificationRequest, error)
+		ReviewVerification(ctx context.Context, idActor, id string, req types.VerificationReviewRequest) error
+	}
+	// Handler is admin web handler
+	Handler struct {
+		conf   *config.Configs
+		em     *config.ErrorMessage
+		srv    service
+		logger glog.Logger
+	}
+)
+
+var (
+	validate = validator.New()
+)
+
+// New returns new res api admin handler
+func New(c *config.Configs, e *config.ErrorMessage, s service, l glog.Logger) *Handler {
+	return &Handler{
+		conf:   c,
+		em:     e,
+		srv:    s,
+		logger: l,
+	}
+}
+
+// decode decodes and validates the body, it responds and returns false on failure
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		h.logger.Errorf("Failed when NewDecoder %T %v", v, err)
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return false
+	}
+	if err := validate.Struct(v); err != nil {
+		h.logger.Errorf("Failed when validate field %T %v", v, err)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return false
+	}
+	return true
+}
+
+// Get handler get reports
+func (h *Handler) GetReports(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	reports, err := h.srv.GetReports(r.Context(), query.Get("status"), query.Get("page"), query.Get("size"))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, reports)
+}
+
+// Patch handler update status of a report
+func (h *Handler) UpdateReportStatus(w http.ResponseWriter, r *http.Request) {
+
+	var req types.ReportStatusRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	if err := h.srv.UpdateReportStatus(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"], req); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Get handler get full record and recent messages of a user
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+
+	user, err := h.srv.GetUser(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, user)
+}
+
+// Post handler disable a user with a reason and an optional expiry
+func (h *Handler) DisableUser(w http.ResponseWriter, r *http.Request) {
+
+	var req types.ModerationRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	if err := h.srv.DisableUser(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"], req); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Post handler ban a user
+func (h *Handler) BanUser(w http.ResponseWriter, r *http.Request) {
+
+	var req types.ReasonRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	if err := h.srv.BanUser(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"], req); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Post handler enable a disabled or banned user
+func (h *Handler) EnableUser(w http.ResponseWriter, r *http.Request) {
+
+	var req types.ReasonRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	if err := h.srv.EnableUser(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"], req); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Patch handler Enable/Disable account, kept for clients of PATCH /users/{id}/disable
+func (h *Handler) DisableUsersByID(w http.ResponseWriter, r *http.Request) {
+
+	var disable types.DisableBody
+	if !h.decode(w, r, &disable) {
+		return
+	}
+
+	reason := disable.Reason
+	if reason == "" {
+		reason = "no reason given"
+	}
+
+	idActor, id := auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"]
+	var err error
+	if *disable.Disable {
+		err = h.srv.DisableUser(r.Context(), idActor, id, types.ModerationRequest{Reason: reason})
+	} else {
+		err = h.srv.EnableUser(r.Context(), idActor, id, types.ReasonRequest{Reason: reason})
+	}
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Patch handler set role of a user
+func (h *Handler) SetRole(w http.ResponseWriter, r *http.Request) {
+
+	var req types.RoleRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	if err := h.srv.SetRole(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"], req); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Delete handler delete a message
+func (h *Handler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+
+	var req types.ReasonRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	if err := h.srv.DeleteMessage(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"], req); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Delete handler delete a media of a user
+func (h *Handler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
+
+	var req types.DeleteMediaRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	if err := h.srv.DeleteMedia(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"], req); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Get handler get the audit log
+func (h *Handler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	logs, err := h.srv.GetAuditLogs(r.Context(), query.Get("target_id"), query.Get("page"), query.Get("size"))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, logs)
+}
+
+// Get handler get photo verification requests, the queue is status=pending
+func (h *Handler) GetVerifications(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	verifications, err := h.srv.GetVerifications(r.Context(), query.Get("status"), query.Get("page"), query.Get("size"))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, verifications)
+}
+
+// Patch handler approve or reject a photo verification request
+func (h *Handler) ReviewVerification(w http.ResponseWriter, r *http.Request) {
+
+	var req types.VerificationReviewRequest
+	if !h.decode(w, r, &req) {
+		return
+	}
+
+	err := h.srv.ReviewVerification(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"], req)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
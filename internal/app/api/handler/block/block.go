@@ -0,0 +1,93 @@
+package blockhandler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/auth"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/respond"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+)
+
+type (
+	service interface {
+		Block(ctx context.Context, idUser, idTargetUser string) error
+		Unblock(ctx context.Context, idUser, idTargetUser string) error
+		Report(ctx context.Context, idUser, idTargetUser string, req types.ReportRequest) error
+	}
+	// Handler is block web handler
+	Handler struct {
+		conf   *config.Configs
+		em     *config.ErrorMessage
+		srv    service
+		logger glog.Logger
+	}
+)
+
+var (
+	validate = validator.New()
+)
+
+// New returns new res api block handler
+func New(c *config.Configs, e *config.ErrorMessage, s service, l glog.Logger) *Handler {
+	return &Handler{
+		conf:   c,
+		em:     e,
+		srv:    s,
+		logger: l,
+	}
+}
+
+// Post handler block the user by id
+func (h *Handler) Block(w http.ResponseWriter, r *http.Request) {
+
+	if err := h.srv.Block(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"]); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Delete handler unblock the user by id
+func (h *Handler) Unblock(w http.ResponseWriter, r *http.Request) {
+
+	if err := h.srv.Unblock(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"]); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Post handler report the user by id
+func (h *Handler) Report(w http.ResponseWriter, r *http.Request) {
+
+	var report types.ReportRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		h.logger.Errorf("Failed when NewDecoder reportRequest", err)
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return
+	}
+
+	if err := validate.Struct(report); err != nil {
+		h.logger.Errorf("Failed when validate field reportRequest", err)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return
+	}
+
+	if err := h.srv.Report(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"], report); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
@@ -0,0 +1,107 @@
+package identityhandler
+
+import (
+	"context"
+	"net/http"
+
+	identityservices "dating/internal/app/api/services/identity"
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/auth"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/respond"
+
+	"github.com/gorilla/mux"
+)
+
+type (
+	service interface {
+		Start(ctx context.Context, provider, idUser string) (string, error)
+		Callback(ctx context.Context, provider, code, state string) (*types.UserResponseSignUp, error)
+		GetIdentities(ctx context.Context, idUser string) ([]types.Identity, error)
+		Unlink(ctx context.Context, idUser, provider string) error
+	}
+	// Handler is identity web handler
+	Handler struct {
+		conf   *config.Configs
+		em     *config.ErrorMessage
+		srv    service
+		logger glog.Logger
+	}
+)
+
+// New returns new res api identity handler
+func New(c *config.Configs, e *config.ErrorMessage, s service, l glog.Logger) *Handler {
+	return &Handler{
+		conf:   c,
+		em:     e,
+		srv:    s,
+		logger: l,
+	}
+}
+
+// Get handler redirect to the provider to login HTTP request
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+
+	authURL, err := h.srv.Start(r.Context(), mux.Vars(r)["provider"], "")
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Get or Post handler the provider redirecting back after a login HTTP request
+func (h *Handler) Callback(w http.ResponseWriter, r *http.Request) {
+
+	// providers post the callback in form_post mode
+	if providerErr := r.FormValue("error"); providerErr != "" {
+		h.logger.Infof("Login at %s failed %s", mux.Vars(r)["provider"], providerErr)
+		respond.Fail(w, r, h.em, identityservices.ErrInvalidLogin)
+		return
+	}
+
+	user, err := h.srv.Callback(r.Context(), mux.Vars(r)["provider"], r.FormValue("code"), r.FormValue("state"))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, user)
+}
+
+// Get handler the providers linked to the caller HTTP request
+func (h *Handler) GetIdentities(w http.ResponseWriter, r *http.Request) {
+
+	identities, err := h.srv.GetIdentities(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, identities)
+}
+
+// Post handler start linking a provider to the caller HTTP request
+func (h *Handler) Link(w http.ResponseWriter, r *http.Request) {
+
+	authURL, err := h.srv.Start(r.Context(), mux.Vars(r)["provider"], auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, types.AuthURLResponse{URL: authURL})
+}
+
+// Delete handler unlink a provider from the caller HTTP request
+func (h *Handler) Unlink(w http.ResponseWriter, r *http.Request) {
+
+	if err := h.srv.Unlink(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["provider"]); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
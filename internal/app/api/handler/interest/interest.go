@@ -0,0 +1,46 @@
+package interesthandler
+
+import (
+	"context"
+	"net/http"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/i18n"
+	"dating/internal/pkg/respond"
+)
+
+type (
+	service interface {
+		GetInterests(ctx context.Context, locales []string) []types.Interest
+	}
+	// Handler is interest web handler
+	Handler struct {
+		conf   *config.Configs
+		em     *config.ErrorMessage
+		srv    service
+		logger glog.Logger
+	}
+)
+
+// New returns new res api interest handler
+func New(c *config.Configs, e *config.ErrorMessage, s service, l glog.Logger) *Handler {
+	return &Handler{
+		conf:   c,
+		em:     e,
+		srv:    s,
+		logger: l,
+	}
+}
+
+// Get handler get the interests catalogue, labelled in the lang parameter, the saved locale
+// or the Accept-Language languages
+func (h *Handler) GetInterests(w http.ResponseWriter, r *http.Request) {
+
+	locales := i18n.FromContext(r.Context())
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		locales = append([]string{lang}, locales...)
+	}
+	respond.JSON(w, http.StatusOK, h.srv.GetInterests(r.Context(), locales))
+}
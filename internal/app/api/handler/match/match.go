@@ -7,18 +7,25 @@ import (
 
 	"dating/internal/app/api/types"
 	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/auth"
 	"dating/internal/pkg/glog"
 	"dating/internal/pkg/respond"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type (
 	service interface {
 		InsertMatch(ctx context.Context, Match types.MatchRequest) (*types.Match, error)
+		InsertSuperLike(ctx context.Context, Match types.MatchRequest) (*types.Match, error)
 		DeleteMatch(ctx context.Context, matchreq types.MatchRequest) error
 		FindRoomsByUserId(ctx context.Context, id string) ([]types.MatchRoomResponse, error)
+		GetMatches(ctx context.Context, idUser, page, size, sort string) (*types.GetMatchesResponse, error)
+		GetLikes(ctx context.Context, idUser, page, size, sort string) (*types.GetLikesResponse, error)
+		ExtendMatch(ctx context.Context, id, idUser string) (*types.Match, error)
 	}
 	// Handler is match web handler
 	Handler struct {
@@ -31,6 +38,8 @@ type (
 
 var (
 	validate = validator.New()
+
+	errNotCaller = apperr.NewForbidden("", "user_id isn't the logged in user")
 )
 
 // New returns new res api match handler
@@ -50,19 +59,55 @@ func (h *Handler) InsertMatch(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&matchRequest); err != nil {
 		h.logger.Errorf("Failed when NewDecoder matchRequest", err)
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.ValidationFailed)
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
 		return
 	}
 
 	if err := validate.Struct(matchRequest); err != nil {
 		h.logger.Errorf("Failed when validate field matchRequest", err)
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.ValidationFailed)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return
+	}
+
+	if err := fromCaller(r, &matchRequest); err != nil {
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
 	match, err := h.srv.InsertMatch(r.Context(), matchRequest)
 	if err != nil {
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.Request)
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, match)
+}
+
+// Post handler super like HTTP request
+func (h *Handler) InsertSuperLike(w http.ResponseWriter, r *http.Request) {
+
+	var matchRequest types.MatchRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&matchRequest); err != nil {
+		h.logger.Errorf("Failed when NewDecoder matchRequest", err)
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return
+	}
+
+	if err := validate.Struct(matchRequest); err != nil {
+		h.logger.Errorf("Failed when validate field matchRequest", err)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return
+	}
+
+	if err := fromCaller(r, &matchRequest); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	match, err := h.srv.InsertSuperLike(r.Context(), matchRequest)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
@@ -76,19 +121,24 @@ func (h *Handler) DeleteMatched(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&unmatchRequest); err != nil {
 		h.logger.Errorf("Failed when NewDecoder matchRequest", err)
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.ValidationFailed)
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
 		return
 	}
 
 	if err := validate.Struct(unmatchRequest); err != nil {
 		h.logger.Errorf("Failed when validate field matchRequest", err)
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.ValidationFailed)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return
+	}
+
+	if err := fromCaller(r, &unmatchRequest); err != nil {
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
 	err := h.srv.DeleteMatch(r.Context(), unmatchRequest)
 	if err != nil {
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.Request)
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
@@ -98,11 +148,69 @@ func (h *Handler) DeleteMatched(w http.ResponseWriter, r *http.Request) {
 // Put handler get list room by user id
 func (h *Handler) GetRoomsByUserId(w http.ResponseWriter, r *http.Request) {
 
+	// the rooms of a user are theirs only
+	if mux.Vars(r)["id"] != auth.UserIDFromContext(r.Context()) {
+		respond.Fail(w, r, h.em, errNotCaller)
+		return
+	}
+
 	roomList, err := h.srv.FindRoomsByUserId(r.Context(), mux.Vars(r)["id"])
 	if err != nil {
-		respond.JSON(w, http.StatusInternalServerError, h.em.InvalidValue.Request)
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
 	respond.JSON(w, http.StatusOK, roomList)
 }
+
+// Get handler the matches of the caller HTTP request
+func (h *Handler) GetMatches(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	matches, err := h.srv.GetMatches(r.Context(), auth.UserIDFromContext(r.Context()), query.Get("page"), query.Get("size"), query.Get("sort"))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, matches)
+}
+
+// Get handler the users who liked the caller HTTP request
+func (h *Handler) GetLikes(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	likes, err := h.srv.GetLikes(r.Context(), auth.UserIDFromContext(r.Context()), query.Get("page"), query.Get("size"), query.Get("sort"))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, likes)
+}
+
+// Post handler extend a match once before it expires HTTP request
+func (h *Handler) ExtendMatch(w http.ResponseWriter, r *http.Request) {
+
+	match, err := h.srv.ExtendMatch(r.Context(), mux.Vars(r)["id"], auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, match)
+}
+
+// fromCaller sets the user of a request to the logged in user, the user_id of the body is
+// optional and must be the logged in user when sent
+func fromCaller(r *http.Request, matchRequest *types.MatchRequest) error {
+	caller, err := primitive.ObjectIDFromHex(auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		return apperr.New(apperr.Unauthorized, "", err.Error())
+	}
+	if !matchRequest.UserID.IsZero() && matchRequest.UserID != caller {
+		return errNotCaller
+	}
+	matchRequest.UserID = caller
+	return nil
+}
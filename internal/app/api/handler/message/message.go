@@ -6,6 +6,8 @@ import (
 
 	"dating/internal/app/api/types"
 	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/auth"
 	"dating/internal/pkg/glog"
 	"dating/internal/pkg/respond"
 	socket "dating/internal/pkg/socket"
@@ -16,21 +18,20 @@ import (
 
 type (
 	service interface {
-		ServeWs(wsServer *socket.WsServer, conn *websocket.Conn, idRoom string)
-		GetMessagesByIdRoom(ctx context.Context, id string) ([]types.Message, error)
+		ServeWs(wsServer *socket.WsServer, conn *websocket.Conn, idUser, idRoom string)
+		GetMessagesByIdRoom(ctx context.Context, idUser, id string) ([]types.Message, error)
 	}
 	// Handler is message web handler
 	Handler struct {
-		conf   *config.Configs
-		em     *config.ErrorMessage
-		srv    service
-		logger glog.Logger
+		conf     *config.Configs
+		em       *config.ErrorMessage
+		srv      service
+		wsServer *socket.WsServer
+		logger   glog.Logger
 	}
 )
 
 var (
-	wsServer = socket.NewWebsocketServer()
-
 	socketBufferSize  = 2048
 	messageBufferSize = 256
 
@@ -43,22 +44,36 @@ var (
 	}
 )
 
-// New returns new res api message handler
-func New(c *config.Configs, e *config.ErrorMessage, s service, l glog.Logger) *Handler {
-
-	go wsServer.Run()
-
+// New returns new res api message handler, ws is the running socket hub
+func New(c *config.Configs, e *config.ErrorMessage, s service, ws *socket.WsServer, l glog.Logger) *Handler {
 	return &Handler{
-		conf:   c,
-		em:     e,
-		srv:    s,
-		logger: l,
+		conf:     c,
+		em:       e,
+		srv:      s,
+		wsServer: ws,
+		logger:   l,
 	}
 }
 
 // Put handler server message socket HTTP request
 func (h *Handler) ServeWs(w http.ResponseWriter, r *http.Request) {
 	idRoom := r.URL.Query().Get("id")
+
+	// browsers can't set headers on websocket, token is passed as a query parameter
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		h.logger.Infof("The socket request does not contain token")
+		respond.Fail(w, r, h.em, apperr.New(apperr.Unauthorized, "", "not authorized"))
+		return
+	}
+	claims, err := auth.IsAuthorized(token)
+	if err != nil {
+		h.logger.Errorf("Not authorized, error: %v", err)
+		respond.Fail(w, r, h.em, apperr.New(apperr.Unauthorized, "", err.Error()))
+		return
+	}
+	idUser := auth.UserID(claims)
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 
 	if err != nil {
@@ -66,7 +81,7 @@ func (h *Handler) ServeWs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.srv.ServeWs(wsServer, conn, idRoom)
+	h.srv.ServeWs(h.wsServer, conn, idUser, idRoom)
 
 	h.logger.Infof("New Client joined the room!" + idRoom)
 }
@@ -74,9 +89,9 @@ func (h *Handler) ServeWs(w http.ResponseWriter, r *http.Request) {
 // Put handler get list message of room
 func (h *Handler) GetMessagesByIdRoom(w http.ResponseWriter, r *http.Request) {
 
-	messagesList, err := h.srv.GetMessagesByIdRoom(r.Context(), mux.Vars(r)["id"])
+	messagesList, err := h.srv.GetMessagesByIdRoom(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"])
 	if err != nil {
-		respond.JSON(w, http.StatusInternalServerError, h.em.InvalidValue.Request)
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
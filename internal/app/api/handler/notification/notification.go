@@ -0,0 +1,75 @@
+package notificationhandler
+
+import (
+	"context"
+	"net/http"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/auth"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/respond"
+
+	"github.com/gorilla/mux"
+)
+
+type (
+	service interface {
+		GetNotifications(ctx context.Context, idUser, page, size, unread string) (*types.GetListNotificationsResponse, error)
+		MarkRead(ctx context.Context, idUser, id string) error
+		MarkAllRead(ctx context.Context, idUser string) error
+	}
+	// Handler is notification web handler
+	Handler struct {
+		conf   *config.Configs
+		em     *config.ErrorMessage
+		srv    service
+		logger glog.Logger
+	}
+)
+
+// New returns new res api notification handler
+func New(c *config.Configs, e *config.ErrorMessage, s service, l glog.Logger) *Handler {
+	return &Handler{
+		conf:   c,
+		em:     e,
+		srv:    s,
+		logger: l,
+	}
+}
+
+// Get handler get notifications of the logged in user
+func (h *Handler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	list, err := h.srv.GetNotifications(r.Context(), auth.UserIDFromContext(r.Context()),
+		query.Get("page"), query.Get("size"), query.Get("unread"))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, list)
+}
+
+// Patch handler mark a notification of the logged in user as read
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+
+	if err := h.srv.MarkRead(r.Context(), auth.UserIDFromContext(r.Context()), mux.Vars(r)["id"]); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Patch handler mark all notifications of the logged in user as read
+func (h *Handler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+
+	if err := h.srv.MarkAllRead(r.Context(), auth.UserIDFromContext(r.Context())); err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
@@ -0,0 +1,103 @@
+package phonehandler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/auth"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/respond"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type (
+	service interface {
+		SendCode(ctx context.Context, idUser, phone string) (*types.PhoneVerificationResponse, error)
+		Verify(ctx context.Context, idUser, code string) error
+		RemovePhone(ctx context.Context, idUser string) error
+	}
+	// Handler is phone web handler
+	Handler struct {
+		conf   *config.Configs
+		em     *config.ErrorMessage
+		srv    service
+		logger glog.Logger
+	}
+)
+
+var (
+	validate = validator.New()
+)
+
+// New returns new res api phone handler
+func New(c *config.Configs, e *config.ErrorMessage, s service, l glog.Logger) *Handler {
+	return &Handler{
+		conf:   c,
+		em:     e,
+		srv:    s,
+		logger: l,
+	}
+}
+
+// Post handler send a code to the phone the caller registers HTTP request
+func (h *Handler) SendCode(w http.ResponseWriter, r *http.Request) {
+
+	var req types.PhoneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		h.logger.Errorf("Failed when validate field PhoneRequest", err)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return
+	}
+
+	verification, err := h.srv.SendCode(r.Context(), auth.UserIDFromContext(r.Context()), req.Phone)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, verification)
+}
+
+// Post handler verify the phone of the caller with the code sent to it HTTP request
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+
+	var code types.PhoneCode
+	if err := json.NewDecoder(r.Body).Decode(&code); err != nil {
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return
+	}
+	if err := validate.Struct(code); err != nil {
+		h.logger.Errorf("Failed when validate field PhoneCode", err)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return
+	}
+
+	err := h.srv.Verify(r.Context(), auth.UserIDFromContext(r.Context()), code.Code)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Delete handler remove the phone of the caller HTTP request
+func (h *Handler) RemovePhone(w http.ResponseWriter, r *http.Request) {
+
+	err := h.srv.RemovePhone(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
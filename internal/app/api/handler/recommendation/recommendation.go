@@ -0,0 +1,55 @@
+package recommendationhandler
+
+import (
+	"context"
+	"net/http"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/auth"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/respond"
+)
+
+type (
+	service interface {
+		GetRecommendations(ctx context.Context, idUser, page, size, minAge, maxAge, gender, interests string, debug bool) (*types.GetRecommendationsResponse, error)
+	}
+	// Handler is recommendation web handler
+	Handler struct {
+		conf   *config.Configs
+		em     *config.ErrorMessage
+		srv    service
+		logger glog.Logger
+	}
+)
+
+// New returns new res api recommendation handler
+func New(c *config.Configs, e *config.ErrorMessage, s service, l glog.Logger) *Handler {
+	return &Handler{
+		conf:   c,
+		em:     e,
+		srv:    s,
+		logger: l,
+	}
+}
+
+// Get handler get the ranked feed of the logged in user, debug=true adds the score
+// breakdown for moderators and admins, or for everyone when enabled in the configuration
+func (h *Handler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+
+	query := r.URL.Query()
+	role := auth.RoleFromContext(r.Context())
+	debug := query.Get("debug") == "true" &&
+		(h.conf.Recommendation.Debug || role == types.RoleModerator || role == types.RoleAdmin)
+
+	list, err := h.srv.GetRecommendations(r.Context(), auth.UserIDFromContext(r.Context()),
+		query.Get("page"), query.Get("size"), query.Get("minAge"), query.Get("maxAge"),
+		query.Get("gender"), query.Get("interests"), debug)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, list)
+}
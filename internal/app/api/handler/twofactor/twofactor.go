@@ -0,0 +1,161 @@
+package twofactorhandler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/auth"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/respond"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type (
+	service interface {
+		GetStatus(ctx context.Context, idUser string) (*types.TwoFactorStatus, error)
+		Enroll(ctx context.Context, idUser string) (*types.TwoFactorEnrollment, error)
+		Confirm(ctx context.Context, idUser, code string) (*types.RecoveryCodesResponse, error)
+		Disable(ctx context.Context, idUser, code string) error
+		RegenerateRecoveryCodes(ctx context.Context, idUser, code string) (*types.RecoveryCodesResponse, error)
+		Login(ctx context.Context, login types.TwoFactorLogin) (*types.UserResponseSignUp, error)
+	}
+	// Handler is two-factor web handler
+	Handler struct {
+		conf   *config.Configs
+		em     *config.ErrorMessage
+		srv    service
+		logger glog.Logger
+	}
+)
+
+var (
+	validate = validator.New()
+)
+
+// New returns new res api two-factor handler
+func New(c *config.Configs, e *config.ErrorMessage, s service, l glog.Logger) *Handler {
+	return &Handler{
+		conf:   c,
+		em:     e,
+		srv:    s,
+		logger: l,
+	}
+}
+
+// Get handler tell if two-factor authentication of the caller is enabled HTTP request
+func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
+
+	status, err := h.srv.GetStatus(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, status)
+}
+
+// Post handler start the enrollment of the caller HTTP request
+func (h *Handler) Enroll(w http.ResponseWriter, r *http.Request) {
+
+	enrollment, err := h.srv.Enroll(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, enrollment)
+}
+
+// Post handler confirm the enrollment of the caller with a code HTTP request
+func (h *Handler) Confirm(w http.ResponseWriter, r *http.Request) {
+
+	code, ok := h.decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.srv.Confirm(r.Context(), auth.UserIDFromContext(r.Context()), code.Code)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, codes)
+}
+
+// Delete handler disable two-factor authentication of the caller HTTP request
+func (h *Handler) Disable(w http.ResponseWriter, r *http.Request) {
+
+	code, ok := h.decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.srv.Disable(r.Context(), auth.UserIDFromContext(r.Context()), code.Code)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, h.em.Success)
+}
+
+// Post handler replace the recovery codes of the caller HTTP request
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+
+	code, ok := h.decodeCode(w, r)
+	if !ok {
+		return
+	}
+
+	codes, err := h.srv.RegenerateRecoveryCodes(r.Context(), auth.UserIDFromContext(r.Context()), code.Code)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, codes)
+}
+
+// Post handler complete a login waiting for its second factor HTTP request
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+
+	var login types.TwoFactorLogin
+	if err := json.NewDecoder(r.Body).Decode(&login); err != nil {
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return
+	}
+	if err := validate.Struct(login); err != nil {
+		h.logger.Errorf("Failed when validate field TwoFactorLogin", err)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return
+	}
+
+	user, err := h.srv.Login(r.Context(), login)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, user)
+}
+
+// decodeCode decodes the code of the body, it responds when it isn't valid
+func (h *Handler) decodeCode(w http.ResponseWriter, r *http.Request) (types.TwoFactorCode, bool) {
+	var code types.TwoFactorCode
+	if err := json.NewDecoder(r.Body).Decode(&code); err != nil {
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return code, false
+	}
+	if err := validate.Struct(code); err != nil {
+		h.logger.Errorf("Failed when validate field TwoFactorCode", err)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return code, false
+	}
+	return code, true
+}
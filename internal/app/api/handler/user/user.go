@@ -1,17 +1,27 @@
 package userhandler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	userservices "dating/internal/app/api/services/user"
 	"dating/internal/app/api/types"
 	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/auth"
 	"dating/internal/pkg/glog"
 	"dating/internal/pkg/respond"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type (
@@ -19,10 +29,13 @@ type (
 		SignUp(ctx context.Context, UserSignUp types.UserSignUp) (*types.UserResponseSignUp, error)
 		Login(ctx context.Context, UserLogin types.UserLogin) (*types.UserResponseSignUp, error)
 		FindUserById(ctx context.Context, id string) (*types.UserResGetInfo, error)
+		FindMe(ctx context.Context, id string) (*types.UserProfile, error)
+		GetPreferences(ctx context.Context, idUser string) (*types.Preferences, error)
+		UpdatePreferences(ctx context.Context, idUser string, preferences types.Preferences) (*types.Preferences, error)
+		PatchMe(ctx context.Context, id string, patch types.UserPatch, fields []string, updatedAt *time.Time) (*types.UserProfile, error)
 		UpdateUserByID(ctx context.Context, User types.User) error
-		GetListUsers(ctx context.Context, page, size, minAge, maxAge, gender string) (*types.GetListUsersResponse, error)
+		GetListUsers(ctx context.Context, idUser string, query types.ListUsersQuery) (*types.GetListUsersResponse, error)
 		GetMatchedUsersByID(ctx context.Context, idUser, matchedParameter string) ([]types.UserResGetInfo, error)
-		DisableUserByID(ctx context.Context, idUser string, disable bool) error
 	}
 	// Handler is user web handler
 	Handler struct {
@@ -53,19 +66,19 @@ func (h *Handler) SignUp(w http.ResponseWriter, r *http.Request) {
 	var userSignup types.UserSignUp
 
 	if err := json.NewDecoder(r.Body).Decode(&userSignup); err != nil {
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.ValidationFailed)
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
 		return
 	}
 
 	if err := validate.Struct(userSignup); err != nil {
 		h.logger.Errorf("Failed when validate field userSignup", err)
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.ValidationFailed)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
 		return
 	}
 
 	user, err := h.srv.SignUp(r.Context(), userSignup)
 	if err != nil {
-		respond.JSON(w, http.StatusConflict, h.em.InvalidValue.EmailExists)
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
@@ -78,19 +91,19 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var UserLogin types.UserLogin
 
 	if err := json.NewDecoder(r.Body).Decode(&UserLogin); err != nil {
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.ValidationFailed)
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
 		return
 	}
 
 	if err := validate.Struct(UserLogin); err != nil {
 		h.logger.Errorf("Failed when validate field UserLogin", err)
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.Request)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
 		return
 	}
 
 	user, err := h.srv.Login(r.Context(), UserLogin)
 	if err != nil {
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.IncorrectPasswordEmail)
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
@@ -102,13 +115,147 @@ func (h *Handler) GetUserByID(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.srv.FindUserById(r.Context(), mux.Vars(r)["id"])
 	if err != nil {
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.Request)
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
 	respond.JSON(w, http.StatusOK, user)
 }
 
+// Get handler get the full profile of the logged in user
+func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
+
+	user, err := h.srv.FindMe(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(user.UpdateAt))
+	respond.JSON(w, http.StatusOK, user)
+}
+
+// Patch handler partially update the logged in user, the body is a JSON Merge Patch,
+// or the fields query parameter is a field mask telling which fields of the body are applied
+func (h *Handler) PatchMe(w http.ResponseWriter, r *http.Request) {
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return
+	}
+
+	// keys of the body, a key with a null value removes the field
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(body, &keys); err != nil {
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return
+	}
+
+	var patch types.UserPatch
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patch); err != nil {
+		h.logger.Errorf("Failed when decode patch %v", err)
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return
+	}
+
+	if err := validate.Struct(patch); err != nil {
+		h.logger.Errorf("Failed when validate field in method PatchMe %v", err)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return
+	}
+
+	fields := []string{}
+	if mask := r.URL.Query().Get("fields"); mask != "" {
+		fields = strings.Split(mask, ",")
+	} else {
+		for key := range keys {
+			fields = append(fields, key)
+		}
+	}
+
+	var updatedAt *time.Time
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" {
+		t, err := parseETag(match)
+		if err != nil {
+			respond.Fail(w, r, h.em, userservices.ErrPreconditionFailed)
+			return
+		}
+		updatedAt = &t
+	}
+
+	user, err := h.srv.PatchMe(r.Context(), auth.UserIDFromContext(r.Context()), patch, fields, updatedAt)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(user.UpdateAt))
+	respond.JSON(w, http.StatusOK, user)
+}
+
+// Get handler get the discovery preferences of the logged in user
+func (h *Handler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+
+	preferences, err := h.srv.GetPreferences(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, preferences)
+}
+
+// Put handler save the discovery preferences of the logged in user
+func (h *Handler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+
+	var preferences types.Preferences
+
+	if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return
+	}
+
+	if err := validate.Struct(preferences); err != nil {
+		h.logger.Errorf("Failed when validate field in method UpdatePreferences %v", err)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return
+	}
+
+	saved, err := h.srv.UpdatePreferences(r.Context(), auth.UserIDFromContext(r.Context()), preferences)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, saved)
+}
+
+// etag returns the entity tag of a user version
+func etag(updatedAt time.Time) string {
+	return strconv.Quote(strconv.FormatInt(updatedAt.UnixNano(), 10))
+}
+
+// parseETag returns the version of an entity tag made by etag, If-Match compares strongly so
+// a weak tag never matches
+func parseETag(tag string) (time.Time, error) {
+	tag = strings.TrimSpace(tag)
+	if strings.HasPrefix(tag, "W/") {
+		return time.Time{}, errors.New("weak entity tag")
+	}
+	version, err := strconv.Unquote(tag)
+	if err != nil {
+		return time.Time{}, err
+	}
+	nsec, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, nsec), nil
+}
+
 // Post handler update information of the user by id
 func (h *Handler) UpdateUserByID(w http.ResponseWriter, r *http.Request) {
 
@@ -116,18 +263,27 @@ func (h *Handler) UpdateUserByID(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
 		h.logger.Errorf("Failed when validate field in method UpdateUserByID", err)
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.Request)
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
 		return
 	}
 
+	// a user can only update itself, whatever id the body carries
+	id, err := primitive.ObjectIDFromHex(auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, apperr.New(apperr.Unauthorized, "", err.Error()))
+		return
+	}
+	user.ID = id
+
 	if err := validate.Struct(user); err != nil {
 		h.logger.Errorf("Failed when validate field in method UpdateUserByID", err)
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.ValidationFailed)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
 		return
 	}
 
-	if error := h.srv.UpdateUserByID(r.Context(), user); error != nil {
-		respond.JSON(w, http.StatusInternalServerError, h.em.InvalidValue.Request)
+	err = h.srv.UpdateUserByID(r.Context(), user)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
@@ -137,15 +293,24 @@ func (h *Handler) UpdateUserByID(w http.ResponseWriter, r *http.Request) {
 // Post handler update information of the user by id
 func (h *Handler) GetListUsers(w http.ResponseWriter, r *http.Request) {
 
-	pageParameter := r.URL.Query().Get("page")
-	sizeParameter := r.URL.Query().Get("size")
-	minAgeParameter := r.URL.Query().Get("minAge")
-	maxAgeParameter := r.URL.Query().Get("maxAge")
-	genderParameter := r.URL.Query().Get("gender")
+	parameters := r.URL.Query()
+	query := types.ListUsersQuery{
+		Page:          parameters.Get("page"),
+		Size:          parameters.Get("size"),
+		MinAge:        parameters.Get("minAge"),
+		MaxAge:        parameters.Get("maxAge"),
+		Gender:        parameters.Get("gender"),
+		Interests:     parameters.Get("interests"),
+		Sort:          parameters.Get("sort"),
+		Cursor:        parameters.Get("cursor"),
+		Count:         parameters.Get("count"),
+		PhoneVerified: parameters.Get("phoneVerified"),
+		Verified:      parameters.Get("verified"),
+	}
 
-	userList, err := h.srv.GetListUsers(r.Context(), pageParameter, sizeParameter, minAgeParameter, maxAgeParameter, genderParameter)
+	userList, err := h.srv.GetListUsers(r.Context(), auth.UserIDFromContext(r.Context()), query)
 	if err != nil {
-		respond.JSON(w, http.StatusInternalServerError, h.em.InvalidValue.Request)
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
@@ -160,32 +325,9 @@ func (h *Handler) GetMatchedUsersByID(w http.ResponseWriter, r *http.Request) {
 
 	list, err := h.srv.GetMatchedUsersByID(r.Context(), userID, matchedParameter)
 	if err != nil {
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.Request)
+		respond.Fail(w, r, h.em, err)
 		return
 	}
 
 	respond.JSON(w, http.StatusOK, list)
 }
-func (h *Handler) DisableUsersByID(w http.ResponseWriter, r *http.Request) {
-
-	userID := mux.Vars(r)["id"]
-	var disable types.DisableBody
-
-	if err := json.NewDecoder(r.Body).Decode(&disable); err != nil {
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.ValidationFailed)
-		return
-	}
-
-	if err := validate.Struct(disable); err != nil {
-		h.logger.Errorf("Failed when validate field in method DisableUsersByID", err)
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.ValidationFailed)
-		return
-	}
-
-	if err := h.srv.DisableUserByID(r.Context(), userID, *disable.Disable); err != nil {
-		respond.JSON(w, http.StatusBadRequest, h.em.InvalidValue.Request)
-		return
-	}
-
-	respond.JSON(w, http.StatusOK, h.em.Success)
-}
@@ -0,0 +1,92 @@
+package verificationhandler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/auth"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/respond"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type (
+	service interface {
+		GetStatus(ctx context.Context, idUser string) (*types.VerificationStatus, error)
+		Prompt(ctx context.Context, idUser string) (*types.VerificationPromptResponse, error)
+		Submit(ctx context.Context, idUser string, req types.VerificationSubmit) (*types.VerificationRequest, error)
+	}
+	// Handler is verification web handler
+	Handler struct {
+		conf   *config.Configs
+		em     *config.ErrorMessage
+		srv    service
+		logger glog.Logger
+	}
+)
+
+var (
+	validate = validator.New()
+)
+
+// New returns new res api verification handler
+func New(c *config.Configs, e *config.ErrorMessage, s service, l glog.Logger) *Handler {
+	return &Handler{
+		conf:   c,
+		em:     e,
+		srv:    s,
+		logger: l,
+	}
+}
+
+// Get handler tell if the photo of the caller is verified HTTP request
+func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
+
+	status, err := h.srv.GetStatus(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, status)
+}
+
+// Post handler give a pose to the caller for the selfie HTTP request
+func (h *Handler) Prompt(w http.ResponseWriter, r *http.Request) {
+
+	prompt, err := h.srv.Prompt(r.Context(), auth.UserIDFromContext(r.Context()))
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, prompt)
+}
+
+// Post handler send the selfie of the caller for review HTTP request
+func (h *Handler) Submit(w http.ResponseWriter, r *http.Request) {
+
+	var req types.VerificationSubmit
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Fail(w, r, h.em, apperr.NewValidation("", err.Error()))
+		return
+	}
+	if err := validate.Struct(req); err != nil {
+		h.logger.Errorf("Failed when validate field VerificationSubmit", err)
+		respond.Fail(w, r, h.em, apperr.FromValidator(err))
+		return
+	}
+
+	request, err := h.srv.Submit(r.Context(), auth.UserIDFromContext(r.Context()), req)
+	if err != nil {
+		respond.Fail(w, r, h.em, err)
+		return
+	}
+
+	respond.JSON(w, http.StatusOK, request)
+}
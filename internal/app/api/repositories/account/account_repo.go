@@ -0,0 +1,312 @@
+package account
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"dating/internal/app/api/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	client *mongo.Client
+}
+
+func NewMongoRepository(c *mongo.Client) *MongoRepository {
+	return &MongoRepository{
+		client: c,
+	}
+}
+
+// This method helps insert an export job
+func (r *MongoRepository) InsertExport(ctx context.Context, export types.Export) error {
+	_, err := r.exports().InsertOne(ctx, export)
+	return err
+}
+
+// This method helps find the latest export of a user
+func (r *MongoRepository) FindLatestExport(ctx context.Context, idUser primitive.ObjectID) (*types.Export, error) {
+	var export *types.Export
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	err := r.exports().FindOne(ctx, bson.M{"user_id": idUser}, opts).Decode(&export)
+	return export, err
+}
+
+// This method helps find an export of a user by id
+func (r *MongoRepository) FindExport(ctx context.Context, idUser, id primitive.ObjectID) (*types.Export, error) {
+	var export *types.Export
+	err := r.exports().FindOne(ctx, bson.M{"_id": id, "user_id": idUser}).Decode(&export)
+	return export, err
+}
+
+// This method helps set the status of an export
+func (r *MongoRepository) UpdateExportStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	_, err := r.exports().UpdateByID(ctx, id, bson.M{"$set": bson.M{"status": status}})
+	return err
+}
+
+// This method helps store the archive of an export
+func (r *MongoRepository) UploadArchive(ctx context.Context, id primitive.ObjectID, archive io.Reader) error {
+	bucket, err := r.archives()
+	if err != nil {
+		return err
+	}
+	return bucket.UploadFromStreamWithID(id, id.Hex()+".zip", archive)
+}
+
+// This method helps read the archive of an export
+func (r *MongoRepository) OpenArchive(ctx context.Context, id primitive.ObjectID) (io.ReadCloser, error) {
+	bucket, err := r.archives()
+	if err != nil {
+		return nil, err
+	}
+	return bucket.OpenDownloadStream(id)
+}
+
+// This method helps delete the exports expired before now with their archives
+func (r *MongoRepository) DeleteExpiredExports(ctx context.Context, now time.Time) (int, error) {
+	return r.deleteExports(ctx, bson.M{"expires_at": bson.M{"$lte": now}})
+}
+
+func (r *MongoRepository) deleteExports(ctx context.Context, filter bson.M) (int, error) {
+	ids, err := r.exports().Distinct(ctx, "_id", filter)
+	if err != nil {
+		return 0, err
+	}
+	bucket, err := r.archives()
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		// failed and pending exports have no archive
+		if err := bucket.Delete(id); err != nil && err != gridfs.ErrFileNotFound {
+			return 0, err
+		}
+	}
+	_, err = r.exports().DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return len(ids), err
+}
+
+// This method helps gather the data of a user for an export
+func (r *MongoRepository) FindExportData(ctx context.Context, idUser primitive.ObjectID) (*types.ExportData, error) {
+	data := types.ExportData{}
+	if err := r.users().FindOne(ctx, bson.M{"_id": idUser}).Decode(&data.Profile); err != nil {
+		return nil, err
+	}
+	if err := r.findAll(ctx, r.matches(), bson.M{"user_id": idUser, "matched": false}, &data.Likes); err != nil {
+		return nil, err
+	}
+	matched := pairsOf(idUser)
+	matched["matched"] = true
+	if err := r.findAll(ctx, r.matches(), matched, &data.Matches); err != nil {
+		return nil, err
+	}
+	if err := r.findAll(ctx, r.messages(), bson.M{"sender_id": idUser}, &data.Messages); err != nil {
+		return nil, err
+	}
+	if err := r.findAll(ctx, r.verifications(), bson.M{"user_id": idUser}, &data.Verifications); err != nil {
+		return nil, err
+	}
+	if err := r.findOne(ctx, r.verificationPrompts(), idUser, &data.VerificationPrompt); err != nil {
+		return nil, err
+	}
+	if err := r.findOne(ctx, r.phoneVerifications(), idUser, &data.PhoneVerification); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// findOne decodes the document of a user keyed by their id, result is left nil when there is none
+func (r *MongoRepository) findOne(ctx context.Context, collection *mongo.Collection, idUser primitive.ObjectID, result interface{}) error {
+	err := collection.FindOne(ctx, bson.M{"_id": idUser}).Decode(result)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	return err
+}
+
+func (r *MongoRepository) findAll(ctx context.Context, collection *mongo.Collection, filter bson.M, results interface{}) error {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+// This method helps request the deletion of a user at deleteAt, it returns when the user
+// is deleted, the time requested first when the deletion was requested before
+func (r *MongoRepository) ScheduleDeletion(ctx context.Context, idUser primitive.ObjectID, deleteAt time.Time) (time.Time, error) {
+	filter := bson.M{"_id": idUser, "delete_at": bson.M{"$exists": false}}
+	// the status is kept to be restored if the deletion is cancelled
+	update := []bson.M{{"$set": bson.M{
+		"delete_at":              deleteAt,
+		"status_before_deletion": "$status",
+		"status":                 types.StatusPendingDeletion,
+	}}}
+	if _, err := r.users().UpdateOne(ctx, filter, update); err != nil {
+		return deleteAt, err
+	}
+	var user struct {
+		DeleteAt time.Time `bson:"delete_at"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"delete_at": 1})
+	err := r.users().FindOne(ctx, bson.M{"_id": idUser}, opts).Decode(&user)
+	return user.DeleteAt, err
+}
+
+// This method helps cancel the requested deletion of a user, the status they had before is
+// restored, e.g. paused. It returns false when none was requested
+func (r *MongoRepository) CancelDeletion(ctx context.Context, idUser primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": idUser, "status": types.StatusPendingDeletion}
+	update := []bson.M{
+		{"$set": bson.M{"status": bson.M{"$ifNull": []interface{}{"$status_before_deletion", types.StatusActive}}}},
+		{"$unset": []string{"delete_at", "status_before_deletion"}},
+	}
+	result, err := r.users().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// This method helps move a user from the status from to the status to, it returns false
+// when the user has neither
+func (r *MongoRepository) SetStatus(ctx context.Context, idUser primitive.ObjectID, from, to string) (bool, error) {
+	filter := bson.M{"_id": idUser, "status": bson.M{"$in": []string{from, to}}}
+	result, err := r.users().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"status": to}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// This method helps find the users whose deletion is due
+func (r *MongoRepository) FindDueDeletions(ctx context.Context, now time.Time, limit int) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(int64(limit))
+	cursor, err := r.users().Find(ctx, bson.M{"delete_at": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var users []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+// This method helps erase a user, the messages of their rooms and the messages they sent, their
+// likes and matches, notifications, exports, quotas and photo and phone verifications are
+// deleted, others forget blocking them.
+// The user is deleted last so an erasure that failed midway is done again. It returns the rooms
+// of the deleted matches.
+func (r *MongoRepository) Erase(ctx context.Context, idUser primitive.ObjectID) ([]primitive.ObjectID, error) {
+	rooms, err := r.matches().Distinct(ctx, "_id", pairsOf(idUser))
+	if err != nil {
+		return nil, err
+	}
+	messages := bson.M{"$or": []interface{}{
+		bson.M{"room_id": bson.M{"$in": rooms}},
+		bson.M{"sender_id": idUser},
+	}}
+	if _, err := r.messages().DeleteMany(ctx, messages); err != nil {
+		return nil, err
+	}
+	if _, err := r.client.Database("dating").Collection("message_archive").DeleteMany(ctx, messages); err != nil {
+		return nil, err
+	}
+	if _, err := r.matches().DeleteMany(ctx, pairsOf(idUser)); err != nil {
+		return nil, err
+	}
+	notifications := bson.M{"$or": []interface{}{
+		bson.M{"user_id": idUser},
+		bson.M{"actor_id": idUser},
+	}}
+	if _, err := r.client.Database("dating").Collection("notifications").DeleteMany(ctx, notifications); err != nil {
+		return nil, err
+	}
+	if _, err := r.deleteExports(ctx, bson.M{"user_id": idUser}); err != nil {
+		return nil, err
+	}
+	if _, err := r.client.Database("dating").Collection("quotas").DeleteMany(ctx, bson.M{"user_id": idUser.Hex()}); err != nil {
+		return nil, err
+	}
+	if _, err := r.verifications().DeleteMany(ctx, bson.M{"user_id": idUser}); err != nil {
+		return nil, err
+	}
+	if _, err := r.verificationPrompts().DeleteOne(ctx, bson.M{"_id": idUser}); err != nil {
+		return nil, err
+	}
+	if _, err := r.phoneVerifications().DeleteOne(ctx, bson.M{"_id": idUser}); err != nil {
+		return nil, err
+	}
+	blocks := bson.M{"$or": []interface{}{
+		bson.M{"blocked_users": idUser},
+		bson.M{"blocked_by": idUser},
+	}}
+	if _, err := r.users().UpdateMany(ctx, blocks, bson.M{"$pull": bson.M{"blocked_users": idUser, "blocked_by": idUser}}); err != nil {
+		return nil, err
+	}
+	if _, err := r.users().DeleteOne(ctx, bson.M{"_id": idUser}); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(rooms))
+	for _, room := range rooms {
+		if id, ok := room.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// pairsOf is the filter of the likes and matches of a user, given or received
+func pairsOf(idUser primitive.ObjectID) bson.M {
+	return bson.M{"$or": []interface{}{
+		bson.M{"user_id": idUser},
+		bson.M{"target_user_id": idUser},
+	}}
+}
+
+func (r *MongoRepository) archives() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(r.client.Database("dating"), options.GridFSBucket().SetName("export_archives"))
+}
+
+func (r *MongoRepository) exports() *mongo.Collection {
+	return r.client.Database("dating").Collection("exports")
+}
+
+func (r *MongoRepository) users() *mongo.Collection {
+	return r.client.Database("dating").Collection("users")
+}
+
+func (r *MongoRepository) matches() *mongo.Collection {
+	return r.client.Database("dating").Collection("matches")
+}
+
+func (r *MongoRepository) messages() *mongo.Collection {
+	return r.client.Database("dating").Collection("message")
+}
+
+func (r *MongoRepository) verifications() *mongo.Collection {
+	return r.client.Database("dating").Collection("verifications")
+}
+
+func (r *MongoRepository) verificationPrompts() *mongo.Collection {
+	return r.client.Database("dating").Collection("verification_prompts")
+}
+
+func (r *MongoRepository) phoneVerifications() *mongo.Collection {
+	return r.client.Database("dating").Collection("phone_verifications")
+}
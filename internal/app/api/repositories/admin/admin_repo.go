@@ -0,0 +1,263 @@
+package admin
+
+import (
+	"context"
+	"time"
+
+	"dating/internal/app/api/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	client *mongo.Client
+}
+
+func NewMongoRepository(c *mongo.Client) *MongoRepository {
+	return &MongoRepository{
+		client: c,
+	}
+}
+
+// This method helps get reports by status, oldest first so they are reviewed in order
+func (r *MongoRepository) FindReports(ctx context.Context, status string, skip, limit int64) ([]*types.Report, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	var result []*types.Report
+	cursor, err := r.database().Collection("reports").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, err
+}
+
+// This method helps update status of a report
+func (r *MongoRepository) UpdateReportStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	result, err := r.database().Collection("reports").UpdateByID(ctx, id, bson.M{
+		"$set": bson.M{"status": status},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// This method helps get the full record of a user, whatever the status of the account
+func (r *MongoRepository) FindUserByID(ctx context.Context, id primitive.ObjectID) (*types.User, error) {
+	var user *types.User
+	err := r.database().Collection("users").FindOne(ctx, bson.M{"_id": id}).Decode(&user)
+	return user, err
+}
+
+// This method helps get the latest messages sent by a user, newest first
+func (r *MongoRepository) FindMessagesBySender(ctx context.Context, idUser primitive.ObjectID, limit int64) ([]*types.Message, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(limit)
+
+	var result []*types.Message
+	cursor, err := r.database().Collection("message").Find(ctx, bson.M{"sender_id": idUser}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, err
+}
+
+// This method helps suspend or ban a user, moderation holds the reason and the expiry
+func (r *MongoRepository) SetModeration(ctx context.Context, id primitive.ObjectID, moderation types.Moderation) error {
+	status := bson.M{"status": types.StatusSuspended, "moderation": moderation}
+	unset := bson.M{}
+	switch {
+	case moderation.Action == types.ModerationBan:
+		status["status"] = types.StatusBanned
+		unset["status_until"] = ""
+	case moderation.Until != nil:
+		status["status_until"] = moderation.Until
+	default:
+		unset["status_until"] = ""
+	}
+	update := bson.M{"$set": status}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	result, err := r.database().Collection("users").UpdateByID(ctx, id, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// This method helps enable a suspended or banned user again, back to pending deletion when
+// the user asked for it, other statuses are kept
+func (r *MongoRepository) ClearModeration(ctx context.Context, id primitive.ObjectID) error {
+	moderated := bson.M{"$in": []interface{}{"$status", []string{types.StatusSuspended, types.StatusBanned}}}
+	result, err := r.database().Collection("users").UpdateByID(ctx, id, []bson.M{
+		{"$set": bson.M{"status": bson.M{"$switch": bson.M{
+			"branches": []bson.M{
+				{"case": bson.M{"$not": []interface{}{moderated}}, "then": "$status"},
+				{"case": bson.M{"$gt": []interface{}{"$delete_at", nil}}, "then": types.StatusPendingDeletion},
+			},
+			"default": types.StatusActive,
+		}}}},
+		{"$unset": []string{"status_until", "moderation"}},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// This method helps set role of a user
+func (r *MongoRepository) SetRole(ctx context.Context, id primitive.ObjectID, role string) error {
+	result, err := r.database().Collection("users").UpdateByID(ctx, id, bson.M{
+		"$set": bson.M{"role": role},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// This method helps delete a message, it returns the deleted message for the audit log
+func (r *MongoRepository) DeleteMessage(ctx context.Context, id primitive.ObjectID) (*types.Message, error) {
+	var message *types.Message
+	err := r.database().Collection("message").FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(&message)
+	return message, err
+}
+
+// This method helps remove a media of a user
+func (r *MongoRepository) PullMedia(ctx context.Context, idUser primitive.ObjectID, url string) error {
+	result, err := r.database().Collection("users").UpdateOne(ctx,
+		bson.M{"_id": idUser, "media": url},
+		bson.M{"$pull": bson.M{"media": url}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// This method helps insert audit log
+func (r *MongoRepository) InsertAuditLog(ctx context.Context, log types.AuditLog) error {
+	_, err := r.database().Collection("audit_logs").InsertOne(ctx, log)
+	return err
+}
+
+// This method helps get audit logs, newest first, optionally of one target
+func (r *MongoRepository) FindAuditLogs(ctx context.Context, idTarget string, skip, limit int64) ([]*types.AuditLog, error) {
+	filter := bson.M{}
+	if idTarget != "" {
+		targetID, err := primitive.ObjectIDFromHex(idTarget)
+		if err != nil {
+			return nil, err
+		}
+		filter["target_id"] = targetID
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	var result []*types.AuditLog
+	cursor, err := r.database().Collection("audit_logs").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, err
+}
+
+// This method helps get photo verification requests by status, oldest first so they are
+// reviewed in order
+func (r *MongoRepository) FindVerifications(ctx context.Context, status string, skip, limit int64) ([]*types.VerificationRequest, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	var result []*types.VerificationRequest
+	cursor, err := r.database().Collection("verifications").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, err
+}
+
+// This method helps review a pending photo verification request, it returns the reviewed
+// request, mongo.ErrNoDocuments when it isn't pending
+func (r *MongoRepository) ReviewVerification(ctx context.Context, id primitive.ObjectID, status, reason string, idReviewer primitive.ObjectID, now time.Time) (*types.VerificationRequest, error) {
+	filter := bson.M{"_id": id, "status": types.VerificationPending}
+	update := bson.M{"$set": bson.M{
+		"status":      status,
+		"reason":      reason,
+		"reviewer_id": idReviewer,
+		"reviewed_at": now,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var request *types.VerificationRequest
+	err := r.database().Collection("verifications").FindOneAndUpdate(ctx, filter, update, opts).Decode(&request)
+	return request, err
+}
+
+// This method helps give the verified badge to a user
+func (r *MongoRepository) SetVerified(ctx context.Context, idUser primitive.ObjectID, now time.Time) error {
+	result, err := r.database().Collection("users").UpdateByID(ctx, idUser, bson.M{"$set": bson.M{
+		"verified":    true,
+		"verified_at": now,
+		"updated_at":  now,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *MongoRepository) database() *mongo.Database {
+	return r.client.Database("dating")
+}
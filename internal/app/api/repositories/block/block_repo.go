@@ -0,0 +1,95 @@
+package block
+
+import (
+	"context"
+
+	"dating/internal/app/api/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoRepository struct {
+	client *mongo.Client
+}
+
+func NewMongoRepository(c *mongo.Client) *MongoRepository {
+	return &MongoRepository{
+		client: c,
+	}
+}
+
+// This method helps store that idUser blocked idTargetUser on both user records
+func (r *MongoRepository) Block(ctx context.Context, idUser, idTargetUser primitive.ObjectID) error {
+	if _, err := r.users().UpdateByID(ctx, idUser, bson.M{
+		"$addToSet": bson.M{"blocked_users": idTargetUser},
+	}); err != nil {
+		return err
+	}
+	result, err := r.users().UpdateByID(ctx, idTargetUser, bson.M{
+		"$addToSet": bson.M{"blocked_by": idUser},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// This method helps remove the block of idUser on idTargetUser
+func (r *MongoRepository) Unblock(ctx context.Context, idUser, idTargetUser primitive.ObjectID) error {
+	if _, err := r.users().UpdateByID(ctx, idUser, bson.M{
+		"$pull": bson.M{"blocked_users": idTargetUser},
+	}); err != nil {
+		return err
+	}
+	_, err := r.users().UpdateByID(ctx, idTargetUser, bson.M{
+		"$pull": bson.M{"blocked_by": idUser},
+	})
+	return err
+}
+
+// This method helps delete every like and match between two users, it returns the deleted
+// matches so their rooms can be closed
+func (r *MongoRepository) DeleteMatchesBetween(ctx context.Context, idUser, idTargetUser primitive.ObjectID) ([]*types.Match, error) {
+	filter := bson.M{
+		"$or": []interface{}{
+			bson.M{
+				"user_id":        idUser,
+				"target_user_id": idTargetUser,
+			},
+			bson.M{
+				"user_id":        idTargetUser,
+				"target_user_id": idUser,
+			},
+		},
+	}
+	var matches []*types.Match
+	cursor, err := r.matches().Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &matches); err != nil {
+		return nil, err
+	}
+
+	_, err = r.matches().DeleteMany(ctx, filter)
+	return matches, err
+}
+
+// This method helps insert report
+func (r *MongoRepository) InsertReport(ctx context.Context, report types.Report) error {
+	_, err := r.client.Database("dating").Collection("reports").InsertOne(ctx, report)
+	return err
+}
+
+func (r *MongoRepository) users() *mongo.Collection {
+	return r.client.Database("dating").Collection("users")
+}
+
+func (r *MongoRepository) matches() *mongo.Collection {
+	return r.client.Database("dating").Collection("matches")
+}
@@ -0,0 +1,119 @@
+package identity
+
+import (
+	"context"
+	"time"
+
+	"dating/internal/app/api/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	client *mongo.Client
+}
+
+func NewMongoRepository(c *mongo.Client) *MongoRepository {
+	return &MongoRepository{
+		client: c,
+	}
+}
+
+// This method helps create the indexes, an identity belongs to one user and states expire
+func (r *MongoRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.users().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"identities.subject": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.states().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// This method helps save a login started at a provider
+func (r *MongoRepository) InsertState(ctx context.Context, state types.OIDCState) error {
+	_, err := r.states().InsertOne(ctx, state)
+	return err
+}
+
+// This method helps take a login started at a provider, a state is taken once before it expires
+func (r *MongoRepository) TakeState(ctx context.Context, id string, now time.Time) (*types.OIDCState, error) {
+	var state *types.OIDCState
+	err := r.states().FindOneAndDelete(ctx, bson.M{"_id": id, "expires_at": bson.M{"$gt": now}}).Decode(&state)
+	return state, err
+}
+
+// This method helps find the user of an identity
+func (r *MongoRepository) FindByIdentity(ctx context.Context, provider, subject string) (*types.User, error) {
+	filter := bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	var user *types.User
+	err := r.users().FindOne(ctx, filter).Decode(&user)
+	return user, err
+}
+
+// This method helps get user with id, whatever the status of the account
+func (r *MongoRepository) FindByID(ctx context.Context, idUser primitive.ObjectID) (*types.User, error) {
+	var user *types.User
+	err := r.users().FindOne(ctx, bson.M{"_id": idUser}).Decode(&user)
+	return user, err
+}
+
+// This method helps get user with email
+func (r *MongoRepository) FindByEmail(ctx context.Context, email string) (*types.User, error) {
+	var user *types.User
+	err := r.users().FindOne(ctx, bson.M{"email": email}).Decode(&user)
+	return user, err
+}
+
+// This method helps insert a user signing up with an identity
+func (r *MongoRepository) Insert(ctx context.Context, user types.User) error {
+	_, err := r.users().InsertOne(ctx, user)
+	return err
+}
+
+// This method helps link an identity to a user, it returns false when the user already has
+// an identity at the provider and a duplicate key error when another user has this one
+func (r *MongoRepository) LinkIdentity(ctx context.Context, idUser primitive.ObjectID, identity types.Identity) (bool, error) {
+	filter := bson.M{"_id": idUser, "identities.provider": bson.M{"$ne": identity.Provider}}
+	result, err := r.users().UpdateOne(ctx, filter, bson.M{"$push": bson.M{"identities": identity}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// This method helps unlink the identity of a user at a provider, it returns false when the
+// user has none or it is the only way left to login, without a password nor another identity
+func (r *MongoRepository) UnlinkIdentity(ctx context.Context, idUser primitive.ObjectID, provider string) (bool, error) {
+	filter := bson.M{
+		"_id":                 idUser,
+		"identities.provider": provider,
+		"$or": []interface{}{
+			bson.M{"password": bson.M{"$nin": []interface{}{"", nil}}},
+			bson.M{"identities.1": bson.M{"$exists": true}},
+		},
+	}
+	result, err := r.users().UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"identities": bson.M{"provider": provider}}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *MongoRepository) users() *mongo.Collection {
+	return r.client.Database("dating").Collection("users")
+}
+
+func (r *MongoRepository) states() *mongo.Collection {
+	return r.client.Database("dating").Collection("oidc_states")
+}
@@ -0,0 +1,65 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	"dating/internal/pkg/uuid"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	client *mongo.Client
+	owner  string // unique per process
+}
+
+func NewMongoRepository(c *mongo.Client) *MongoRepository {
+	return &MongoRepository{
+		client: c,
+		owner:  uuid.New(),
+	}
+}
+
+// This method helps take or renew the lease of a named lock shared by all replicas. It returns
+// false when another process holds an unexpired lease.
+func (r *MongoRepository) Lock(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	// one document per lock, _id is unique so an upsert on a lease held by
+	// another process fails with a duplicate key error
+	filter := bson.M{
+		"_id": name,
+		"$or": []interface{}{
+			bson.M{"owner": r.owner},
+			bson.M{"expires_at": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"owner":      r.owner,
+			"expires_at": now.Add(ttl),
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	_, err := r.collection().UpdateOne(ctx, filter, update, opts)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// This method helps release a lock held by this process
+func (r *MongoRepository) Unlock(ctx context.Context, name string) error {
+	_, err := r.collection().DeleteOne(ctx, bson.M{"_id": name, "owner": r.owner})
+	return err
+}
+
+func (r *MongoRepository) collection() *mongo.Collection {
+	return r.client.Database("dating").Collection("locks")
+}
@@ -29,6 +29,7 @@ func NewMongoRepository(c *mongo.Client) *MongoRepository {
 
 // this method helps insert match
 func (r *MongoRepository) Insert(ctx context.Context, match types.Match) error {
+	match.PairKey = types.PairKey(match.UserID, match.TargetUserID)
 	_, err := r.collection().InsertOne(context.TODO(), match)
 	return err
 }
@@ -53,7 +54,7 @@ func (r *MongoRepository) FindByID(ctx context.Context, id string) (*types.Match
 		return nil, err
 	}
 	var match *types.Match
-	err = r.collection().FindOne(ctx, bson.M{"user_id": objectID}).Decode(&match)
+	err = r.collection().FindOne(ctx, bson.M{"_id": objectID}).Decode(&match)
 	return match, err
 }
 
@@ -78,94 +79,146 @@ func (r *MongoRepository) CheckAB(ctx context.Context, idUser, idTargetUser stri
 	return match, err
 }
 
-// this method help get record when user A liked user B
-func (r *MongoRepository) FindALikeB(ctx context.Context, idUser, idTargetUser string) (*types.Match, error) {
-
-	userID, err := primitive.ObjectIDFromHex(idUser)
+// This method helps end the match of two users, it removes the likes of the pair both ways
+// and returns the match, ErrNoDocuments when they haven't matched
+func (r *MongoRepository) Unmatch(ctx context.Context, idUser, idTargetUser primitive.ObjectID) (*types.Match, error) {
+	var match types.Match
+	err := r.collection().FindOneAndDelete(ctx, bson.M{"pair_key": types.PairKey(idUser, idTargetUser), "matched": true}).Decode(&match)
 	if err != nil {
 		return nil, err
 	}
-	targetUserID, err := primitive.ObjectIDFromHex(idTargetUser)
+	return &match, nil
+}
+
+// This method helps move the messages of a room to the archive, they are deleted at expiresAt
+func (r *MongoRepository) ArchiveMessages(ctx context.Context, idRoom primitive.ObjectID, expiresAt time.Time) error {
+	cursor, err := r.messages().Aggregate(ctx, []bson.M{
+		{"$match": bson.M{"room_id": idRoom}},
+		{"$set": bson.M{"archived_at": time.Now(), "expires_at": expiresAt}},
+		{"$merge": bson.M{"into": "message_archive"}},
+	})
 	if err != nil {
-		return nil, err
-	}
-	filter := bson.M{
-		"user_id":        userID,
-		"target_user_id": targetUserID,
+		return err
 	}
+	cursor.Close(ctx)
+	return r.PurgeMessages(ctx, idRoom)
+}
 
-	var match *types.Match
-	err = r.collection().FindOne(ctx, filter).Decode(&match)
-	return match, err
+// This method helps delete the messages of a room
+func (r *MongoRepository) PurgeMessages(ctx context.Context, idRoom primitive.ObjectID) error {
+	_, err := r.messages().DeleteMany(ctx, bson.M{"room_id": idRoom})
+	return err
+}
 
+// This method helps delete archived messages once expired
+func (r *MongoRepository) EnsureMessageArchive(ctx context.Context) error {
+	_, err := r.client.Database("dating").Collection("message_archive").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return errors.Wrap(err, "create message_archive expires_at index")
 }
-func (r *MongoRepository) FindAMatchB(ctx context.Context, idUser, idTargetUser string) (*types.Match, error) {
 
-	userID, err := primitive.ObjectIDFromHex(idUser)
-	if err != nil {
-		return nil, err
-	}
-	targetUserID, err := primitive.ObjectIDFromHex(idTargetUser)
-	if err != nil {
-		return nil, err
-	}
-	filter := bson.M{
-		"$or": []interface{}{
-			bson.M{
-				"user_id":        userID,
-				"target_user_id": targetUserID,
-			},
-			bson.M{
-				"user_id":        targetUserID,
-				"target_user_id": userID,
+// This method helps record the like of user_id for target_user_id, completing the match when the
+// target liked first. The pair holds a single match whose pair_key is unique, so likes made both
+// ways at the same moment end in exactly one match. matchedNow is true when this like completed it.
+func (r *MongoRepository) Like(ctx context.Context, like types.Match) (*types.Match, bool, error) {
+	key := types.PairKey(like.UserID, like.TargetUserID)
+	for i := 0; i < likeAttempts; i++ {
+		// the own like, inserted unless the target liked first
+		update := bson.M{
+			"$setOnInsert": bson.M{
+				"pair_key":       key,
+				"user_id":        like.UserID,
+				"target_user_id": like.TargetUserID,
+				"matched":        false,
+				"created_at":     like.CreateAt,
 			},
-		},
-		"matched": true,
+		}
+		// a plain like never downgrades an earlier super like
+		if like.SuperLike {
+			update["$set"] = bson.M{"super_like": true}
+		}
+		var match types.Match
+		opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+		err := r.collection().FindOneAndUpdate(ctx, bson.M{"pair_key": key, "user_id": like.UserID}, update, opts).Decode(&match)
+		if err == nil {
+			return &match, false, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, false, err
+		}
+
+		// the target liked first, the like becomes the match
+		update = bson.M{"$set": bson.M{"matched": true, "matched_at": time.Now()}}
+		opts = options.FindOneAndUpdate().SetReturnDocument(options.After)
+		err = r.collection().FindOneAndUpdate(ctx, bson.M{"pair_key": key, "user_id": like.TargetUserID, "matched": false}, update, opts).Decode(&match)
+		if err == nil {
+			return &match, true, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, false, err
+		}
+
+		// matched before
+		err = r.collection().FindOne(ctx, bson.M{"pair_key": key, "matched": true}).Decode(&match)
+		if err == nil {
+			return &match, false, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, false, err
+		}
+		// the like of the target was removed meanwhile, like again
 	}
+	return nil, false, errors.Errorf("like of %s kept conflicting", key)
+}
 
-	var match *types.Match
-	if err := r.collection().FindOne(ctx, filter).Decode(&match); err == nil {
-		return match, err
+// likeAttempts is the number of times a like is retried while the other like of the pair comes and goes
+const likeAttempts = 3
+
+// This method helps key the likes made before pair_key by their pair, merge the pairs liked both
+// ways into one match and make pair_key unique
+func (r *MongoRepository) EnsurePairKeys(ctx context.Context) error {
+	backfill := []bson.M{
+		{"$set": bson.M{"pair_key": bson.M{"$concat": []interface{}{
+			bson.M{"$toString": bson.M{"$min": []string{"$user_id", "$target_user_id"}}},
+			"_",
+			bson.M{"$toString": bson.M{"$max": []string{"$user_id", "$target_user_id"}}},
+		}}}},
+	}
+	if _, err := r.collection().UpdateMany(ctx, bson.M{"pair_key": bson.M{"$exists": false}}, backfill); err != nil {
+		return errors.Wrap(err, "backfill pair_key")
 	}
 
-	return match, err
-}
-
-// this method help get update match true when A,B liked
-func (r *MongoRepository) UpdateMatchByID(ctx context.Context, id string) error {
-	matchID, err := primitive.ObjectIDFromHex(id)
+	// the matched one, or else the first like, is kept
+	cursor, err := r.collection().Aggregate(ctx, []bson.M{
+		{"$sort": bson.D{{Key: "matched", Value: -1}, {Key: "created_at", Value: 1}}},
+		{"$group": bson.M{"_id": "$pair_key", "ids": bson.M{"$push": "$_id"}}},
+		{"$match": bson.M{"ids.1": bson.M{"$exists": true}}},
+	})
 	if err != nil {
-		return err
+		return errors.Wrap(err, "find pairs liked both ways")
 	}
-	update := bson.D{
-		{"$set", bson.D{
-			{"matched", true},
-		}},
+	var pairs []struct {
+		IDs []primitive.ObjectID `bson:"ids"`
 	}
-
-	_, err = r.collection().UpdateByID(ctx, matchID, update)
-	return err
-}
-
-// this method help get Upsert match
-func (r *MongoRepository) UpsertMatch(ctx context.Context, match types.Match) error {
-	filter := bson.M{
-		"user_id":        match.UserID,
-		"target_user_id": match.TargetUserID,
+	if err = cursor.All(ctx, &pairs); err != nil {
+		return errors.Wrap(err, "find pairs liked both ways")
 	}
-	updatedMath := bson.M{
-
-		"$set": bson.M{
-			"user_id":        match.UserID,
-			"target_user_id": match.TargetUserID,
-			"created_at":     time.Now(),
-		},
-		"$setOnInsert": bson.M{"matched": false},
+	for _, pair := range pairs {
+		if _, err := r.collection().UpdateByID(ctx, pair.IDs[0], bson.M{"$set": bson.M{"matched": true}}); err != nil {
+			return errors.Wrap(err, "merge pair liked both ways")
+		}
+		if _, err := r.collection().DeleteMany(ctx, bson.M{"_id": bson.M{"$in": pair.IDs[1:]}}); err != nil {
+			return errors.Wrap(err, "merge pair liked both ways")
+		}
 	}
 
-	opts := options.Update().SetUpsert(true)
-	_, err := r.collection().UpdateOne(ctx, filter, updatedMath, opts)
-	return err
+	_, err = r.collection().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "pair_key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return errors.Wrap(err, "create pair_key index")
 }
 
 // this method help get list like
@@ -243,7 +296,7 @@ func (r *MongoRepository) FindRoomsByUserId(ctx context.Context, idUser string)
 							bson.M{"$eq": []string{"$_id", "$$target_user_id"}}, // Attached campaign is active
 						},
 					},
-					"disable": false,
+					"status": bson.M{"$in": types.ChatStatuses},
 				},
 				},
 				{"$addFields": bson.M{
@@ -284,6 +337,353 @@ func (r *MongoRepository) FindRoomsByUserId(ctx context.Context, idUser string)
 	return result, err
 }
 
+// This method helps check whether one of the users blocked the other
+func (r *MongoRepository) IsBlocked(ctx context.Context, idUser, idTargetUser string) (bool, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return false, err
+	}
+	targetUserID, err := primitive.ObjectIDFromHex(idTargetUser)
+	if err != nil {
+		return false, err
+	}
+	filter := bson.M{
+		"_id": userID,
+		"$or": []interface{}{
+			bson.M{"blocked_users": targetUserID},
+			bson.M{"blocked_by": targetUserID},
+		},
+	}
+	count, err := r.client.Database("dating").Collection("users").CountDocuments(ctx, filter)
+	return count > 0, err
+}
+
+// This method helps get the timezone of a user, empty when the user has not set one
+func (r *MongoRepository) FindUserTimezone(ctx context.Context, idUser string) (string, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return "", err
+	}
+	var user struct {
+		Timezone string `bson:"timezone"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"timezone": 1})
+	err = r.client.Database("dating").Collection("users").FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user)
+	return user.Timezone, err
+}
+
+// This method helps get the gender, the birthday and the preferences of a user
+func (r *MongoRepository) FindCandidate(ctx context.Context, idUser string) (*types.Candidate, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+	var candidate *types.Candidate
+	opts := options.FindOne().SetProjection(bson.M{"gender": 1, "birthday": 1, "preferences": 1})
+	err = r.client.Database("dating").Collection("users").FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&candidate)
+	return candidate, err
+}
+
+// This method helps get a page of the matches of a user with the other user and the last message of each room
+func (r *MongoRepository) ListMatches(ctx context.Context, idUser string, ps types.PagingNSorting) ([]*types.MatchItem, int, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := []bson.M{
+		{"$match": bson.M{
+			"$or": []interface{}{
+				bson.M{"user_id": userID},
+				bson.M{"target_user_id": userID},
+			},
+			"matched": true,
+		}},
+		{"$project": bson.M{
+			"liked_at": "$created_at",
+			// matches made before matched_at was recorded fall back to the like
+			"matched_at": bson.M{"$ifNull": []interface{}{"$matched_at", "$created_at"}},
+			"expires_at": 1,
+			"extended":   1,
+			"stale":      1,
+			"other_id": bson.M{
+				"$cond": []interface{}{
+					bson.M{"$eq": []interface{}{"$user_id", userID}},
+					"$target_user_id", "$user_id"},
+			},
+		}},
+	}
+	query = append(query, withUser("other_id", userID)...)
+	query = append(query,
+		bson.M{"$lookup": bson.M{
+			"from": "message",
+			"let":  bson.M{"room_id": "$_id"},
+			"pipeline": []bson.M{
+				{"$match": bson.M{"$expr": bson.M{"$eq": []string{"$room_id", "$$room_id"}}}},
+				{"$sort": bson.D{{Key: "created_at", Value: -1}}},
+				{"$limit": 1},
+				{"$project": bson.M{
+					"sender_id":  1,
+					"created_at": 1,
+					"content":    bson.M{"$substrCP": []interface{}{"$content", 0, messagePreviewLength}},
+				}},
+			},
+			"as": "last_message",
+		}},
+		bson.M{"$addFields": bson.M{"last_message": bson.M{"$first": "$last_message"}}},
+		pageFacet(listSort(ps.Sort, map[string]string{
+			"matched_at":   "matched_at",
+			"liked_at":     "liked_at",
+			"last_message": "last_message.created_at",
+		}), ps),
+	)
+
+	var result []struct {
+		Items []*types.MatchItem `bson:"items"`
+		Total []struct {
+			Count int `bson:"count"`
+		} `bson:"total"`
+	}
+	cursor, err := r.collection().Aggregate(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, 0, err
+	}
+	if len(result) == 0 || len(result[0].Total) == 0 {
+		return []*types.MatchItem{}, 0, nil
+	}
+	return result[0].Items, result[0].Total[0].Count, nil
+}
+
+// This method helps get a page of the users who liked a user, who has not liked them back
+func (r *MongoRepository) ListLikedMe(ctx context.Context, idUser string, ps types.PagingNSorting) ([]*types.LikeItem, int, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := []bson.M{
+		{"$match": bson.M{
+			"target_user_id": userID,
+			"matched":        false,
+		}},
+		{"$project": bson.M{
+			"user_id":    1,
+			"super_like": 1,
+			"liked_at":   "$created_at",
+		}},
+	}
+	query = append(query, withUser("user_id", userID)...)
+	query = append(query, pageFacet(listSort(ps.Sort, map[string]string{
+		"liked_at":   "liked_at",
+		"super_like": "super_like",
+	}), ps))
+
+	var result []struct {
+		Items []*types.LikeItem `bson:"items"`
+		Total []struct {
+			Count int `bson:"count"`
+		} `bson:"total"`
+	}
+	cursor, err := r.collection().Aggregate(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, 0, err
+	}
+	if len(result) == 0 || len(result[0].Total) == 0 {
+		return []*types.LikeItem{}, 0, nil
+	}
+	return result[0].Items, result[0].Total[0].Count, nil
+}
+
+// This method helps find the matches matched before a time nobody wrote in and not reminded yet
+func (r *MongoRepository) FindUnremindedMatches(ctx context.Context, matchedBefore time.Time, limit int) ([]*types.Match, error) {
+	return r.findSilentMatches(ctx, bson.M{
+		"reminded": bson.M{"$ne": true},
+		"$expr":    matchedBeforeExpr(matchedBefore),
+	}, limit)
+}
+
+// This method helps find the matches nobody wrote in past their deadline, the extended deadline
+// or the match time when not extended
+func (r *MongoRepository) FindExpiredMatches(ctx context.Context, matchedBefore, now time.Time, limit int) ([]*types.Match, error) {
+	return r.findSilentMatches(ctx, expiredFilter(matchedBefore, now), limit)
+}
+
+// This method helps expire a match found by FindExpiredMatches, marked stale or deleted. It
+// returns false when a message was sent or the match was extended in between
+func (r *MongoRepository) ExpireMatch(ctx context.Context, id string, matchedBefore, now time.Time, stale bool) (bool, error) {
+	matchID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	filter := expiredFilter(matchedBefore, now)
+	filter["_id"] = matchID
+	filter["matched"] = true
+	filter["stale"] = bson.M{"$ne": true}
+	filter["messaged"] = bson.M{"$ne": true}
+	if stale {
+		result, err := r.collection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"stale": true}})
+		if err != nil {
+			return false, err
+		}
+		return result.ModifiedCount > 0, nil
+	}
+	result, err := r.collection().DeleteOne(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	return result.DeletedCount > 0, nil
+}
+
+// expiredFilter is the filter of the matches past their deadline
+func expiredFilter(matchedBefore, now time.Time) bson.M {
+	return bson.M{
+		"$or": []interface{}{
+			bson.M{"expires_at": bson.M{"$lte": now}},
+			bson.M{
+				"expires_at": bson.M{"$exists": false},
+				"$expr":      matchedBeforeExpr(matchedBefore),
+			},
+		},
+	}
+}
+
+// findSilentMatches finds the matches without any message in their room and not stale passing filter
+func (r *MongoRepository) findSilentMatches(ctx context.Context, filter bson.M, limit int) ([]*types.Match, error) {
+	filter["matched"] = true
+	filter["stale"] = bson.M{"$ne": true}
+	filter["messaged"] = bson.M{"$ne": true}
+	query := []bson.M{
+		{"$match": filter},
+		{"$lookup": bson.M{
+			"from": "message",
+			"let":  bson.M{"room_id": "$_id"},
+			"pipeline": []bson.M{
+				{"$match": bson.M{"$expr": bson.M{"$eq": []string{"$room_id", "$$room_id"}}}},
+				{"$limit": 1},
+				{"$project": bson.M{"_id": 1}},
+			},
+			"as": "messages",
+		}},
+		{"$match": bson.M{"messages": bson.M{"$size": 0}}},
+		{"$project": bson.M{"messages": 0}},
+		{"$limit": int64(limit)},
+	}
+
+	var matches []*types.Match
+	cursor, err := r.collection().Aggregate(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &matches); err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
+
+// This method helps mark the reminder of a match as sent
+func (r *MongoRepository) MarkReminded(ctx context.Context, id string) error {
+	matchID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection().UpdateByID(ctx, matchID, bson.M{"$set": bson.M{"reminded": true}})
+	return err
+}
+
+// This method helps extend a match of a user until expiresAt, it returns false
+// when the match was already extended or is stale
+func (r *MongoRepository) ExtendMatch(ctx context.Context, id, idUser string, expiresAt time.Time) (bool, error) {
+	matchID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return false, err
+	}
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return false, err
+	}
+	filter := bson.M{
+		"_id": matchID,
+		"$or": []interface{}{
+			bson.M{"user_id": userID},
+			bson.M{"target_user_id": userID},
+		},
+		"matched":  true,
+		"extended": bson.M{"$ne": true},
+		"stale":    bson.M{"$ne": true},
+	}
+	update := bson.M{"$set": bson.M{
+		"extended":   true,
+		"expires_at": expiresAt,
+	}}
+	result, err := r.collection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// matchedBeforeExpr is the expression of the matches matched before a time, matches
+// made before matched_at was recorded fall back to the like
+func matchedBeforeExpr(before time.Time) bson.M {
+	return bson.M{"$lte": []interface{}{bson.M{"$ifNull": []interface{}{"$matched_at", "$created_at"}}, before}}
+}
+
+// messagePreviewLength is the number of characters of the last message previewed in the matches
+const messagePreviewLength = 100
+
+// withUser is the stages joining the user whose id is in field as user, dropping
+// users who can't chat and users blocking or blocked by the viewer
+func withUser(field string, viewerID primitive.ObjectID) []bson.M {
+	return []bson.M{
+		{"$lookup": bson.M{
+			"from":         "users",
+			"localField":   field,
+			"foreignField": "_id",
+			"as":           "user",
+		}},
+		{"$unwind": "$user"},
+		{"$match": bson.M{
+			"user.status":        bson.M{"$in": types.ChatStatuses},
+			"user.blocked_users": bson.M{"$ne": viewerID},
+			"user.blocked_by":    bson.M{"$ne": viewerID},
+		}},
+	}
+}
+
+// listSort is the sort of a listing, keys maps a sort field to its document field,
+// ties are broken by _id so pages are stable
+func listSort(fields []types.SortField, keys map[string]string) bson.D {
+	sort := bson.D{}
+	for _, field := range fields {
+		order := 1
+		if field.Desc {
+			order = -1
+		}
+		sort = append(sort, bson.E{Key: keys[field.Field], Value: order})
+	}
+	return append(sort, bson.E{Key: "_id", Value: 1})
+}
+
+// pageFacet is the stage returning a page of the documents as items and their number as total
+func pageFacet(sort bson.D, ps types.PagingNSorting) bson.M {
+	return bson.M{"$facet": bson.M{
+		"items": []bson.M{
+			{"$sort": sort},
+			{"$skip": int64((ps.Page - 1) * ps.Size)},
+			{"$limit": int64(ps.Size)},
+		},
+		"total": []bson.M{{"$count": "count"}},
+	}}
+}
+
 func (r *MongoRepository) collection() *mongo.Collection {
 	return r.client.Database("dating").Collection("matches")
 }
+
+func (r *MongoRepository) messages() *mongo.Collection {
+	return r.client.Database("dating").Collection("message")
+}
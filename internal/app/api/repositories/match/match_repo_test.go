@@ -0,0 +1,89 @@
+package match
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"dating/internal/app/api/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testRepository connects to the Mongo of MONGODB_URI, the test is skipped without it
+func testRepository(t *testing.T) *MongoRepository {
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		t.Skip("MONGODB_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Disconnect(context.Background())
+	})
+	r := NewMongoRepository(client)
+	if err := r.EnsurePairKeys(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestMutualLikesMatchOnce(t *testing.T) {
+	r := testRepository(t)
+	ctx := context.Background()
+	for i := 0; i < 50; i++ {
+		a, b := primitive.NewObjectID(), primitive.NewObjectID()
+		key := types.PairKey(a, b)
+
+		var wg sync.WaitGroup
+		matchedNow := make(chan bool, 2)
+		for _, like := range []types.Match{
+			{UserID: a, TargetUserID: b, CreateAt: time.Now()},
+			{UserID: b, TargetUserID: a, CreateAt: time.Now()},
+		} {
+			wg.Add(1)
+			go func(like types.Match) {
+				defer wg.Done()
+				_, now, err := r.Like(ctx, like)
+				if err != nil {
+					t.Error(err)
+				}
+				matchedNow <- now
+			}(like)
+		}
+		wg.Wait()
+		close(matchedNow)
+
+		completed := 0
+		for now := range matchedNow {
+			if now {
+				completed++
+			}
+		}
+		var matches []*types.Match
+		cursor, err := r.collection().Find(ctx, bson.M{"pair_key": key})
+		if err == nil {
+			err = cursor.All(ctx, &matches)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.collection().DeleteMany(ctx, bson.M{"pair_key": key})
+
+		if len(matches) != 1 || completed != 1 {
+			t.Fatalf("run %d: %d matches, %d likes completed a match; expected 1, 1;", i, len(matches), completed)
+		}
+		if !matches[0].Matched {
+			t.Fatalf("run %d: pair not matched", i)
+		}
+	}
+}
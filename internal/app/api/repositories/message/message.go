@@ -2,6 +2,7 @@ package message
 
 import (
 	"context"
+	"time"
 
 	"dating/internal/app/api/types"
 
@@ -26,9 +27,16 @@ func NewMongoRepository(c *mongo.Client) *MongoRepository {
 	}
 }
 
-// This method helps insert message
+// This method helps insert message, the match of the room is flagged first so the
+// expiry can't remove it once the message is sent
 func (r *MongoRepository) Insert(ctx context.Context, message types.Message) error {
-	_, err := r.collection().InsertOne(context.TODO(), message)
+	_, err := r.client.Database("dating").Collection("matches").UpdateOne(ctx,
+		bson.M{"_id": message.RoomID, "messaged": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"messaged": true}})
+	if err != nil {
+		return err
+	}
+	_, err = r.collection().InsertOne(context.TODO(), message)
 	return err
 }
 
@@ -59,6 +67,56 @@ func (r *MongoRepository) FindByIDRoom(ctx context.Context, id string) ([]*types
 	return result, err
 }
 
+// This method helps find the status of an account, nil when the user doesn't exist
+func (r *MongoRepository) FindStatus(ctx context.Context, idUser string) (*types.AccountStatus, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+	var status *types.AccountStatus
+	opts := options.FindOne().SetProjection(bson.M{"status": 1, "status_until": 1})
+	err = r.client.Database("dating").Collection("users").FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&status)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return status, err
+}
+
+// This method helps count messages of a room
+func (r *MongoRepository) CountByRoomID(ctx context.Context, idRoom string) (int64, error) {
+	objectID, err := primitive.ObjectIDFromHex(idRoom)
+	if err != nil {
+		return 0, err
+	}
+	return r.collection().CountDocuments(ctx, bson.M{"room_id": objectID})
+}
+
+// This method helps send a message flagged by the chat filters to the moderator review queue
+func (r *MongoRepository) Flag(ctx context.Context, message types.Message, reason string) error {
+	report := types.Report{
+		ID:         primitive.NewObjectID(),
+		ReportedID: message.SenderID,
+		Reason:     types.ReportReasonAutoFlagged,
+		Details:    reason,
+		MessageIDs: []primitive.ObjectID{message.ID},
+		Status:     types.ReportStatusOpen,
+		CreateAt:   time.Now(),
+	}
+	_, err := r.client.Database("dating").Collection("reports").InsertOne(ctx, report)
+	return err
+}
+
+// This method helps get the match which owns a room, the room id is the match id
+func (r *MongoRepository) FindMatchByRoomID(ctx context.Context, idRoom string) (*types.Match, error) {
+	objectID, err := primitive.ObjectIDFromHex(idRoom)
+	if err != nil {
+		return nil, err
+	}
+	var match *types.Match
+	err = r.client.Database("dating").Collection("matches").FindOne(ctx, bson.M{"_id": objectID}).Decode(&match)
+	return match, err
+}
+
 func (r *MongoRepository) collection() *mongo.Collection {
 	return r.client.Database("dating").Collection("message")
 }
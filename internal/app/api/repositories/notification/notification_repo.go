@@ -0,0 +1,116 @@
+package notification
+
+import (
+	"context"
+
+	"dating/internal/app/api/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	client *mongo.Client
+}
+
+func NewMongoRepository(c *mongo.Client) *MongoRepository {
+	return &MongoRepository{
+		client: c,
+	}
+}
+
+// This method helps insert notification
+func (r *MongoRepository) Insert(ctx context.Context, notification types.Notification) error {
+	_, err := r.collection().InsertOne(ctx, notification)
+	return err
+}
+
+// This method helps get notifications of a user, newest first
+func (r *MongoRepository) FindByUserID(ctx context.Context, idUser string, unreadOnly bool, skip, limit int64) ([]*types.Notification, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"user_id": userID}
+	if unreadOnly {
+		filter["read"] = false
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+
+	var result []*types.Notification
+	cursor, err := r.collection().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, err
+}
+
+// This method helps count unread notifications of a user
+func (r *MongoRepository) CountUnread(ctx context.Context, idUser string) (int64, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return 0, err
+	}
+	return r.collection().CountDocuments(ctx, bson.M{"user_id": userID, "read": false})
+}
+
+// This method helps mark a notification of a user as read
+func (r *MongoRepository) MarkRead(ctx context.Context, idUser, id string) error {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return err
+	}
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	result, err := r.collection().UpdateOne(ctx,
+		bson.M{"_id": objectID, "user_id": userID},
+		bson.M{"$set": bson.M{"read": true}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// This method helps mark all notifications of a user as read
+func (r *MongoRepository) MarkAllRead(ctx context.Context, idUser string) error {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return err
+	}
+	_, err = r.collection().UpdateMany(ctx,
+		bson.M{"user_id": userID, "read": false},
+		bson.M{"$set": bson.M{"read": true}})
+	return err
+}
+
+// This method helps find the locale saved by a user, empty when the user has not set one
+func (r *MongoRepository) FindLocale(ctx context.Context, idUser primitive.ObjectID) (string, error) {
+	var user struct {
+		Locale string `bson:"locale"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"locale": 1})
+	err := r.client.Database("dating").Collection("users").FindOne(ctx, bson.M{"_id": idUser}, opts).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return "", nil
+	}
+	return user.Locale, err
+}
+
+func (r *MongoRepository) collection() *mongo.Collection {
+	return r.client.Database("dating").Collection("notifications")
+}
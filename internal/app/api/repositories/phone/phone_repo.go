@@ -0,0 +1,130 @@
+package phone
+
+import (
+	"context"
+	"time"
+
+	"dating/internal/app/api/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	client *mongo.Client
+}
+
+func NewMongoRepository(c *mongo.Client) *MongoRepository {
+	return &MongoRepository{
+		client: c,
+	}
+}
+
+// This method helps create the indexes, a phone is verified by one user and codes expire
+func (r *MongoRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.users().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "phone", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"phone": bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.verifications().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// This method helps find the user who verified a phone, nil when none did
+func (r *MongoRepository) FindPhoneOwner(ctx context.Context, phone string) (*primitive.ObjectID, error) {
+	var user struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1})
+	err := r.users().FindOne(ctx, bson.M{"phone": phone}, opts).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user.ID, nil
+}
+
+// This method helps find the code sent to a user, whether it expired or not
+func (r *MongoRepository) FindVerification(ctx context.Context, idUser primitive.ObjectID) (*types.PhoneVerification, error) {
+	var verification *types.PhoneVerification
+	err := r.verifications().FindOne(ctx, bson.M{"_id": idUser}).Decode(&verification)
+	return verification, err
+}
+
+// This method helps save the code sent to a user, replacing the previous one
+func (r *MongoRepository) SaveVerification(ctx context.Context, verification types.PhoneVerification) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.verifications().ReplaceOne(ctx, bson.M{"_id": verification.UserID}, verification, opts)
+	return err
+}
+
+// This method helps count an attempt at the code of a user, it returns the code when it is
+// still valid: not expired and attempted less than maxAttempts times
+func (r *MongoRepository) Attempt(ctx context.Context, idUser primitive.ObjectID, maxAttempts int, now time.Time) (*types.PhoneVerification, error) {
+	filter := bson.M{
+		"_id":        idUser,
+		"attempts":   bson.M{"$lt": maxAttempts},
+		"expires_at": bson.M{"$gt": now},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var verification *types.PhoneVerification
+	err := r.verifications().FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"attempts": 1}}, opts).Decode(&verification)
+	return verification, err
+}
+
+// This method helps delete the code of a user
+func (r *MongoRepository) DeleteVerification(ctx context.Context, idUser primitive.ObjectID) error {
+	_, err := r.verifications().DeleteOne(ctx, bson.M{"_id": idUser})
+	return err
+}
+
+// This method helps set the verified phone of a user, a duplicate key error is returned when
+// another user verified it
+func (r *MongoRepository) SetPhone(ctx context.Context, idUser primitive.ObjectID, phone string, now time.Time) error {
+	result, err := r.users().UpdateByID(ctx, idUser, bson.M{"$set": bson.M{
+		"phone":             phone,
+		"phone_verified":    true,
+		"phone_verified_at": now,
+		"updated_at":        now,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// This method helps remove the phone of a user, it returns false when there is none
+func (r *MongoRepository) RemovePhone(ctx context.Context, idUser primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": idUser, "phone": bson.M{"$exists": true}}
+	result, err := r.users().UpdateOne(ctx, filter, bson.M{
+		"$unset": bson.M{"phone": "", "phone_verified": "", "phone_verified_at": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *MongoRepository) users() *mongo.Collection {
+	return r.client.Database("dating").Collection("users")
+}
+
+func (r *MongoRepository) verifications() *mongo.Collection {
+	return r.client.Database("dating").Collection("phone_verifications")
+}
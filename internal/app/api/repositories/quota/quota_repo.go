@@ -0,0 +1,66 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	client *mongo.Client
+}
+
+func NewMongoRepository(c *mongo.Client) *MongoRepository {
+	return &MongoRepository{
+		client: c,
+	}
+}
+
+// This method helps take one unit of a daily quota, day is the user's local date (yyyy-mm-dd)
+// so the quota resets at the user's local midnight. It returns false when the quota is used up.
+func (r *MongoRepository) Take(ctx context.Context, idUser, kind, day string, limit int) (bool, error) {
+	if limit <= 0 {
+		return false, nil
+	}
+	// one document per user, kind and day, _id is unique so a concurrent upsert
+	// on an exhausted quota fails with a duplicate key error
+	filter := bson.M{
+		"_id":   fmt.Sprintf("%s:%s:%s", idUser, kind, day),
+		"count": bson.M{"$lt": limit},
+	}
+	update := bson.M{
+		"$inc": bson.M{"count": 1},
+		"$setOnInsert": bson.M{
+			"user_id": idUser,
+			"kind":    kind,
+			"day":     day,
+		},
+	}
+
+	opts := options.Update().SetUpsert(true)
+	_, err := r.collection().UpdateOne(ctx, filter, update, opts)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// This method helps give back one unit of a daily quota taken for an action that then failed
+func (r *MongoRepository) Refund(ctx context.Context, idUser, kind, day string) error {
+	filter := bson.M{
+		"_id":   fmt.Sprintf("%s:%s:%s", idUser, kind, day),
+		"count": bson.M{"$gt": 0},
+	}
+	_, err := r.collection().UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"count": -1}})
+	return err
+}
+
+func (r *MongoRepository) collection() *mongo.Collection {
+	return r.client.Database("dating").Collection("quotas")
+}
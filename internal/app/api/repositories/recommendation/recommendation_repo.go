@@ -0,0 +1,101 @@
+package recommendation
+
+import (
+	"context"
+	"time"
+
+	"dating/internal/app/api/repositories/user"
+	"dating/internal/app/api/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// candidateProjection leaves out the private fields of a user
+var candidateProjection = bson.M{
+	"password":      0,
+	"email":         0,
+	"phone":         0,
+	"blocked_users": 0,
+	"blocked_by":    0,
+	"moderation":    0,
+}
+
+type MongoRepository struct {
+	client *mongo.Client
+}
+
+func NewMongoRepository(c *mongo.Client) *MongoRepository {
+	return &MongoRepository{
+		client: c,
+	}
+}
+
+// This method helps find a user as seen by the recommendation engine
+func (r *MongoRepository) FindCandidate(ctx context.Context, idUser string) (*types.Candidate, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+	var user *types.Candidate
+	opts := options.FindOne().SetProjection(candidateProjection)
+	err = r.users().FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&user)
+	return user, err
+}
+
+// This method helps find the most recently active users passing the discovery filter
+// that idUser hasn't liked or matched yet
+func (r *MongoRepository) FindCandidates(ctx context.Context, idUser string, ps types.PagingNSorting, limit int) ([]*types.Candidate, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+
+	liked, err := r.matches().Distinct(ctx, "target_user_id", bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	matched, err := r.matches().Distinct(ctx, "user_id", bson.M{"target_user_id": userID, "matched": true})
+	if err != nil {
+		return nil, err
+	}
+	seen := append(append([]interface{}{userID}, liked...), matched...)
+
+	filter := user.DiscoveryFilter(userID, ps)
+	filter["_id"] = bson.M{"$nin": seen}
+
+	opts := options.Find().
+		SetProjection(candidateProjection).
+		SetSort(bson.D{{Key: "last_active_at", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	var result []*types.Candidate
+	cursor, err := r.users().Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// This method helps record the user is active now
+func (r *MongoRepository) TouchLastActive(ctx context.Context, idUser string) error {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return err
+	}
+	_, err = r.users().UpdateByID(ctx, userID, bson.M{"$set": bson.M{"last_active_at": time.Now()}})
+	return err
+}
+
+func (r *MongoRepository) users() *mongo.Collection {
+	return r.client.Database("dating").Collection("users")
+}
+
+func (r *MongoRepository) matches() *mongo.Collection {
+	return r.client.Database("dating").Collection("matches")
+}
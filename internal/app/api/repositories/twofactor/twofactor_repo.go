@@ -0,0 +1,111 @@
+package twofactor
+
+import (
+	"context"
+	"time"
+
+	"dating/internal/app/api/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoRepository struct {
+	client *mongo.Client
+}
+
+func NewMongoRepository(c *mongo.Client) *MongoRepository {
+	return &MongoRepository{
+		client: c,
+	}
+}
+
+// This method helps get user with id, whatever the status of the account
+func (r *MongoRepository) FindByID(ctx context.Context, idUser primitive.ObjectID) (*types.User, error) {
+	var user *types.User
+	err := r.collection().FindOne(ctx, bson.M{"_id": idUser}).Decode(&user)
+	return user, err
+}
+
+// This method helps start an enrollment with a new secret, replacing a pending one, it returns
+// false when two-factor authentication is already enabled
+func (r *MongoRepository) SetPending(ctx context.Context, idUser primitive.ObjectID, secret string) (bool, error) {
+	filter := bson.M{"_id": idUser, "two_factor.enabled": bson.M{"$ne": true}}
+	update := bson.M{"$set": bson.M{"two_factor": types.TwoFactor{
+		Secret:        secret,
+		RecoveryCodes: []string{},
+	}}}
+	result, err := r.collection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// This method helps enable the pending enrollment of secret confirmed with the code of step
+func (r *MongoRepository) Enable(ctx context.Context, idUser primitive.ObjectID, secret string, step int64, recoveryCodes []string, now time.Time) (bool, error) {
+	filter := bson.M{
+		"_id":                  idUser,
+		"two_factor.secret":    secret,
+		"two_factor.enabled":   false,
+		"two_factor.last_step": bson.M{"$lt": step},
+	}
+	update := bson.M{"$set": bson.M{
+		"two_factor.enabled":        true,
+		"two_factor.enabled_at":     now,
+		"two_factor.last_step":      step,
+		"two_factor.recovery_codes": recoveryCodes,
+	}}
+	result, err := r.collection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// This method helps use the code of step, it returns false when a code of this step or a later
+// one was already used
+func (r *MongoRepository) UseStep(ctx context.Context, idUser primitive.ObjectID, step int64) (bool, error) {
+	filter := bson.M{"_id": idUser, "two_factor.enabled": true, "two_factor.last_step": bson.M{"$lt": step}}
+	result, err := r.collection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"two_factor.last_step": step}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// This method helps use a recovery code by its hash, it returns false when it isn't one of the
+// unused codes
+func (r *MongoRepository) UseRecoveryCode(ctx context.Context, idUser primitive.ObjectID, hash string) (bool, error) {
+	filter := bson.M{"_id": idUser, "two_factor.enabled": true, "two_factor.recovery_codes": hash}
+	result, err := r.collection().UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"two_factor.recovery_codes": hash}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// This method helps replace the recovery codes of a user
+func (r *MongoRepository) SetRecoveryCodes(ctx context.Context, idUser primitive.ObjectID, recoveryCodes []string) (bool, error) {
+	filter := bson.M{"_id": idUser, "two_factor.enabled": true}
+	result, err := r.collection().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"two_factor.recovery_codes": recoveryCodes}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// This method helps disable two-factor authentication of a user
+func (r *MongoRepository) Disable(ctx context.Context, idUser primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": idUser, "two_factor.enabled": true}
+	result, err := r.collection().UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"two_factor": ""}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *MongoRepository) collection() *mongo.Collection {
+	return r.client.Database("dating").Collection("users")
+}
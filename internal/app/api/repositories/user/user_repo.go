@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"math"
 	"time"
 
 	"dating/internal/app/api/types"
@@ -13,6 +14,8 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+const earthRadiusKm = 6371
+
 var (
 	ErrNotFound = errors.New("not found")
 )
@@ -33,10 +36,10 @@ func (r *MongoRepository) Insert(ctx context.Context, user types.User) error {
 	return err
 }
 
-// this method helps get user with email
+// this method helps get user with email, whatever the status of the account
 func (r *MongoRepository) FindByEmail(ctx context.Context, email string) (*types.User, error) {
 	var user *types.User
-	err := r.collection().FindOne(ctx, bson.M{"email": email, "disable": false}).Decode(&user)
+	err := r.collection().FindOne(ctx, bson.M{"email": email}).Decode(&user)
 	return user, err
 }
 
@@ -47,7 +50,19 @@ func (r *MongoRepository) FindByID(ctx context.Context, id string) (*types.UserR
 		return nil, err
 	}
 	var user *types.UserResGetInfo
-	err = r.collection().FindOne(ctx, bson.M{"_id": objectID, "disable": false}).Decode(&user)
+	err = r.collection().FindOne(ctx, bson.M{"_id": objectID, "status": bson.M{"$in": types.ChatStatuses}}).Decode(&user)
+
+	return user, err
+}
+
+// This method helps find the full profile of a user, the password is never read
+func (r *MongoRepository) FindProfileByID(ctx context.Context, id string) (*types.UserProfile, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	var user *types.UserProfile
+	err = r.collection().FindOne(ctx, bson.M{"_id": objectID}).Decode(&user)
 
 	return user, err
 }
@@ -63,8 +78,17 @@ func (r *MongoRepository) UpdateUserByID(ctx context.Context, user types.User) e
 		"gender":       user.Gender,
 		"country":      user.Country,
 		"hobby":        user.Hobby,
+		"interests":    user.Interests,
 		"sex":          user.Sex,
 		"about":        user.About,
+		"timezone":     user.Timezone,
+		"locale":       user.Locale,
+		"location":     user.Location,
+		"height":       user.Height,
+		"education":    user.Education,
+		"job":          user.Job,
+		"languages":    user.Languages,
+		"prompts":      user.Prompts,
 		"updated_at":   time.Now(),
 	}}
 
@@ -72,59 +96,419 @@ func (r *MongoRepository) UpdateUserByID(ctx context.Context, user types.User) e
 	return err
 }
 
-// This method helps Enable/Disable account
-func (r *MongoRepository) DisableUserByID(ctx context.Context, idUser string, disable bool) error {
+// This method helps set some fields of a user and returns the updated profile,
+// when updatedAt is given the user is only updated if it wasn't modified since
+func (r *MongoRepository) PatchUserByID(ctx context.Context, id string, set map[string]interface{}, updatedAt *time.Time) (*types.UserProfile, error) {
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.M{"_id": userID}
+	if updatedAt != nil {
+		filter["updated_at"] = *updatedAt
+	}
+	set["updated_at"] = time.Now()
+
+	var user *types.UserProfile
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = r.collection().FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&user)
+
+	return user, err
+}
+
+// This method helps record the user is active now
+func (r *MongoRepository) TouchLastActive(ctx context.Context, idUser string) error {
 	userID, err := primitive.ObjectIDFromHex(idUser)
 	if err != nil {
 		return err
 	}
-	disableUpdate := bson.M{"$set": bson.M{
-		"disable": disable,
-	}}
-	_, err = r.collection().UpdateByID(ctx, userID, disableUpdate)
+	_, err = r.collection().UpdateByID(ctx, userID, bson.M{"$set": bson.M{"last_active_at": time.Now()}})
 	return err
 }
 
-// This method helps get all user by page
-func (r *MongoRepository) GetListUsers(ctx context.Context, ps types.PagingNSorting) ([]*types.UserResGetInfo, error) {
-	query := bson.M{
-		"disable": false,
-		"birthday": bson.M{
-			"$gte": ps.Filter.AgeRange.Gte,
-			"$lt":  ps.Filter.AgeRange.Lt,
-		},
-		"gender": bson.M{
-			"$in": ps.Filter.Gender,
-		},
+// This method helps lift the suspension of a user once it is over, it returns false when
+// the user isn't suspended or the suspension isn't over
+func (r *MongoRepository) LiftSuspension(ctx context.Context, idUser string, now time.Time) (bool, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return false, err
+	}
+	filter := bson.M{"_id": userID, "status": types.StatusSuspended, "status_until": bson.M{"$lte": now}}
+	result, err := r.collection().UpdateOne(ctx, filter, bson.M{
+		"$set":   bson.M{"status": types.StatusActive},
+		"$unset": bson.M{"status_until": "", "moderation": ""},
+	})
+	if err != nil {
+		return false, err
 	}
-	var result []*types.UserResGetInfo
-	opts := options.Find()
-	opts.SetSkip(int64((ps.Page - 1) * ps.Size))
-	opts.SetLimit(int64(ps.Size))
-	cursor, err := r.collection().Find(ctx, query, opts)
+	return result.ModifiedCount > 0, nil
+}
+
+// This method helps find the status of an account, nil when the user doesn't exist
+func (r *MongoRepository) FindStatus(ctx context.Context, idUser string) (*types.AccountStatus, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
 	if err != nil {
 		return nil, err
 	}
+	var status *types.AccountStatus
+	opts := options.FindOne().SetProjection(bson.M{"status": 1, "status_until": 1, "role": 1, "locale": 1})
+	err = r.collection().FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&status)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return status, err
+}
+
+// This method helps give a status to the users saved before the status replaced the disable
+// flag, derived from the flag, the last moderation and a requested deletion
+func (r *MongoRepository) EnsureStatus(ctx context.Context) error {
+	disabled := bson.M{"$eq": []interface{}{"$disable", true}}
+	update := []bson.M{
+		{"$set": bson.M{
+			"status": bson.M{"$switch": bson.M{
+				"branches": []bson.M{
+					{"case": bson.M{"$and": []interface{}{disabled, bson.M{"$eq": []interface{}{"$moderation.action", types.ModerationBan}}}}, "then": types.StatusBanned},
+					{"case": disabled, "then": types.StatusSuspended},
+					{"case": bson.M{"$gt": []interface{}{"$delete_at", nil}}, "then": types.StatusPendingDeletion},
+				},
+				"default": types.StatusActive,
+			}},
+			"status_until": bson.M{"$cond": []interface{}{disabled, "$moderation.until", "$$REMOVE"}},
+		}},
+		{"$unset": "disable"},
+	}
+	_, err := r.collection().UpdateMany(ctx, bson.M{"status": bson.M{"$exists": false}}, update)
+	return err
+}
 
+// This method helps get a page of users in the asked order, after ps.After or skipping the
+// previous pages, and the sort key of the last user. Users who super liked idUser come first
+// by default, each user carries the number of interests shared with idUser
+func (r *MongoRepository) GetListUsers(ctx context.Context, idUser string, ps types.PagingNSorting) ([]*types.UserResGetInfo, []interface{}, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, nil, err
+	}
+	var caller struct {
+		Interests []string `bson:"interests"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"interests": 1})
+	if err := r.collection().FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&caller); err != nil {
+		return nil, nil, err
+	}
+	if caller.Interests == nil {
+		caller.Interests = []string{}
+	}
+	sort := listUsersSort(ps)
+	filter := DiscoveryFilter(userID, ps)
+	// a range on stored fields is an index range of the first stage, computed fields only
+	// exist after the $addFields stages
+	stored := ps.After != nil && storedSort(sort)
+	if stored {
+		filter = bson.M{"$and": bson.A{filter, storedAfter(sort, ps.After)}}
+	}
+	query := []bson.M{
+		{"$match": filter},
+		{"$lookup": bson.M{
+			"from": "matches",
+			"let":  bson.M{"user_id": "$_id"},
+			"pipeline": []bson.M{
+				{"$match": bson.M{
+					"$expr": bson.M{
+						"$eq": []string{"$user_id", "$$user_id"},
+					},
+					"target_user_id": userID,
+					"super_like":     true,
+					"matched":        false,
+				},
+				},
+			},
+			"as": "super_likes",
+		}},
+		{"$addFields": bson.M{
+			"super_liked": bson.M{"$gt": []interface{}{bson.M{"$size": "$super_likes"}, 0}},
+			"shared_interests": bson.M{"$size": bson.M{
+				"$setIntersection": []interface{}{
+					bson.M{"$ifNull": []interface{}{"$interests", []string{}}},
+					caller.Interests,
+				},
+			}},
+		}},
+	}
+	if sortsBy(ps, "distance") {
+		query = append(query, bson.M{"$addFields": bson.M{"distance": distanceFrom(ps.Filter.Viewer)}})
+	}
+
+	// the sort key of each user, missing fields are null like the sort sees them
+	key := bson.A{}
+	for _, e := range sort {
+		key = append(key, bson.M{"$ifNull": bson.A{"$" + e.Key, nil}})
+	}
+	query = append(query, bson.M{"$addFields": bson.M{"sort_key": key}})
+
+	if stored {
+		query = append(query, bson.M{"$sort": sort})
+	} else if ps.After != nil {
+		// keyset pagination, users after the last one of the previous page
+		query = append(query, bson.M{"$match": bson.M{"$expr": after(sort, ps.After)}}, bson.M{"$sort": sort})
+	} else {
+		query = append(query, bson.M{"$sort": sort}, bson.M{"$skip": int64((ps.Page - 1) * ps.Size)})
+	}
+	query = append(query, bson.M{"$limit": int64(ps.Size)})
+
+	var result []*struct {
+		types.UserResGetInfo `bson:",inline"`
+		SortKey              []interface{} `bson:"sort_key"`
+	}
+	cursor, err := r.collection().Aggregate(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
 	if err = cursor.All(ctx, &result); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return result, err
+
+	list := make([]*types.UserResGetInfo, 0, len(result))
+	for _, user := range result {
+		list = append(list, &user.UserResGetInfo)
+	}
+	var last []interface{}
+	if len(result) > 0 {
+		last = result[len(result)-1].SortKey
+	}
+	return list, last, nil
+}
+
+// after is the expression of the users whose sort key comes after key in sort
+func after(sort bson.D, key []interface{}) bson.M {
+	or := bson.A{}
+	for i, e := range sort {
+		if i >= len(key) {
+			break
+		}
+		and := bson.A{}
+		for j := 0; j < i; j++ {
+			and = append(and, bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$" + sort[j].Key, nil}}, key[j]}})
+		}
+		cmp := "$gt"
+		if e.Value == -1 {
+			cmp = "$lt"
+		}
+		and = append(and, bson.M{cmp: bson.A{bson.M{"$ifNull": bson.A{"$" + e.Key, nil}}, key[i]}})
+		or = append(or, bson.M{"$and": and})
+	}
+	return bson.M{"$or": or}
+}
+
+// storedSort tells whether every key of sort is a field of the users collection
+func storedSort(sort bson.D) bool {
+	for _, e := range sort {
+		switch e.Key {
+		case "_id", "created_at", "updated_at", "last_active_at", "birthday":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// storedAfter is the filter of the users whose sort key comes after key in sort, like after
+// but in the query language so it can use an index. A missing field is null, the lowest value
+func storedAfter(sort bson.D, key []interface{}) bson.M {
+	or := bson.A{}
+	for i, e := range sort {
+		if i >= len(key) {
+			break
+		}
+		and := bson.A{}
+		for j := 0; j < i; j++ {
+			// null also matches a missing field
+			and = append(and, bson.M{sort[j].Key: key[j]})
+		}
+		switch {
+		case e.Value == 1 && key[i] == nil:
+			and = append(and, bson.M{e.Key: bson.M{"$ne": nil}})
+		case e.Value == 1:
+			and = append(and, bson.M{e.Key: bson.M{"$gt": key[i]}})
+		case key[i] == nil:
+			// nothing is lower than null
+			continue
+		default:
+			and = append(and, bson.M{"$or": bson.A{bson.M{e.Key: bson.M{"$lt": key[i]}}, bson.M{e.Key: nil}}})
+		}
+		or = append(or, bson.M{"$and": and})
+	}
+	return bson.M{"$or": or}
+}
+
+// listUsersSort is the sort of the users list, ties are broken by _id so pages are stable
+func listUsersSort(ps types.PagingNSorting) bson.D {
+	if len(ps.Sort) == 0 {
+		return bson.D{
+			{Key: "super_liked", Value: -1},
+			{Key: "_id", Value: 1},
+		}
+	}
+	sort := bson.D{}
+	for _, field := range ps.Sort {
+		key, order := field.Field, 1
+		switch field.Field {
+		case "last_active":
+			key = "last_active_at"
+		case "age":
+			// older is an earlier birthday
+			key, order = "birthday", -1
+		}
+		if field.Desc {
+			order = -order
+		}
+		sort = append(sort, bson.E{Key: key, Value: order})
+	}
+	return append(sort, bson.E{Key: "_id", Value: 1})
+}
+
+// sortsBy tells whether the users are sorted by field
+func sortsBy(ps types.PagingNSorting, field string) bool {
+	for _, f := range ps.Sort {
+		if f.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// distanceFrom is the expression of the great circle distance in km between a user
+// and the viewer, users are the farthest when either location is unknown
+func distanceFrom(viewer *types.Candidate) interface{} {
+	if viewer == nil || viewer.Location == nil {
+		return math.MaxFloat64
+	}
+	location := *viewer.Location
+	lat := location.Lat * math.Pi / 180
+	lng := location.Lng * math.Pi / 180
+	userLat := bson.M{"$degreesToRadians": "$location.lat"}
+	userLng := bson.M{"$degreesToRadians": "$location.lng"}
+	sinHalf := func(delta bson.M) bson.M {
+		return bson.M{"$pow": bson.A{bson.M{"$sin": bson.M{"$divide": bson.A{delta, 2}}}, 2}}
+	}
+	h := bson.M{"$add": bson.A{
+		sinHalf(bson.M{"$subtract": bson.A{userLat, lat}}),
+		bson.M{"$multiply": bson.A{
+			math.Cos(lat),
+			bson.M{"$cos": userLat},
+			sinHalf(bson.M{"$subtract": bson.A{userLng, lng}}),
+		}},
+	}}
+	return bson.M{"$ifNull": bson.A{
+		bson.M{"$multiply": bson.A{2 * earthRadiusKm, bson.M{"$asin": bson.M{"$sqrt": h}}}},
+		math.MaxFloat64,
+	}}
 }
 
 // This method helps count number users in collection
-func (r *MongoRepository) CountUser(ctx context.Context, ps types.PagingNSorting) (int64, error) {
-	query := bson.M{
-		"disable": false,
+func (r *MongoRepository) CountUser(ctx context.Context, idUser string, ps types.PagingNSorting) (int64, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return 0, err
+	}
+	return r.collection().CountDocuments(ctx, DiscoveryFilter(userID, ps))
+}
+
+// DiscoveryFilter is the filter of users visible to userID in discovery, only active users
+// are listed, users blocked by or blocking userID and users hiding from discovery are hidden. When
+// the viewer is known, users must be within its max distance and want someone like it
+func DiscoveryFilter(userID primitive.ObjectID, ps types.PagingNSorting) bson.M {
+	filter := bson.M{
+		"_id":    bson.M{"$ne": userID},
+		"status": types.StatusActive,
 		"birthday": bson.M{
 			"$gte": ps.Filter.AgeRange.Gte,
 			"$lt":  ps.Filter.AgeRange.Lt,
 		},
-		"gender": bson.M{
-			"$in": ps.Filter.Gender,
-		},
+		"blocked_users":       bson.M{"$ne": userID},
+		"blocked_by":          bson.M{"$ne": userID},
+		"preferences.show_me": bson.M{"$ne": false},
+	}
+	if len(ps.Filter.Gender) > 0 {
+		filter["gender"] = bson.M{"$in": ps.Filter.Gender}
+	}
+	if len(ps.Filter.Interests) > 0 {
+		filter["interests"] = bson.M{"$in": ps.Filter.Interests}
+	}
+	if ps.Filter.PhoneVerified {
+		filter["phone_verified"] = true
+	}
+	if ps.Filter.Verified {
+		filter["verified"] = true
+	}
+
+	viewer := ps.Filter.Viewer
+	if viewer == nil {
+		return filter
+	}
+	if ps.Filter.MaxDistance > 0 && viewer.Location != nil {
+		filter["location"] = bson.M{"$geoWithin": bson.M{
+			"$centerSphere": bson.A{
+				bson.A{viewer.Location.Lng, viewer.Location.Lat},
+				float64(ps.Filter.MaxDistance) / earthRadiusKm,
+			},
+		}}
+	}
+
+	// the preferences of the users must fit the viewer too
+	wants := bson.A{}
+	if viewer.Gender != "" {
+		wants = append(wants, bson.M{"$or": bson.A{
+			bson.M{"preferences.genders": nil},
+			bson.M{"preferences.genders": bson.M{"$size": 0}},
+			bson.M{"preferences.genders": viewer.Gender},
+		}})
+	}
+	if !viewer.Birthday.IsZero() {
+		age := viewer.Age(time.Now())
+		wants = append(wants,
+			bson.M{"preferences.min_age": bson.M{"$not": bson.M{"$gt": age}}},
+			bson.M{"$or": bson.A{
+				bson.M{"preferences.max_age": bson.M{"$in": bson.A{nil, 0}}},
+				bson.M{"preferences.max_age": bson.M{"$gte": age}},
+			}},
+		)
+	}
+	if len(wants) > 0 {
+		filter["$and"] = wants
+	}
+	return filter
+}
+
+// This method helps find the user browsing discovery with its preferences
+func (r *MongoRepository) FindViewer(ctx context.Context, idUser string) (*types.Candidate, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+	var viewer *types.Candidate
+	opts := options.FindOne().SetProjection(bson.M{"password": 0, "email": 0})
+	err = r.collection().FindOne(ctx, bson.M{"_id": userID}, opts).Decode(&viewer)
+	return viewer, err
+}
+
+// This method helps save the discovery preferences of a user
+func (r *MongoRepository) UpdatePreferences(ctx context.Context, idUser string, preferences types.Preferences) error {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return err
+	}
+	result, err := r.collection().UpdateByID(ctx, userID, bson.M{"$set": bson.M{
+		"preferences": preferences,
+		"updated_at":  time.Now(),
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
 	}
-	return r.collection().CountDocuments(ctx, query)
+	return nil
 }
 
 // this method help get list matched include info
@@ -158,7 +542,9 @@ func (r *MongoRepository) GetListMatchedInfo(ctx context.Context, idUser string)
 		{"$unwind": "$target_user"},
 		{"$replaceRoot": bson.M{"newRoot": "$target_user"}},
 		{"$match": bson.M{
-			"disable": false,
+			"status":        bson.M{"$in": types.ChatStatuses},
+			"blocked_users": bson.M{"$ne": userID},
+			"blocked_by":    bson.M{"$ne": userID},
 		}},
 	}
 	var listMatched []*types.UserResGetInfo
@@ -194,7 +580,9 @@ func (r *MongoRepository) GetListlikedInfo(ctx context.Context, idUser string) (
 		{"$unwind": "$target_user"},
 		{"$replaceRoot": bson.M{"newRoot": "$target_user"}},
 		{"$match": bson.M{
-			"disable": false,
+			"status":        bson.M{"$in": types.ChatStatuses},
+			"blocked_users": bson.M{"$ne": userID},
+			"blocked_by":    bson.M{"$ne": userID},
 		}},
 	}
 
@@ -0,0 +1,101 @@
+package verification
+
+import (
+	"context"
+
+	"dating/internal/app/api/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	client *mongo.Client
+}
+
+func NewMongoRepository(c *mongo.Client) *MongoRepository {
+	return &MongoRepository{
+		client: c,
+	}
+}
+
+// This method helps create the indexes, a user has one pending request and prompts expire
+func (r *MongoRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.requests().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"status": types.VerificationPending}),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.prompts().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// This method helps tell if the photo of a user is verified
+func (r *MongoRepository) IsVerified(ctx context.Context, idUser primitive.ObjectID) (bool, error) {
+	var user struct {
+		Verified bool `bson:"verified"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"verified": 1})
+	err := r.client.Database("dating").Collection("users").FindOne(ctx, bson.M{"_id": idUser}, opts).Decode(&user)
+	return user.Verified, err
+}
+
+// This method helps find the pose given to a user, whether it expired or not
+func (r *MongoRepository) FindPrompt(ctx context.Context, idUser primitive.ObjectID) (*types.VerificationPrompt, error) {
+	var prompt *types.VerificationPrompt
+	err := r.prompts().FindOne(ctx, bson.M{"_id": idUser}).Decode(&prompt)
+	return prompt, err
+}
+
+// This method helps save the pose given to a user, replacing the previous one
+func (r *MongoRepository) SavePrompt(ctx context.Context, prompt types.VerificationPrompt) error {
+	opts := options.Replace().SetUpsert(true)
+	_, err := r.prompts().ReplaceOne(ctx, bson.M{"_id": prompt.UserID}, prompt, opts)
+	return err
+}
+
+// This method helps delete the pose given to a user
+func (r *MongoRepository) DeletePrompt(ctx context.Context, idUser primitive.ObjectID) error {
+	_, err := r.prompts().DeleteOne(ctx, bson.M{"_id": idUser})
+	return err
+}
+
+// This method helps insert a request, a duplicate key error is returned when the user has a
+// pending one
+func (r *MongoRepository) InsertRequest(ctx context.Context, request types.VerificationRequest) error {
+	_, err := r.requests().InsertOne(ctx, request)
+	return err
+}
+
+// This method helps find the latest request of a user
+func (r *MongoRepository) FindLatestRequest(ctx context.Context, idUser primitive.ObjectID) (*types.VerificationRequest, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	var request *types.VerificationRequest
+	err := r.requests().FindOne(ctx, bson.M{"user_id": idUser}, opts).Decode(&request)
+	return request, err
+}
+
+func (r *MongoRepository) requests() *mongo.Collection {
+	return r.client.Database("dating").Collection("verifications")
+}
+
+func (r *MongoRepository) prompts() *mongo.Collection {
+	return r.client.Database("dating").Collection("verification_prompts")
+}
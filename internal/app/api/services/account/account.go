@@ -0,0 +1,452 @@
+package accountservices
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"syscall"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/media"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	erasureLock  = "account-erasure"
+	erasureBatch = 100
+
+	// exportTimeout is how long an export is pending before another one is started
+	exportTimeout = time.Hour
+	// mediaMaxBytes is the size of the biggest media of an export when none is configured
+	mediaMaxBytes = 20 << 20
+)
+
+var (
+	// ErrNotFound is returned when the export or the deletion doesn't exist
+	ErrNotFound = apperr.NewNotFound("not found")
+	// ErrNotReady is returned when the archive of an export isn't built yet
+	ErrNotReady = apperr.NewConflict("invalid_value.export_not_ready", "export not ready")
+	// ErrStatusConflict is returned when the account can't be paused or resumed from its status
+	ErrStatusConflict = apperr.NewConflict("invalid_value.account_status_conflict", "status conflict")
+)
+
+// Repository is an interface of an account repository
+type Repository interface {
+	InsertExport(ctx context.Context, export types.Export) error
+	FindLatestExport(ctx context.Context, idUser primitive.ObjectID) (*types.Export, error)
+	FindExport(ctx context.Context, idUser, id primitive.ObjectID) (*types.Export, error)
+	UpdateExportStatus(ctx context.Context, id primitive.ObjectID, status string) error
+	UploadArchive(ctx context.Context, id primitive.ObjectID, archive io.Reader) error
+	OpenArchive(ctx context.Context, id primitive.ObjectID) (io.ReadCloser, error)
+	DeleteExpiredExports(ctx context.Context, now time.Time) (int, error)
+	FindExportData(ctx context.Context, idUser primitive.ObjectID) (*types.ExportData, error)
+	ScheduleDeletion(ctx context.Context, idUser primitive.ObjectID, deleteAt time.Time) (time.Time, error)
+	CancelDeletion(ctx context.Context, idUser primitive.ObjectID) (bool, error)
+	SetStatus(ctx context.Context, idUser primitive.ObjectID, from, to string) (bool, error)
+	FindDueDeletions(ctx context.Context, now time.Time, limit int) ([]primitive.ObjectID, error)
+	Erase(ctx context.Context, idUser primitive.ObjectID) ([]primitive.ObjectID, error)
+}
+
+// Locker is an interface of a lock shared by all replicas
+type Locker interface {
+	Lock(ctx context.Context, name string, ttl time.Duration) (bool, error)
+}
+
+// Disconnector is an interface to close the socket connections of a user and the rooms
+type Disconnector interface {
+	DisconnectUser(idUser primitive.ObjectID)
+	CloseRoom(id primitive.ObjectID)
+}
+
+// Service is an account service
+type Service struct {
+	conf         *config.Configs
+	em           *config.ErrorMessage
+	repo         Repository
+	disconnector Disconnector
+	client       *http.Client
+	logger       glog.Logger
+}
+
+// NewService returns a new account service
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, d Disconnector, l glog.Logger) *Service {
+	return &Service{
+		conf:         c,
+		em:           e,
+		repo:         r,
+		disconnector: d,
+		client:       mediaClient(c.Account.MediaTimeout, c.Media.Hosts),
+		logger:       l,
+	}
+}
+
+// GetExport returns the latest export of a user, a new one is started when there is
+// none in progress or ready to download
+func (s *Service) GetExport(ctx context.Context, idUser string) (*types.Export, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	export, err := s.repo.FindLatestExport(ctx, userID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		s.logger.Errorf("Failed when find export of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when find export")
+	}
+	if export != nil && now.Before(export.ExpiresAt) {
+		switch {
+		case export.Status == types.ExportReady:
+			export.DownloadURL = downloadURL(export.ID)
+			return export, nil
+		case export.Status == types.ExportPending && now.Sub(export.CreateAt) < exportTimeout:
+			return export, nil
+		}
+	}
+
+	export = &types.Export{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Status:    types.ExportPending,
+		CreateAt:  now,
+		ExpiresAt: now.Add(s.conf.Account.ExportTTL),
+	}
+	if err := s.repo.InsertExport(ctx, *export); err != nil {
+		s.logger.Errorf("Failed when insert export of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when insert export")
+	}
+	go s.buildExport(context.Background(), *export)
+
+	s.logger.Infof("Export %s of %s started", export.ID.Hex(), idUser)
+	return export, nil
+}
+
+// buildExport builds the archive of an export, data.json and the media of the user
+func (s *Service) buildExport(ctx context.Context, export types.Export) {
+	status := types.ExportFailed
+	defer func() {
+		if err := s.repo.UpdateExportStatus(ctx, export.ID, status); err != nil {
+			s.logger.Errorf("Failed when update export %s %v", export.ID.Hex(), err)
+		}
+	}()
+
+	data, err := s.repo.FindExportData(ctx, export.UserID)
+	if err != nil {
+		s.logger.Errorf("Failed when find data of export %s %v", export.ID.Hex(), err)
+		return
+	}
+
+	// the archive is streamed to the store as it is written, never held in memory
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(s.writeArchive(ctx, pw, export, data))
+	}()
+	err = s.repo.UploadArchive(ctx, export.ID, pr)
+	pr.CloseWithError(err)
+	if err != nil {
+		s.logger.Errorf("Failed when build archive of export %s %v", export.ID.Hex(), err)
+		return
+	}
+
+	status = types.ExportReady
+	s.logger.Infof("Export %s ready", export.ID.Hex())
+}
+
+// writeArchive writes the media then data.json of an export into w
+func (s *Service) writeArchive(ctx context.Context, w io.Writer, export types.Export, data *types.ExportData) error {
+	zw := zip.NewWriter(w)
+	data.Media = []string{}
+	for i, link := range data.Profile.Media {
+		name := fmt.Sprintf("media/%d%s", i+1, mediaExt(link))
+		body, err := s.fetchMedia(ctx, link)
+		if err != nil {
+			s.logger.Errorf("Failed when fetch media %s of export %s %v", link, export.ID.Hex(), err)
+			data.MissingMedia = append(data.MissingMedia, link)
+			continue
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(body); err != nil {
+			return err
+		}
+		data.Media = append(data.Media, name)
+	}
+	data.ExportedAt = time.Now()
+
+	f, err := zw.Create("data.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// fetchMedia downloads a media of a configured media host, up to the configured size
+func (s *Service) fetchMedia(ctx context.Context, link string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := media.Check(req.URL, s.conf.Media.Hosts); err != nil {
+		return nil, err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("status %d", res.StatusCode)
+	}
+	max := s.conf.Account.MediaMaxBytes
+	if max <= 0 {
+		max = mediaMaxBytes
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > max {
+		return nil, errors.Errorf("media bigger than %d bytes", max)
+	}
+	return body, nil
+}
+
+// mediaClient is the client of the media, it follows the redirects to the hosts only and
+// never connects to a private address, whatever a host resolves to
+func mediaClient(timeout time.Duration, hosts []string) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialPublic}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return media.Check(req.URL, hosts)
+		},
+	}
+}
+
+// privateNets are the loopback, private, link-local and other non public ranges
+var privateNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+		"172.16.0.0/12", "192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/3",
+		"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+// dialPublic refuses a connection to an address of privateNets
+func dialPublic(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return errors.Errorf("media address %q not an ip", host)
+	}
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return errors.Errorf("media address %s not public", ip)
+		}
+	}
+	return nil
+}
+
+// OpenExport returns the archive of a ready export of a user
+func (s *Service) OpenExport(ctx context.Context, idUser, id string) (io.ReadCloser, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+	exportID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	export, err := s.repo.FindExport(ctx, userID, exportID)
+	if err == mongo.ErrNoDocuments || (err == nil && time.Now().After(export.ExpiresAt)) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		s.logger.Errorf("Failed when find export %s %v", id, err)
+		return nil, errors.Wrap(err, "Failed when find export")
+	}
+	if export.Status != types.ExportReady {
+		return nil, ErrNotReady
+	}
+
+	archive, err := s.repo.OpenArchive(ctx, exportID)
+	if err != nil {
+		s.logger.Errorf("Failed when open archive of export %s %v", id, err)
+		return nil, errors.Wrap(err, "Failed when open archive")
+	}
+	return archive, nil
+}
+
+// DeleteAccount requests the deletion of a user, the account is pending deletion at once
+// and erased once the grace has passed
+func (s *Service) DeleteAccount(ctx context.Context, idUser string) (*types.DeleteAccountResponse, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteAt, err := s.repo.ScheduleDeletion(ctx, userID, time.Now().Add(s.conf.Account.DeletionGrace))
+	if err != nil {
+		s.logger.Errorf("Failed when schedule deletion of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when schedule deletion")
+	}
+
+	// an account pending deletion doesn't chat anymore
+	s.disconnector.DisconnectUser(userID)
+
+	s.logger.Infof("Deletion of %s requested for %v", idUser, deleteAt)
+	return &types.DeleteAccountResponse{DeleteAt: deleteAt}, nil
+}
+
+// RestoreAccount cancels the requested deletion of a user during the grace
+func (s *Service) RestoreAccount(ctx context.Context, idUser string) error {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.repo.CancelDeletion(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Failed when cancel deletion of %s %v", idUser, err)
+		return errors.Wrap(err, "Failed when cancel deletion")
+	}
+	if !ok {
+		return ErrNotFound
+	}
+
+	s.logger.Infof("Deletion of %s cancelled", idUser)
+	return nil
+}
+
+// PauseAccount hides a user from discovery, the user keeps chatting with their matches
+func (s *Service) PauseAccount(ctx context.Context, idUser string) error {
+	return s.setStatus(ctx, idUser, types.StatusActive, types.StatusPaused)
+}
+
+// ResumeAccount shows a paused user in discovery again
+func (s *Service) ResumeAccount(ctx context.Context, idUser string) error {
+	return s.setStatus(ctx, idUser, types.StatusPaused, types.StatusActive)
+}
+
+func (s *Service) setStatus(ctx context.Context, idUser, from, to string) error {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.repo.SetStatus(ctx, userID, from, to)
+	if err != nil {
+		s.logger.Errorf("Failed when set status of %s to %s %v", idUser, to, err)
+		return errors.Wrap(err, "Failed when set status")
+	}
+	if !ok {
+		return ErrStatusConflict
+	}
+
+	s.logger.Infof("Account %s is %s", idUser, to)
+	return nil
+}
+
+// RunErasure erases the accounts past their grace and deletes the expired exports every
+// interval until ctx is done, only the replica holding the lock does the work
+func (s *Service) RunErasure(ctx context.Context, locker Locker) {
+	interval := s.conf.Account.ErasureInterval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// the lease outlives the interval so the holder keeps it on its next tick
+		ok, err := locker.Lock(ctx, erasureLock, 2*interval)
+		if err != nil {
+			s.logger.Errorf("Can't take the account erasure lock %v", err)
+		}
+		if ok {
+			s.erase(ctx, time.Now())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) erase(ctx context.Context, now time.Time) {
+	users, err := s.repo.FindDueDeletions(ctx, now, erasureBatch)
+	if err != nil {
+		s.logger.Errorf("Can't find accounts to erase %v", err)
+	}
+	for _, userID := range users {
+		rooms, err := s.repo.Erase(ctx, userID)
+		if err != nil {
+			s.logger.Errorf("Can't erase account %s %v", userID.Hex(), err)
+			continue
+		}
+		// the matches are gone, so are their chats
+		for _, room := range rooms {
+			s.disconnector.CloseRoom(room)
+		}
+		s.logger.Infof("Account %s erased", userID.Hex())
+	}
+
+	exports, err := s.repo.DeleteExpiredExports(ctx, now)
+	if err != nil {
+		s.logger.Errorf("Can't delete expired exports %v", err)
+	}
+	if exports > 0 {
+		s.logger.Infof("Deleted %d expired exports", exports)
+	}
+}
+
+// downloadURL is the path an export archive is downloaded from
+func downloadURL(id primitive.ObjectID) string {
+	return "/users/me/export/" + id.Hex()
+}
+
+// mediaExt is the file extension of a media link
+func mediaExt(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return path.Ext(u.Path)
+}
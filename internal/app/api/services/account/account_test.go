@@ -0,0 +1,107 @@
+package accountservices
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/glog"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type exportRepo struct {
+	Repository
+	data    types.ExportData
+	archive []byte
+	status  string
+}
+
+func (r *exportRepo) FindExportData(ctx context.Context, idUser primitive.ObjectID) (*types.ExportData, error) {
+	data := r.data
+	return &data, nil
+}
+
+func (r *exportRepo) UploadArchive(ctx context.Context, id primitive.ObjectID, archive io.Reader) error {
+	var err error
+	r.archive, err = ioutil.ReadAll(archive)
+	return err
+}
+
+func (r *exportRepo) UpdateExportStatus(ctx context.Context, id primitive.ObjectID, status string) error {
+	r.status = status
+	return nil
+}
+
+func TestBuildExport(t *testing.T) {
+	media := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/avatar.jpg":
+			w.Write([]byte("jpeg"))
+		case "/big.jpg":
+			w.Write(bytes.Repeat([]byte("jpeg"), 100))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer media.Close()
+
+	repo := &exportRepo{}
+	repo.data.Profile.Name = "Lan"
+	insecure := "http" + strings.TrimPrefix(media.URL, "https") + "/avatar.jpg"
+	repo.data.Profile.Media = []string{media.URL + "/avatar.jpg", media.URL + "/gone.png", insecure, "https://example.com/avatar.jpg", media.URL + "/big.jpg"}
+	repo.data.Messages = []*types.Message{{Content: "hi"}}
+
+	conf := &config.Configs{}
+	conf.Account.MediaTimeout = time.Second
+	conf.Media.Hosts = []string{"127.0.0.1"}
+	conf.Account.MediaMaxBytes = 64
+	s := NewService(conf, &config.ErrorMessage{}, repo, nil, glog.New())
+	if _, err := s.client.Get(media.URL + "/avatar.jpg"); err == nil || !strings.Contains(err.Error(), "not public") {
+		t.Errorf("media of a loopback address fetched, err = %v", err)
+	}
+	// the test server is on a loopback address
+	s.client = media.Client()
+	s.buildExport(context.Background(), types.Export{ID: primitive.NewObjectID()})
+
+	if repo.status != types.ExportReady {
+		t.Fatalf("status = %s; expected %s;", repo.status, types.ExportReady)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(repo.archive), int64(len(repo.archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name], _ = ioutil.ReadAll(rc)
+		rc.Close()
+	}
+	if string(files["media/1.jpg"]) != "jpeg" {
+		t.Errorf("media/1.jpg = %q; expected %q;", files["media/1.jpg"], "jpeg")
+	}
+
+	var data types.ExportData
+	if err := json.Unmarshal(files["data.json"], &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Profile.Name != "Lan" || len(data.Messages) != 1 {
+		t.Errorf("data.json profile %q with %d messages; expected %q with 1;", data.Profile.Name, len(data.Messages), "Lan")
+	}
+	if len(data.Media) != 1 || len(data.MissingMedia) != 4 || data.MissingMedia[0] != media.URL+"/gone.png" {
+		t.Errorf("media %v, missing %v; expected [media/1.jpg], [%s/gone.png %s https://example.com/avatar.jpg %[3]s/big.jpg];", data.Media, data.MissingMedia, media.URL, insecure)
+	}
+}
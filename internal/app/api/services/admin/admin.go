@@ -0,0 +1,375 @@
+package adminservices
+
+import (
+	"context"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/glog"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultPageSize    = 20
+	maxPageSize        = 100
+	recentMessagesSize = 50
+
+	auditDisableUser   = "disable_user"
+	auditBanUser       = "ban_user"
+	auditEnableUser    = "enable_user"
+	auditSetRole       = "set_role"
+	auditDeleteMessage = "delete_message"
+	auditDeleteMedia   = "delete_media"
+	auditReviewReport  = "review_report"
+
+	auditReviewVerification = "review_verification"
+)
+
+var (
+	// ErrSelf is returned when a moderator acts on their own account
+	ErrSelf = apperr.NewForbidden("", "can't moderate yourself")
+	// ErrStaff is returned when a moderator acts on a moderator or an admin, or lifts a ban
+	ErrStaff = apperr.NewForbidden("", "only an admin can do this")
+)
+
+// Repository is an interface of an admin repository
+type Repository interface {
+	FindReports(ctx context.Context, status string, skip, limit int64) ([]*types.Report, error)
+	UpdateReportStatus(ctx context.Context, id primitive.ObjectID, status string) error
+	FindUserByID(ctx context.Context, id primitive.ObjectID) (*types.User, error)
+	FindMessagesBySender(ctx context.Context, idUser primitive.ObjectID, limit int64) ([]*types.Message, error)
+	SetModeration(ctx context.Context, id primitive.ObjectID, moderation types.Moderation) error
+	ClearModeration(ctx context.Context, id primitive.ObjectID) error
+	SetRole(ctx context.Context, id primitive.ObjectID, role string) error
+	DeleteMessage(ctx context.Context, id primitive.ObjectID) (*types.Message, error)
+	PullMedia(ctx context.Context, idUser primitive.ObjectID, url string) error
+	InsertAuditLog(ctx context.Context, log types.AuditLog) error
+	FindAuditLogs(ctx context.Context, idTarget string, skip, limit int64) ([]*types.AuditLog, error)
+	FindVerifications(ctx context.Context, status string, skip, limit int64) ([]*types.VerificationRequest, error)
+	ReviewVerification(ctx context.Context, id primitive.ObjectID, status, reason string, idReviewer primitive.ObjectID, now time.Time) (*types.VerificationRequest, error)
+	SetVerified(ctx context.Context, idUser primitive.ObjectID, now time.Time) error
+}
+
+// Disconnector is an interface to close the socket connections of a user
+type Disconnector interface {
+	DisconnectUser(idUser primitive.ObjectID)
+}
+
+// Notifier is an interface to notify a user
+type Notifier interface {
+	Notify(ctx context.Context, idUser, idActor primitive.ObjectID, kind string, data interface{}) error
+}
+
+// Service is an admin service, every action of a moderator is written to the audit log
+type Service struct {
+	conf         *config.Configs
+	em           *config.ErrorMessage
+	repo         Repository
+	disconnector Disconnector
+	notifier     Notifier
+	logger       glog.Logger
+}
+
+// NewService returns a new admin service
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, d Disconnector, n Notifier, l glog.Logger) *Service {
+	return &Service{
+		conf:         c,
+		em:           e,
+		repo:         r,
+		disconnector: d,
+		notifier:     n,
+		logger:       l,
+	}
+}
+
+// audit writes an entry of the audit log
+func (s *Service) audit(ctx context.Context, idActor string, action, targetType string, targetID primitive.ObjectID, reason string, data interface{}) error {
+	actorID, err := primitive.ObjectIDFromHex(idActor)
+	if err != nil {
+		return err
+	}
+	log := types.AuditLog{
+		ID:         primitive.NewObjectID(),
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		Data:       data,
+		CreateAt:   time.Now(),
+	}
+	if err := s.repo.InsertAuditLog(ctx, log); err != nil {
+		s.logger.Errorf("Can't insert audit log %v", err)
+		return errors.Wrap(err, "Can't insert audit log")
+	}
+	s.logger.Infof("Moderator %s did %s on %s %s", idActor, action, targetType, targetID.Hex())
+	return nil
+}
+
+// Get reports by status, empty status means all
+func (s *Service) GetReports(ctx context.Context, status, page, size string) ([]types.Report, error) {
+	pageInt, sizeInt, err := types.ParsePage(page, size, defaultPageSize, maxPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.repo.FindReports(ctx, status, int64((pageInt-1)*sizeInt), int64(sizeInt))
+	if err != nil {
+		s.logger.Errorf("Failed when get reports %v", err)
+		return nil, errors.Wrap(err, "Failed when get reports")
+	}
+
+	reports := []types.Report{}
+	for _, report := range list {
+		reports = append(reports, *report)
+	}
+	return reports, nil
+}
+
+// Update status of a report after review
+func (s *Service) UpdateReportStatus(ctx context.Context, idActor, id string, req types.ReportStatusRequest) error {
+	reportID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdateReportStatus(ctx, reportID, req.Status); err != nil {
+		s.logger.Errorf("Failed when update report %s %v", id, err)
+		return err
+	}
+	return s.audit(ctx, idActor, auditReviewReport, "report", reportID, req.Reason, req.Status)
+}
+
+// Get the full record and the recent messages of a user
+func (s *Service) GetUser(ctx context.Context, id string) (*types.AdminUserResponse, error) {
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Not found id user %v", err)
+		return nil, errors.Wrap(err, "Failed to find id user from database")
+	}
+	user.Password = ""
+
+	list, err := s.repo.FindMessagesBySender(ctx, userID, recentMessagesSize)
+	if err != nil {
+		s.logger.Errorf("Failed when get messages of user %v", err)
+		return nil, errors.Wrap(err, "Failed when get messages of user")
+	}
+	messages := []types.Message{}
+	for _, message := range list {
+		messages = append(messages, *message)
+	}
+
+	return &types.AdminUserResponse{
+		User:           *user,
+		RecentMessages: messages,
+	}, nil
+}
+
+// Suspend a user, until nil means until enabled again
+func (s *Service) DisableUser(ctx context.Context, idActor, id string, req types.ModerationRequest) error {
+	if req.Until != nil && req.Until.Before(time.Now()) {
+		return apperr.NewValidation("", "until must be in the future")
+	}
+	return s.moderate(ctx, idActor, id, types.ModerationDisable, auditDisableUser, req.Reason, req.Until)
+}
+
+// Ban a user permanently
+func (s *Service) BanUser(ctx context.Context, idActor, id string, req types.ReasonRequest) error {
+	return s.moderate(ctx, idActor, id, types.ModerationBan, auditBanUser, req.Reason, nil)
+}
+
+func (s *Service) moderate(ctx context.Context, idActor, id, action, auditAction, reason string, until *time.Time) error {
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	actorID, err := primitive.ObjectIDFromHex(idActor)
+	if err != nil {
+		return err
+	}
+	if userID == actorID {
+		return ErrSelf
+	}
+	actor, target, err := s.findActorAndTarget(ctx, actorID, userID)
+	if err != nil {
+		return err
+	}
+	if (target.Role == types.RoleModerator || target.Role == types.RoleAdmin) && actor.Role != types.RoleAdmin {
+		s.logger.Infof("Moderator %s can't %s %s %s", idActor, action, target.Role, id)
+		return ErrStaff
+	}
+	moderation := types.Moderation{
+		Action: action,
+		Reason: reason,
+		Until:  until,
+		By:     actorID,
+		At:     time.Now(),
+	}
+	if err := s.repo.SetModeration(ctx, userID, moderation); err != nil {
+		s.logger.Errorf("Failed when %s user %s %v", action, id, err)
+		return err
+	}
+	// issued tokens stop working once the cached status expires, sockets are closed now
+	s.disconnector.DisconnectUser(userID)
+	return s.audit(ctx, idActor, auditAction, "user", userID, reason, moderation)
+}
+
+// findActorAndTarget finds the moderator and the user they act on
+func (s *Service) findActorAndTarget(ctx context.Context, actorID, userID primitive.ObjectID) (*types.User, *types.User, error) {
+	actor, err := s.repo.FindUserByID(ctx, actorID)
+	if err != nil {
+		s.logger.Errorf("Not found moderator %s %v", actorID.Hex(), err)
+		return nil, nil, errors.Wrap(err, "Failed to find moderator")
+	}
+	target, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Not found id user %s %v", userID.Hex(), err)
+		return nil, nil, errors.Wrap(err, "Failed to find id user")
+	}
+	return actor, target, nil
+}
+
+// Enable a suspended or banned user, only an admin lifts a ban as only an admin bans
+func (s *Service) EnableUser(ctx context.Context, idActor, id string, req types.ReasonRequest) error {
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	actorID, err := primitive.ObjectIDFromHex(idActor)
+	if err != nil {
+		return err
+	}
+	actor, target, err := s.findActorAndTarget(ctx, actorID, userID)
+	if err != nil {
+		return err
+	}
+	if target.Moderation != nil && target.Moderation.Action == types.ModerationBan && actor.Role != types.RoleAdmin {
+		s.logger.Infof("Moderator %s can't lift the ban of %s", idActor, id)
+		return ErrStaff
+	}
+	if err := s.repo.ClearModeration(ctx, userID); err != nil {
+		s.logger.Errorf("Failed when enable user %s %v", id, err)
+		return err
+	}
+	return s.audit(ctx, idActor, auditEnableUser, "user", userID, req.Reason, nil)
+}
+
+// Set role of a user, authentication reads the stored role so it applies once the cached
+// status of the user expires
+func (s *Service) SetRole(ctx context.Context, idActor, id string, req types.RoleRequest) error {
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.SetRole(ctx, userID, req.Role); err != nil {
+		s.logger.Errorf("Failed when set role of user %s %v", id, err)
+		return err
+	}
+	return s.audit(ctx, idActor, auditSetRole, "user", userID, "", req.Role)
+}
+
+// Delete a message
+func (s *Service) DeleteMessage(ctx context.Context, idActor, id string, req types.ReasonRequest) error {
+	messageID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	message, err := s.repo.DeleteMessage(ctx, messageID)
+	if err != nil {
+		s.logger.Errorf("Failed when delete message %s %v", id, err)
+		return err
+	}
+	// keep the content in the audit log as evidence
+	return s.audit(ctx, idActor, auditDeleteMessage, "message", messageID, req.Reason, message)
+}
+
+// Delete a media of a user
+func (s *Service) DeleteMedia(ctx context.Context, idActor, id string, req types.DeleteMediaRequest) error {
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.PullMedia(ctx, userID, req.URL); err != nil {
+		s.logger.Errorf("Failed when delete media of user %s %v", id, err)
+		return err
+	}
+	return s.audit(ctx, idActor, auditDeleteMedia, "user", userID, req.Reason, req.URL)
+}
+
+// Get the audit log, newest first, optionally of one target
+func (s *Service) GetAuditLogs(ctx context.Context, idTarget, page, size string) ([]types.AuditLog, error) {
+	pageInt, sizeInt, err := types.ParsePage(page, size, defaultPageSize, maxPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.repo.FindAuditLogs(ctx, idTarget, int64((pageInt-1)*sizeInt), int64(sizeInt))
+	if err != nil {
+		s.logger.Errorf("Failed when get audit logs %v", err)
+		return nil, errors.Wrap(err, "Failed when get audit logs")
+	}
+
+	logs := []types.AuditLog{}
+	for _, log := range list {
+		logs = append(logs, *log)
+	}
+	return logs, nil
+}
+
+// Get photo verification requests by status, oldest first, empty status means all
+func (s *Service) GetVerifications(ctx context.Context, status, page, size string) ([]types.VerificationRequest, error) {
+	pageInt, sizeInt, err := types.ParsePage(page, size, defaultPageSize, maxPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.repo.FindVerifications(ctx, status, int64((pageInt-1)*sizeInt), int64(sizeInt))
+	if err != nil {
+		s.logger.Errorf("Failed when get verifications %v", err)
+		return nil, errors.Wrap(err, "Failed when get verifications")
+	}
+
+	verifications := []types.VerificationRequest{}
+	for _, verification := range list {
+		verifications = append(verifications, *verification)
+	}
+	return verifications, nil
+}
+
+// Approve or reject a pending photo verification request, the user gets the verified badge
+// once approved
+func (s *Service) ReviewVerification(ctx context.Context, idActor, id string, req types.VerificationReviewRequest) error {
+	requestID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	actorID, err := primitive.ObjectIDFromHex(idActor)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	request, err := s.repo.ReviewVerification(ctx, requestID, req.Status, req.Reason, actorID, now)
+	if err != nil {
+		s.logger.Errorf("Failed when review verification %s %v", id, err)
+		return err
+	}
+	if req.Status == types.VerificationApproved {
+		if err := s.repo.SetVerified(ctx, request.UserID, now); err != nil {
+			s.logger.Errorf("Failed when set verified of user %s %v", request.UserID.Hex(), err)
+			return err
+		}
+	}
+
+	if err := s.notifier.Notify(ctx, request.UserID, actorID, types.NotificationVerified, request); err != nil {
+		s.logger.Errorf("Failed when notify review of verification %s %v", id, err)
+	}
+	return s.audit(ctx, idActor, auditReviewVerification, "verification", requestID, req.Reason, req.Status)
+}
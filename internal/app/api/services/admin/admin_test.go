@@ -0,0 +1,70 @@
+package adminservices
+
+import (
+	"context"
+	"testing"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/glog"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type moderationRepo struct {
+	Repository
+	users   map[primitive.ObjectID]*types.User
+	cleared bool
+	set     bool
+}
+
+func (r *moderationRepo) FindUserByID(ctx context.Context, id primitive.ObjectID) (*types.User, error) {
+	return r.users[id], nil
+}
+
+func (r *moderationRepo) ClearModeration(ctx context.Context, id primitive.ObjectID) error {
+	r.cleared = true
+	return nil
+}
+
+func (r *moderationRepo) SetModeration(ctx context.Context, id primitive.ObjectID, moderation types.Moderation) error {
+	r.set = true
+	return nil
+}
+
+func (r *moderationRepo) InsertAuditLog(ctx context.Context, log types.AuditLog) error {
+	return nil
+}
+
+func TestModerationRoles(t *testing.T) {
+	moderator, admin, banned, other := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	repo := &moderationRepo{users: map[primitive.ObjectID]*types.User{
+		moderator: {ID: moderator, Role: types.RoleModerator},
+		admin:     {ID: admin, Role: types.RoleAdmin},
+		banned:    {ID: banned, Role: types.RoleUser, Moderation: &types.Moderation{Action: types.ModerationBan}},
+		other:     {ID: other, Role: types.RoleModerator},
+	}}
+	s := NewService(&config.Configs{}, &config.ErrorMessage{}, repo, nil, nil, glog.New())
+	ctx := context.Background()
+
+	if err := s.EnableUser(ctx, moderator.Hex(), banned.Hex(), types.ReasonRequest{}); err != ErrStaff || repo.cleared {
+		t.Errorf("moderator enabling a banned user = %v, cleared %v; expected %v, false", err, repo.cleared, ErrStaff)
+	}
+	if err := s.EnableUser(ctx, admin.Hex(), banned.Hex(), types.ReasonRequest{}); err != nil || !repo.cleared {
+		t.Errorf("admin enabling a banned user = %v, cleared %v; expected nil, true", err, repo.cleared)
+	}
+
+	req := types.ModerationRequest{Reason: "spam"}
+	if err := s.DisableUser(ctx, moderator.Hex(), other.Hex(), req); err != ErrStaff {
+		t.Errorf("moderator disabling a moderator = %v; expected %v", err, ErrStaff)
+	}
+	if err := s.DisableUser(ctx, moderator.Hex(), admin.Hex(), req); err != ErrStaff {
+		t.Errorf("moderator disabling an admin = %v; expected %v", err, ErrStaff)
+	}
+	if err := s.DisableUser(ctx, moderator.Hex(), moderator.Hex(), req); err != ErrSelf {
+		t.Errorf("moderator disabling themselves = %v; expected %v", err, ErrSelf)
+	}
+	if repo.set {
+		t.Error("moderation set by a refused action")
+	}
+}
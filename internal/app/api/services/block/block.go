@@ -0,0 +1,140 @@
+package blockservices
+
+import (
+	"context"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/glog"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrSelf is returned when a user tries to block or report their own account
+	ErrSelf = apperr.NewValidation("", "can't block or report yourself")
+)
+
+// Repository is an interface of a block repository
+type Repository interface {
+	Block(ctx context.Context, idUser, idTargetUser primitive.ObjectID) error
+	Unblock(ctx context.Context, idUser, idTargetUser primitive.ObjectID) error
+	DeleteMatchesBetween(ctx context.Context, idUser, idTargetUser primitive.ObjectID) ([]*types.Match, error)
+	InsertReport(ctx context.Context, report types.Report) error
+}
+
+// RoomCloser is an interface to close a chat room and its open connections
+type RoomCloser interface {
+	CloseRoom(id primitive.ObjectID)
+}
+
+// Service is a block service
+type Service struct {
+	conf   *config.Configs
+	em     *config.ErrorMessage
+	repo   Repository
+	rooms  RoomCloser
+	logger glog.Logger
+}
+
+// NewService returns a new block service
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, rc RoomCloser, l glog.Logger) *Service {
+	return &Service{
+		conf:   c,
+		em:     e,
+		repo:   r,
+		rooms:  rc,
+		logger: l,
+	}
+}
+
+// parsePair converts the ids of the caller and the target user
+func parsePair(idUser, idTargetUser string) (primitive.ObjectID, primitive.ObjectID, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return userID, userID, err
+	}
+	targetUserID, err := primitive.ObjectIDFromHex(idTargetUser)
+	if err != nil {
+		return userID, targetUserID, err
+	}
+	if userID == targetUserID {
+		return userID, targetUserID, ErrSelf
+	}
+	return userID, targetUserID, nil
+}
+
+// Block a user, it ends any like or match between the users and closes their chat room
+func (s *Service) Block(ctx context.Context, idUser, idTargetUser string) error {
+	userID, targetUserID, err := parsePair(idUser, idTargetUser)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Block(ctx, userID, targetUserID); err != nil {
+		s.logger.Errorf("Can't block user %s %v", idTargetUser, err)
+		return errors.Wrap(err, "Can't block user")
+	}
+
+	matches, err := s.repo.DeleteMatchesBetween(ctx, userID, targetUserID)
+	if err != nil {
+		s.logger.Errorf("Can't delete matches of blocked user %s %v", idTargetUser, err)
+		return errors.Wrap(err, "Can't delete matches of blocked user")
+	}
+	for _, match := range matches {
+		if match.Matched {
+			s.rooms.CloseRoom(match.ID)
+		}
+	}
+
+	s.logger.Infof("User %s blocked %s", idUser, idTargetUser)
+	return nil
+}
+
+// Unblock a user, ended matches are not restored
+func (s *Service) Unblock(ctx context.Context, idUser, idTargetUser string) error {
+	userID, targetUserID, err := parsePair(idUser, idTargetUser)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Unblock(ctx, userID, targetUserID); err != nil {
+		s.logger.Errorf("Can't unblock user %s %v", idTargetUser, err)
+		return errors.Wrap(err, "Can't unblock user")
+	}
+
+	s.logger.Infof("User %s unblocked %s", idUser, idTargetUser)
+	return nil
+}
+
+// Report a user for later review by moderators
+func (s *Service) Report(ctx context.Context, idUser, idTargetUser string, req types.ReportRequest) error {
+	userID, targetUserID, err := parsePair(idUser, idTargetUser)
+	if err != nil {
+		return err
+	}
+
+	if req.MessageIDs == nil {
+		req.MessageIDs = []primitive.ObjectID{}
+	}
+	report := types.Report{
+		ID:         primitive.NewObjectID(),
+		ReporterID: userID,
+		ReportedID: targetUserID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+		MessageIDs: req.MessageIDs,
+		Status:     types.ReportStatusOpen,
+		CreateAt:   time.Now(),
+	}
+	if err := s.repo.InsertReport(ctx, report); err != nil {
+		s.logger.Errorf("Can't insert report %v", err)
+		return errors.Wrap(err, "Can't insert report")
+	}
+
+	s.logger.Infof("User %s reported %s", idUser, idTargetUser)
+	return nil
+}
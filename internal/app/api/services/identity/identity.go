@@ -0,0 +1,344 @@
+package identityservices
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/jwt"
+	"dating/internal/pkg/oidc"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrUnknownProvider is returned when the provider isn't configured
+	ErrUnknownProvider = apperr.NewNotFound("unknown provider")
+	// ErrInvalidLogin is returned when a callback doesn't complete a login started here,
+	// e.g. an expired state or an id token which isn't valid
+	ErrInvalidLogin = apperr.NewValidation("invalid_value.invalid_login", "invalid login")
+	// ErrEmailExists is returned when a new identity has the email of an account, the user
+	// must login to that account and link the provider first
+	ErrEmailExists = apperr.NewConflict("invalid_value.email_exists", "email exists")
+	// ErrIdentityConflict is returned when the identity is linked to another user or the
+	// user already has another identity at the provider
+	ErrIdentityConflict = apperr.NewConflict("invalid_value.identity_conflict", "identity conflict")
+	// ErrLastLogin is returned when unlinking the only way left to login
+	ErrLastLogin = apperr.NewConflict("invalid_value.last_login", "last login")
+	// ErrNotFound is returned when the user has no identity at the provider
+	ErrNotFound = apperr.NewNotFound("not found")
+	// ErrAccountSuspended is returned when a suspended user logs in
+	ErrAccountSuspended = apperr.NewForbidden("invalid_value.account_suspended", "account is suspended")
+	// ErrAccountBanned is returned when a banned user logs in
+	ErrAccountBanned = apperr.NewForbidden("invalid_value.account_banned", "account is banned")
+	// ErrProviderFailed is returned when the provider can't be reached
+	ErrProviderFailed = apperr.New(apperr.BadGateway, "", "provider failed")
+)
+
+// Repository is an interface of an identity repository
+type Repository interface {
+	InsertState(ctx context.Context, state types.OIDCState) error
+	TakeState(ctx context.Context, id string, now time.Time) (*types.OIDCState, error)
+	FindByIdentity(ctx context.Context, provider, subject string) (*types.User, error)
+	FindByID(ctx context.Context, idUser primitive.ObjectID) (*types.User, error)
+	FindByEmail(ctx context.Context, email string) (*types.User, error)
+	Insert(ctx context.Context, user types.User) error
+	LinkIdentity(ctx context.Context, idUser primitive.ObjectID, identity types.Identity) (bool, error)
+	UnlinkIdentity(ctx context.Context, idUser primitive.ObjectID, provider string) (bool, error)
+}
+
+// Provider is an interface of an OpenID Connect provider
+type Provider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier string) (string, error)
+	Verify(ctx context.Context, raw, nonce string) (*oidc.Claims, error)
+}
+
+// Service is an identity service, users login with and link the providers by name
+type Service struct {
+	conf      *config.Configs
+	em        *config.ErrorMessage
+	repo      Repository
+	providers map[string]Provider
+	logger    glog.Logger
+}
+
+// NewService returns a new identity service
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, p map[string]Provider, l glog.Logger) *Service {
+	return &Service{
+		conf:      c,
+		em:        e,
+		repo:      r,
+		providers: p,
+		logger:    l,
+	}
+}
+
+// Start starts a login at a provider and returns the URL to open, the login links the
+// provider to the user idUser when given
+func (s *Service) Start(ctx context.Context, provider, idUser string) (string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	state := types.OIDCState{
+		Provider:  provider,
+		ExpiresAt: time.Now().Add(s.conf.OIDC.StateTTL),
+	}
+	if idUser != "" {
+		userID, err := primitive.ObjectIDFromHex(idUser)
+		if err != nil {
+			return "", err
+		}
+		state.UserID = &userID
+	}
+	for _, random := range []*string{&state.ID, &state.Nonce, &state.Verifier} {
+		var err error
+		if *random, err = oidc.NewRandom(); err != nil {
+			return "", err
+		}
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state.ID, state.Nonce, state.Verifier)
+	if err != nil {
+		s.logger.Errorf("Failed when start login at %s %v", provider, err)
+		return "", errors.Wrap(ErrProviderFailed, err.Error())
+	}
+	if err := s.repo.InsertState(ctx, state); err != nil {
+		s.logger.Errorf("Failed when insert login state %v", err)
+		return "", errors.Wrap(err, "Failed when insert login state")
+	}
+	return authURL, nil
+}
+
+// Callback completes a login started at a provider, the user of the identity is logged in,
+// signed up when new, or the identity is linked to the user who started the login
+func (s *Service) Callback(ctx context.Context, provider, code, stateID string) (*types.UserResponseSignUp, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	state, err := s.repo.TakeState(ctx, stateID, time.Now())
+	if err == mongo.ErrNoDocuments || (err == nil && state.Provider != provider) {
+		s.logger.Infof("Login at %s with an unknown state", provider)
+		return nil, ErrInvalidLogin
+	}
+	if err != nil {
+		s.logger.Errorf("Failed when take login state %v", err)
+		return nil, errors.Wrap(err, "Failed when take login state")
+	}
+
+	raw, err := p.Exchange(ctx, code, state.Verifier)
+	if err != nil {
+		s.logger.Errorf("Failed when exchange code at %s %v", provider, err)
+		return nil, errors.Wrap(ErrInvalidLogin, err.Error())
+	}
+	claims, err := p.Verify(ctx, raw, state.Nonce)
+	if err != nil {
+		s.logger.Errorf("Failed when verify id token of %s %v", provider, err)
+		return nil, errors.Wrap(ErrInvalidLogin, err.Error())
+	}
+
+	identity := types.Identity{
+		Provider: provider,
+		Subject:  claims.Subject,
+		LinkedAt: time.Now(),
+	}
+	if claims.EmailVerified {
+		identity.Email = claims.Email
+	}
+
+	var user *types.User
+	if state.UserID != nil {
+		user, err = s.link(ctx, *state.UserID, identity)
+	} else {
+		user, err = s.signIn(ctx, identity, claims.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.login(ctx, user)
+}
+
+// signIn finds the user of an identity, a new user is signed up with it
+func (s *Service) signIn(ctx context.Context, identity types.Identity, name string) (*types.User, error) {
+	user, err := s.repo.FindByIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		s.logger.Errorf("Failed when find user of identity %v", err)
+		return nil, errors.Wrap(err, "Failed when find user of identity")
+	}
+
+	// an account is never taken over by an email, its owner links the provider
+	if identity.Email != "" {
+		if _, err := s.repo.FindByEmail(ctx, identity.Email); err == nil {
+			s.logger.Infof("Identity of %s has the email of an account", identity.Provider)
+			return nil, ErrEmailExists
+		}
+	}
+	if name == "" {
+		name = strings.Split(identity.Email, "@")[0]
+	}
+	if name == "" {
+		name = identity.Provider + " user"
+	}
+
+	now := time.Now()
+	user = &types.User{
+		ID:         primitive.NewObjectID(),
+		Name:       name,
+		Email:      identity.Email,
+		Status:     types.StatusActive,
+		Role:       types.RoleUser,
+		Media:      []string{},
+		Hobby:      []string{},
+		Identities: []types.Identity{identity},
+		CreateAt:   now,
+		UpdateAt:   now,
+	}
+	if err := s.repo.Insert(ctx, *user); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// the same identity signed up at the same time
+			return nil, ErrIdentityConflict
+		}
+		s.logger.Errorf("Can't insert user of identity %v", err)
+		return nil, errors.Wrap(err, "Can't insert user")
+	}
+	s.logger.Infof("Register with %s completed %s", identity.Provider, user.ID.Hex())
+	return user, nil
+}
+
+// link links an identity to a user, linking it again is a no-op
+func (s *Service) link(ctx context.Context, userID primitive.ObjectID, identity types.Identity) (*types.User, error) {
+	owner, err := s.repo.FindByIdentity(ctx, identity.Provider, identity.Subject)
+	switch {
+	case err == nil && owner.ID == userID:
+		return owner, nil
+	case err == nil:
+		s.logger.Infof("Identity of %s is linked to another user", identity.Provider)
+		return nil, ErrIdentityConflict
+	case err != mongo.ErrNoDocuments:
+		s.logger.Errorf("Failed when find user of identity %v", err)
+		return nil, errors.Wrap(err, "Failed when find user of identity")
+	}
+
+	ok, err := s.repo.LinkIdentity(ctx, userID, identity)
+	if mongo.IsDuplicateKeyError(err) || (err == nil && !ok) {
+		return nil, ErrIdentityConflict
+	}
+	if err != nil {
+		s.logger.Errorf("Failed when link identity %v", err)
+		return nil, errors.Wrap(err, "Failed when link identity")
+	}
+
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Failed when find user %s %v", userID.Hex(), err)
+		return nil, errors.Wrap(err, "Failed when find user")
+	}
+	s.logger.Infof("%s linked to %s", identity.Provider, userID.Hex())
+	return user, nil
+}
+
+// login mints the token of a user like a login with a password does
+func (s *Service) login(ctx context.Context, user *types.User) (*types.UserResponseSignUp, error) {
+	status := user.AccountStatus().At(time.Now())
+	switch status {
+	case types.StatusSuspended:
+		s.logger.Infof("Suspended user tried to login %s", user.ID.Hex())
+		return nil, ErrAccountSuspended
+	case types.StatusBanned:
+		s.logger.Infof("Banned user tried to login %s", user.ID.Hex())
+		return nil, ErrAccountBanned
+	}
+
+	// the login waits for the second factor like a login with a password does
+	if user.TwoFactorEnabled() {
+		token, err := jwt.GenPurposeToken(user.ID, types.PurposeTwoFactor, s.conf.TwoFactor.TokenTTL)
+		if err != nil {
+			s.logger.Errorf("Can not gen two-factor token", err)
+			return nil, errors.Wrap(err, "Can't gen two-factor token")
+		}
+		return &types.UserResponseSignUp{
+			Name:           user.Name,
+			Email:          user.Email,
+			Status:         status,
+			TwoFactorToken: token}, nil
+	}
+
+	role := user.Role
+	if role == "" {
+		role = types.RoleUser
+	}
+	token, err := jwt.GenToken(types.UserFieldInToken{
+		ID:    user.ID,
+		Name:  user.Name,
+		Email: user.Email,
+		Role:  role}, s.conf.Jwt.Duration)
+	if err != nil {
+		s.logger.Errorf("Can not gen token", err)
+		return nil, errors.Wrap(err, "Can't gen token")
+	}
+	return &types.UserResponseSignUp{
+		Name:           user.Name,
+		Email:          user.Email,
+		Token:          token,
+		Status:         status,
+		TwoFactorSetup: s.conf.TwoFactor.Enforced(role)}, nil
+}
+
+// GetIdentities returns the identities linked to a user
+func (s *Service) GetIdentities(ctx context.Context, idUser string) ([]types.Identity, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Failed when find user %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when find user")
+	}
+	if user.Identities == nil {
+		return []types.Identity{}, nil
+	}
+	return user.Identities, nil
+}
+
+// Unlink unlinks the identity of a user at a provider, unless it is the only way left to login
+func (s *Service) Unlink(ctx context.Context, idUser, provider string) error {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.repo.UnlinkIdentity(ctx, userID, provider)
+	if err != nil {
+		s.logger.Errorf("Failed when unlink %s of %s %v", provider, idUser, err)
+		return errors.Wrap(err, "Failed when unlink identity")
+	}
+	if ok {
+		s.logger.Infof("%s unlinked from %s", provider, idUser)
+		return nil
+	}
+
+	identities, err := s.GetIdentities(ctx, idUser)
+	if err != nil {
+		return err
+	}
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			return ErrLastLogin
+		}
+	}
+	return ErrNotFound
+}
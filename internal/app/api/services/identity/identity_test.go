@@ -0,0 +1,180 @@
+package identityservices
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/oidc"
+	"dating/internal/pkg/oidc/oidctest"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryRepo keeps the users and states in memory
+type memoryRepo struct {
+	states map[string]types.OIDCState
+	users  []*types.User
+}
+
+func (r *memoryRepo) InsertState(ctx context.Context, state types.OIDCState) error {
+	r.states[state.ID] = state
+	return nil
+}
+
+func (r *memoryRepo) TakeState(ctx context.Context, id string, now time.Time) (*types.OIDCState, error) {
+	state, ok := r.states[id]
+	delete(r.states, id)
+	if !ok || !now.Before(state.ExpiresAt) {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &state, nil
+}
+
+func (r *memoryRepo) FindByIdentity(ctx context.Context, provider, subject string) (*types.User, error) {
+	for _, user := range r.users {
+		for _, identity := range user.Identities {
+			if identity.Provider == provider && identity.Subject == subject {
+				return user, nil
+			}
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memoryRepo) FindByID(ctx context.Context, idUser primitive.ObjectID) (*types.User, error) {
+	for _, user := range r.users {
+		if user.ID == idUser {
+			return user, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memoryRepo) FindByEmail(ctx context.Context, email string) (*types.User, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (r *memoryRepo) Insert(ctx context.Context, user types.User) error {
+	r.users = append(r.users, &user)
+	return nil
+}
+
+func (r *memoryRepo) LinkIdentity(ctx context.Context, idUser primitive.ObjectID, identity types.Identity) (bool, error) {
+	user, err := r.FindByID(ctx, idUser)
+	if err != nil {
+		return false, nil
+	}
+	for _, linked := range user.Identities {
+		if linked.Provider == identity.Provider {
+			return false, nil
+		}
+	}
+	user.Identities = append(user.Identities, identity)
+	return true, nil
+}
+
+func (r *memoryRepo) UnlinkIdentity(ctx context.Context, idUser primitive.ObjectID, provider string) (bool, error) {
+	user, err := r.FindByID(ctx, idUser)
+	if err != nil || (user.Password == "" && len(user.Identities) < 2) {
+		return false, nil
+	}
+	for i, identity := range user.Identities {
+		if identity.Provider == provider {
+			user.Identities = append(user.Identities[:i], user.Identities[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestLoginAndLink(t *testing.T) {
+	issuer, err := oidctest.NewIssuer("dating", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer issuer.Close()
+
+	conf := &config.Configs{}
+	conf.OIDC.StateTTL = time.Minute
+	conf.Jwt.Duration = time.Hour
+	repo := &memoryRepo{states: map[string]types.OIDCState{}}
+	providers := map[string]Provider{
+		"mock": oidc.New(issuer.Config("https://dating.example.com/auth/mock/callback"), http.DefaultClient),
+	}
+	s := NewService(conf, &config.ErrorMessage{}, repo, providers, glog.New())
+	ctx := context.Background()
+
+	// login follows the provider redirecting back to the callback
+	login := func(idUser string) (*types.UserResponseSignUp, error) {
+		authURL, err := s.Start(ctx, "mock", idUser)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		res, err := client.Get(authURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		location, err := res.Location()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Callback(ctx, "mock", location.Query().Get("code"), location.Query().Get("state"))
+	}
+
+	issuer.SignIn(oidctest.User{Subject: "1", Email: "lan@example.com", EmailVerified: true, Name: "Lan"})
+	first, err := login("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := login(""); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.users) != 1 || first.Token == "" || first.Status != types.StatusActive {
+		t.Fatalf("%d users, login %+v; expected 1 active user with a token", len(repo.users), first)
+	}
+	if _, err := s.Callback(ctx, "mock", "code", "unknown"); err != ErrInvalidLogin {
+		t.Errorf("Callback with an unknown state = %v; expected %v", err, ErrInvalidLogin)
+	}
+
+	// the email of an account isn't enough to login to it
+	password := &types.User{ID: primitive.NewObjectID(), Email: "minh@example.com", Password: "hash", Status: types.StatusActive}
+	repo.users = append(repo.users, password)
+	issuer.SignIn(oidctest.User{Subject: "2", Email: "minh@example.com", EmailVerified: true})
+	if _, err := login(""); err != ErrEmailExists {
+		t.Fatalf("login with the email of an account = %v; expected %v", err, ErrEmailExists)
+	}
+	if _, err := login(password.ID.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := login(""); err != nil {
+		t.Fatalf("login once linked = %v", err)
+	}
+
+	// a linked identity can't be linked to another user
+	issuer.SignIn(oidctest.User{Subject: "1"})
+	if _, err := login(password.ID.Hex()); err != ErrIdentityConflict {
+		t.Errorf("link of an identity of another user = %v; expected %v", err, ErrIdentityConflict)
+	}
+
+	if err := s.Unlink(ctx, password.ID.Hex(), "mock"); err != nil {
+		t.Errorf("Unlink with a password = %v", err)
+	}
+	if err := s.Unlink(ctx, repo.users[0].ID.Hex(), "mock"); err != ErrLastLogin {
+		t.Errorf("Unlink of the last login = %v; expected %v", err, ErrLastLogin)
+	}
+}
@@ -0,0 +1,85 @@
+package interestservices
+
+import (
+	"context"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/i18n"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrUnknownInterest is returned when an interest id isn't in the catalogue
+	ErrUnknownInterest = apperr.NewValidation("", "unknown interest")
+	// ErrTooManyInterests is returned when a user picks more interests than allowed
+	ErrTooManyInterests = apperr.NewValidation("", "too many interests")
+)
+
+// Service is an interest service, the catalogue is read from the configuration
+type Service struct {
+	conf   *config.Configs
+	em     *config.ErrorMessage
+	logger glog.Logger
+}
+
+// NewService returns a new interest service
+func NewService(c *config.Configs, e *config.ErrorMessage, l glog.Logger) *Service {
+	return &Service{
+		conf:   c,
+		em:     e,
+		logger: l,
+	}
+}
+
+// Get the interests catalogue labelled in the first of locales with a label, falling back to
+// the default locale
+func (s *Service) GetInterests(ctx context.Context, locales []string) []types.Interest {
+
+	chain := i18n.Chain(locales, s.conf.Interests.DefaultLocale)
+	list := []types.Interest{}
+	for _, interest := range s.conf.Interests.Catalogue {
+		label := interest.ID
+		for _, locale := range chain {
+			if l, ok := interest.Labels[locale]; ok {
+				label = l
+				break
+			}
+		}
+		list = append(list, types.Interest{
+			ID:       interest.ID,
+			Category: interest.Category,
+			Label:    label,
+		})
+	}
+	return list
+}
+
+// Check checks the interests picked by a user are in the catalogue and within the cap
+func (s *Service) Check(ids []string) error {
+
+	if len(ids) > s.conf.Interests.MaxPerUser {
+		s.logger.Infof("Picked %d interests, at most %d", len(ids), s.conf.Interests.MaxPerUser)
+		return ErrTooManyInterests
+	}
+	return s.Known(ids)
+}
+
+// Known checks the interests are in the catalogue
+func (s *Service) Known(ids []string) error {
+
+	known := make(map[string]bool, len(s.conf.Interests.Catalogue))
+	for _, interest := range s.conf.Interests.Catalogue {
+		known[interest.ID] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			s.logger.Infof("Unknown interest %s", id)
+			return errors.Wrap(ErrUnknownInterest, id)
+		}
+	}
+	return nil
+}
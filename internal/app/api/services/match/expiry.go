@@ -0,0 +1,138 @@
+package matchservices
+
+import (
+	"context"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/pkg/apperr"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	expiryLock  = "match-expiry"
+	expiryBatch = 500
+
+	// ExpiryActionStale keeps an expired match marked as stale instead of removing it
+	ExpiryActionStale = "stale"
+)
+
+var (
+	// ErrMatchNotFound is returned when the match doesn't exist or the user isn't part of it
+	ErrMatchNotFound = apperr.NewNotFound("match not found")
+	// ErrAlreadyExtended is returned when the match was extended before or has expired
+	ErrAlreadyExtended = apperr.NewConflict("invalid_value.already_extended", "match can't be extended")
+)
+
+// Locker is an interface of a lock shared by all replicas
+type Locker interface {
+	Lock(ctx context.Context, name string, ttl time.Duration) (bool, error)
+}
+
+// RunExpiry sweeps the matches nobody wrote in every interval until ctx is done,
+// only the replica holding the lock sweeps
+func (s *Service) RunExpiry(ctx context.Context, locker Locker) {
+	interval := s.conf.Match.Expiry.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// the lease outlives the interval so the holder keeps it on its next tick
+		ok, err := locker.Lock(ctx, expiryLock, 2*interval)
+		if err != nil {
+			s.logger.Errorf("Can't take the match expiry lock %v", err)
+		}
+		if ok {
+			s.sweepExpiry(ctx, time.Now())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepExpiry reminds both users of the matches nobody wrote in, then expires the
+// ones past their deadline
+func (s *Service) sweepExpiry(ctx context.Context, now time.Time) {
+	expiry := s.conf.Match.Expiry
+
+	reminders, err := s.repo.FindUnremindedMatches(ctx, now.Add(-expiry.ReminderAfter), expiryBatch)
+	if err != nil {
+		s.logger.Errorf("Can't find matches to remind %v", err)
+	}
+	for _, match := range reminders {
+		if err := s.repo.MarkReminded(ctx, match.ID.Hex()); err != nil {
+			s.logger.Errorf("Can't mark match %s reminded %v", match.ID.Hex(), err)
+			continue
+		}
+		s.notifyBoth(ctx, match, types.NotificationReminder)
+	}
+
+	matchedBefore := now.Add(-expiry.ExpireAfter)
+	found, err := s.repo.FindExpiredMatches(ctx, matchedBefore, now, expiryBatch)
+	if err != nil {
+		s.logger.Errorf("Can't find expired matches %v", err)
+	}
+	expired := 0
+	for _, match := range found {
+		// the match is checked again, a message or an extension may have come since
+		ok, err := s.repo.ExpireMatch(ctx, match.ID.Hex(), matchedBefore, now, expiry.Action == ExpiryActionStale)
+		if err != nil {
+			s.logger.Errorf("Can't expire match %s %v", match.ID.Hex(), err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if expiry.Action != ExpiryActionStale {
+			// the match is gone, so is its chat
+			s.rooms.CloseRoom(match.ID)
+		}
+		expired++
+		s.notifyBoth(ctx, match, types.NotificationExpired)
+	}
+
+	if len(reminders) > 0 || expired > 0 {
+		s.logger.Infof("Reminded %d and expired %d matches", len(reminders), expired)
+	}
+}
+
+// notifyBoth notifies each user of a match, the other user being the actor
+func (s *Service) notifyBoth(ctx context.Context, match *types.Match, kind string) {
+	s.notifier.Notify(ctx, match.UserID, match.TargetUserID, kind, match)
+	s.notifier.Notify(ctx, match.TargetUserID, match.UserID, kind, match)
+}
+
+// ExtendMatch pushes the deadline of a match of a user by the configured extension, once per match
+func (s *Service) ExtendMatch(ctx context.Context, id, idUser string) (*types.Match, error) {
+	match, err := s.repo.FindByID(ctx, id)
+	if err != nil || !match.Matched || (match.UserID.Hex() != idUser && match.TargetUserID.Hex() != idUser) {
+		s.logger.Infof("Match %s of %s not found %v", id, idUser, err)
+		return nil, ErrMatchNotFound
+	}
+	expiry := s.conf.Match.Expiry
+	deadline := match.Deadline(expiry.ExpireAfter)
+	if match.Extended || match.Stale || time.Now().After(deadline) {
+		return nil, ErrAlreadyExtended
+	}
+
+	expiresAt := deadline.Add(expiry.Extension)
+	ok, err := s.repo.ExtendMatch(ctx, id, idUser, expiresAt)
+	if err != nil {
+		s.logger.Errorf("Can't extend match %v", err)
+		return nil, errors.Wrap(err, "Can't extend match")
+	}
+	if !ok {
+		return nil, ErrAlreadyExtended
+	}
+
+	match.Extended = true
+	match.ExpiresAt = &expiresAt
+	s.logger.Infof("Match %s extended until %v", id, expiresAt)
+	return match, nil
+}
@@ -6,80 +6,229 @@ import (
 
 	"dating/internal/app/api/types"
 	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
 	"dating/internal/pkg/glog"
 
 	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	quotaSuperLike = "super_like"
+
+	// UnmatchArchive moves the messages of an ended match to the archive
+	UnmatchArchive = "archive"
+	// UnmatchPurge deletes the messages of an ended match
+	UnmatchPurge = "purge"
+)
+
+var (
+	// ErrQuotaExceeded is returned when the user has used all of the daily super likes
+	ErrQuotaExceeded = apperr.New(apperr.TooManyRequests, "invalid_value.quota_exceeded", "daily quota exceeded")
+	// ErrSelf is returned when a user likes their own account
+	ErrSelf = apperr.NewValidation("", "can't like yourself")
+	// ErrBlocked is returned when one of the users blocked the other
+	ErrBlocked = apperr.NewForbidden("", "user is blocked")
+	// ErrNotWanted is returned when the user doesn't fit the preferences of the target
+	ErrNotWanted = apperr.NewValidation("", "user doesn't fit the preferences of the target")
 )
 
 // Repository is an interface of a match repository
 type Repository interface {
 	Insert(ctx context.Context, Match types.Match) error
 	FindByID(ctx context.Context, id string) (*types.Match, error)
-	FindALikeB(ctx context.Context, idUser, idTargetUser string) (*types.Match, error)
-	UpdateMatchByID(ctx context.Context, id string) error
+	Like(ctx context.Context, like types.Match) (*types.Match, bool, error)
 	GetListLiked(ctx context.Context, idUser string) ([]*types.Match, error)
 	GetListMatched(ctx context.Context, idUser string) ([]*types.Match, error)
 	DeleteMatch(ctx context.Context, id string) error
-	UpsertMatch(ctx context.Context, match types.Match) error
 	CheckAB(ctx context.Context, idUser, idTargetUser string, matched bool) (*types.Match, error)
-	FindAMatchB(ctx context.Context, idUser, idTargetUser string) (*types.Match, error)
+	Unmatch(ctx context.Context, idUser, idTargetUser primitive.ObjectID) (*types.Match, error)
+	ArchiveMessages(ctx context.Context, idRoom primitive.ObjectID, expiresAt time.Time) error
+	PurgeMessages(ctx context.Context, idRoom primitive.ObjectID) error
 	FindRoomsByUserId(ctx context.Context, idUser string) ([]*types.MatchRoomResponse, error)
+	FindUserTimezone(ctx context.Context, idUser string) (string, error)
+	FindCandidate(ctx context.Context, idUser string) (*types.Candidate, error)
+	IsBlocked(ctx context.Context, idUser, idTargetUser string) (bool, error)
+	ListMatches(ctx context.Context, idUser string, ps types.PagingNSorting) ([]*types.MatchItem, int, error)
+	ListLikedMe(ctx context.Context, idUser string, ps types.PagingNSorting) ([]*types.LikeItem, int, error)
+	FindUnremindedMatches(ctx context.Context, matchedBefore time.Time, limit int) ([]*types.Match, error)
+	FindExpiredMatches(ctx context.Context, matchedBefore, now time.Time, limit int) ([]*types.Match, error)
+	MarkReminded(ctx context.Context, id string) error
+	ExpireMatch(ctx context.Context, id string, matchedBefore, now time.Time, stale bool) (bool, error)
+	ExtendMatch(ctx context.Context, id, idUser string, expiresAt time.Time) (bool, error)
+}
+
+// QuotaStore is an interface of a daily quota store
+type QuotaStore interface {
+	Take(ctx context.Context, idUser, kind, day string, limit int) (bool, error)
+	Refund(ctx context.Context, idUser, kind, day string) error
+}
+
+// Notifier is an interface to notify a user about an event
+type Notifier interface {
+	Notify(ctx context.Context, idUser, idActor primitive.ObjectID, kind string, data interface{}) error
+}
+
+// RoomCloser is an interface to close a chat room and its open connections
+type RoomCloser interface {
+	CloseRoom(id primitive.ObjectID)
 }
 
 // Service is an match service
 type Service struct {
-	conf   *config.Configs
-	em     *config.ErrorMessage
-	repo   Repository
-	logger glog.Logger
+	conf     *config.Configs
+	em       *config.ErrorMessage
+	repo     Repository
+	quota    QuotaStore
+	notifier Notifier
+	rooms    RoomCloser
+	logger   glog.Logger
 }
 
 // NewService returns a new match service
-func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, l glog.Logger) *Service {
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, q QuotaStore, n Notifier, rc RoomCloser, l glog.Logger) *Service {
 	return &Service{
-		conf:   c,
-		em:     e,
-		repo:   r,
-		logger: l,
+		conf:     c,
+		em:       e,
+		repo:     r,
+		quota:    q,
+		notifier: n,
+		rooms:    rc,
+		logger:   l,
 	}
 }
 
 // Post basic, user match someone
 func (s *Service) InsertMatch(ctx context.Context, matchreq types.MatchRequest) (*types.Match, error) {
-
-	// check user B like user A
-	matchcheckBA, err := s.repo.FindALikeB(ctx, matchreq.TargetUserID.Hex(), matchreq.UserID.Hex())
+	match, matchedNow, err := s.like(ctx, matchreq)
 	if err != nil {
-		match := types.Match{
-			UserID:       matchreq.UserID,
-			TargetUserID: matchreq.TargetUserID,
-			Matched:      false,
-			CreateAt:     time.Now(),
-		}
+		return nil, err
+	}
+	s.notifyLike(ctx, matchreq, match, matchedNow)
+	return match, nil
+}
 
-		err := s.repo.UpsertMatch(ctx, match)
-		if err != nil {
-			s.logger.Errorf("Can't update match", err)
-			return nil, errors.Wrap(err, "Can't update match")
-		}
+// like records A likes B, matchedNow is true when this like completed a match
+func (s *Service) like(ctx context.Context, matchreq types.MatchRequest) (*types.Match, bool, error) {
+	if err := s.checkLike(ctx, matchreq); err != nil {
+		return nil, false, err
+	}
+	return s.saveLike(ctx, matchreq)
+}
 
-		s.logger.Infof("A liked B before", matchreq)
-		return &match, nil
+// checkLike checks A may like B, A must fit the preferences of B as discovery only shows B
+// to the users B wants
+func (s *Service) checkLike(ctx context.Context, matchreq types.MatchRequest) error {
+	if matchreq.UserID == matchreq.TargetUserID {
+		return ErrSelf
+	}
+
+	blocked, err := s.repo.IsBlocked(ctx, matchreq.UserID.Hex(), matchreq.TargetUserID.Hex())
+	if err != nil {
+		s.logger.Errorf("Can't check block %v", err)
+		return errors.Wrap(err, "Can't check block")
 	}
-	// B liked A
-	if matchcheckBA.Matched {
-		s.logger.Infof("B, A matched before", matchreq)
-		return matchcheckBA, nil
+	if blocked {
+		s.logger.Infof("Can't like a blocked user %v", matchreq)
+		return ErrBlocked
 	}
-	if err := s.repo.UpdateMatchByID(ctx, matchcheckBA.ID.Hex()); err != nil {
+
+	liker, err := s.repo.FindCandidate(ctx, matchreq.UserID.Hex())
+	if err != nil {
+		s.logger.Errorf("Can't find user %s %v", matchreq.UserID.Hex(), err)
+		return errors.Wrap(err, "Can't find user")
+	}
+	target, err := s.repo.FindCandidate(ctx, matchreq.TargetUserID.Hex())
+	if err != nil {
+		s.logger.Errorf("Can't find target %s %v", matchreq.TargetUserID.Hex(), err)
+		return errors.Wrap(err, "Can't find target")
+	}
+	if !target.Preferences.Wants(liker.UserResGetInfo, time.Now()) {
+		s.logger.Infof("User doesn't fit the preferences of the target %v", matchreq)
+		return ErrNotWanted
+	}
+	return nil
+}
+
+// saveLike records a like that was checked
+func (s *Service) saveLike(ctx context.Context, matchreq types.MatchRequest) (*types.Match, bool, error) {
+
+	match, matchedNow, err := s.repo.Like(ctx, types.Match{
+		UserID:       matchreq.UserID,
+		TargetUserID: matchreq.TargetUserID,
+		SuperLike:    matchreq.SuperLike,
+		CreateAt:     time.Now(),
+	})
+	if err != nil {
 		s.logger.Errorf("Can't update match", err)
-		return nil, errors.Wrap(err, "Can't update match")
+		return nil, false, errors.Wrap(err, "Can't update match")
+	}
+
+	if matchedNow {
+		s.logger.Infof("Match completed", matchreq)
+	}
+	return match, matchedNow, nil
+}
+
+// notifyLike tells both users about a new match, or the target about a new like
+func (s *Service) notifyLike(ctx context.Context, matchreq types.MatchRequest, match *types.Match, matchedNow bool) {
+	if matchedNow {
+		s.notifier.Notify(ctx, matchreq.UserID, matchreq.TargetUserID, types.NotificationNewMatch, match)
+		s.notifier.Notify(ctx, matchreq.TargetUserID, matchreq.UserID, types.NotificationNewMatch, match)
+		return
+	}
+	if match.Matched {
+		return
+	}
+	kind := types.NotificationLikedYou
+	if matchreq.SuperLike {
+		kind = types.NotificationSuperLiked
+	}
+	s.notifier.Notify(ctx, matchreq.TargetUserID, matchreq.UserID, kind, match)
+}
+
+// Post super like, user super likes someone within the daily quota, the quota is only
+// used by a super like that was recorded
+func (s *Service) InsertSuperLike(ctx context.Context, matchreq types.MatchRequest) (*types.Match, error) {
+
+	matchreq.SuperLike = true
+	if err := s.checkLike(ctx, matchreq); err != nil {
+		return nil, err
 	}
 
-	matchcheckBA.Matched = true
+	// quota resets at the user's local midnight
+	tz, err := s.repo.FindUserTimezone(ctx, matchreq.UserID.Hex())
+	if err != nil {
+		s.logger.Errorf("Can't find user timezone %v", err)
+		return nil, errors.Wrap(err, "Can't find user timezone")
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		loc = time.UTC
+	}
+	day := time.Now().In(loc).Format("2006-01-02")
 
-	s.logger.Infof("Match completed", matchreq)
-	return matchcheckBA, nil
+	ok, err := s.quota.Take(ctx, matchreq.UserID.Hex(), quotaSuperLike, day, s.conf.Match.SuperLikeDailyQuota)
+	if err != nil {
+		s.logger.Errorf("Can't take super like quota %v", err)
+		return nil, errors.Wrap(err, "Can't take super like quota")
+	}
+	if !ok {
+		s.logger.Infof("Super like quota exceeded %v", matchreq)
+		return nil, ErrQuotaExceeded
+	}
+
+	match, matchedNow, err := s.saveLike(ctx, matchreq)
+	if err != nil {
+		if err := s.quota.Refund(ctx, matchreq.UserID.Hex(), quotaSuperLike, day); err != nil {
+			s.logger.Errorf("Can't refund super like quota %v", err)
+		}
+		return nil, err
+	}
+	s.notifyLike(ctx, matchreq, match, matchedNow)
+
+	s.logger.Infof("Super like completed %v", matchreq)
+	return match, nil
 }
 
 // Post basic help user unlike someone
@@ -99,19 +248,28 @@ func (s *Service) unlike(ctx context.Context, matchreq types.MatchRequest) error
 	return nil
 }
 
-// Post basic help user unMatch someone
+// Post basic help user unMatch someone, it ends the chat room, handles its messages
+// as configured and tells the other user
 func (s *Service) unmatched(ctx context.Context, matchreq types.MatchRequest) error {
-	// check user A matched user B
-	matchcheckAB, err := s.repo.FindAMatchB(ctx, matchreq.UserID.Hex(), matchreq.TargetUserID.Hex())
+	match, err := s.repo.Unmatch(ctx, matchreq.UserID, matchreq.TargetUserID)
 	if err != nil {
 		s.logger.Errorf("A B have not matched before", err)
 		return err
 	}
-	if err := s.repo.DeleteMatch(ctx, matchcheckAB.ID.Hex()); err != nil {
-		s.logger.Errorf("Can't del match", err)
-		return err
-	}
+	s.rooms.CloseRoom(match.ID)
 
+	unmatch := s.conf.Match.Unmatch
+	switch unmatch.Messages {
+	case UnmatchArchive:
+		err = s.repo.ArchiveMessages(ctx, match.ID, time.Now().Add(unmatch.Retention))
+	case UnmatchPurge:
+		err = s.repo.PurgeMessages(ctx, match.ID)
+	}
+	s.notifier.Notify(ctx, matchreq.TargetUserID, matchreq.UserID, types.NotificationUnmatched, match)
+	if err != nil {
+		s.logger.Errorf("Can't %s messages of room %s %v", unmatch.Messages, match.ID.Hex(), err)
+		return errors.Wrapf(err, "Can't %s messages", unmatch.Messages)
+	}
 	s.logger.Infof("Unmatched completed", matchreq)
 	return nil
 }
@@ -137,6 +295,68 @@ func (s *Service) FindRoomsByUserId(ctx context.Context, id string) ([]types.Mat
 	return s.convertPointerArrayToArrayRooms(listRooms), nil
 }
 
+// GetMatches returns a page of the matches of a user, the latest matched first by default
+func (s *Service) GetMatches(ctx context.Context, idUser, page, size, sort string) (*types.GetMatchesResponse, error) {
+	ps, err := listQuery(page, size, sort, "-matched_at", types.MatchSortFields)
+	if err != nil {
+		return nil, err
+	}
+	list, total, err := s.repo.ListMatches(ctx, idUser, ps)
+	if err != nil {
+		s.logger.Errorf("Failed when get list matches %v", err)
+		return nil, errors.Wrap(err, "Failed when get list matches")
+	}
+	// matches only expire until somebody writes
+	for _, item := range list {
+		switch {
+		case !s.conf.Match.Expiry.Enabled || item.LastMessage != nil || item.Stale:
+			item.ExpiresAt = nil
+		case item.ExpiresAt == nil:
+			expiresAt := item.MatchedAt.Add(s.conf.Match.Expiry.ExpireAfter)
+			item.ExpiresAt = &expiresAt
+		}
+	}
+	return &types.GetMatchesResponse{Pagination: pagination(ps, total), Matches: list}, nil
+}
+
+// GetLikes returns a page of the users who liked a user, super likes then the latest first by default
+func (s *Service) GetLikes(ctx context.Context, idUser, page, size, sort string) (*types.GetLikesResponse, error) {
+	ps, err := listQuery(page, size, sort, "-super_like,-liked_at", types.LikeSortFields)
+	if err != nil {
+		return nil, err
+	}
+	list, total, err := s.repo.ListLikedMe(ctx, idUser, ps)
+	if err != nil {
+		s.logger.Errorf("Failed when get list likes %v", err)
+		return nil, errors.Wrap(err, "Failed when get list likes")
+	}
+	return &types.GetLikesResponse{Pagination: pagination(ps, total), Likes: list}, nil
+}
+
+// listQuery parses the url parameters of a listing, sort falls back to defaultSort
+func listQuery(page, size, sort, defaultSort string, fields []string) (types.PagingNSorting, error) {
+	var ps types.PagingNSorting
+	var err error
+	if ps.Page, ps.Size, err = types.ParsePage(page, size, types.DefaultPageSize, types.MaxPageSize); err != nil {
+		return ps, err
+	}
+	if sort == "" {
+		sort = defaultSort
+	}
+	ps.Sort, err = types.ParseSort(sort, fields)
+	return ps, err
+}
+
+func pagination(ps types.PagingNSorting, total int) types.Pagination {
+	return types.Pagination{
+		TotalItems:      total,
+		TotalPages:      (total + ps.Size - 1) / ps.Size,
+		CurrentPage:     ps.Page,
+		MaxItemsPerPage: ps.Size,
+		Sort:            ps.SortString(),
+	}
+}
+
 // convert []*types.Rooms to []types.Rooms - if empty return []
 func (s *Service) convertPointerArrayToArrayRooms(list []*types.MatchRoomResponse) []types.MatchRoomResponse {
 
@@ -0,0 +1,155 @@
+package matchservices
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/glog"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pairRepo keeps the matches in memory keyed by pair key like the unique pair_key index
+type pairRepo struct {
+	Repository
+	matches map[string]*types.Match
+	blocked bool
+	likeErr error
+	// users by id, a missing user has no gender, birthday nor preferences
+	users map[string]*types.Candidate
+}
+
+func (r *pairRepo) IsBlocked(ctx context.Context, idUser, idTargetUser string) (bool, error) {
+	return r.blocked, nil
+}
+
+func (r *pairRepo) FindUserTimezone(ctx context.Context, idUser string) (string, error) {
+	return "", nil
+}
+
+func (r *pairRepo) FindCandidate(ctx context.Context, idUser string) (*types.Candidate, error) {
+	if user, ok := r.users[idUser]; ok {
+		return user, nil
+	}
+	return &types.Candidate{}, nil
+}
+
+func (r *pairRepo) Like(ctx context.Context, like types.Match) (*types.Match, bool, error) {
+	if r.likeErr != nil {
+		return nil, false, r.likeErr
+	}
+	key := types.PairKey(like.UserID, like.TargetUserID)
+	match, ok := r.matches[key]
+	switch {
+	case !ok:
+		like.ID = primitive.NewObjectID()
+		like.PairKey = key
+		r.matches[key] = &like
+		return &like, false, nil
+	case match.UserID == like.TargetUserID && !match.Matched:
+		match.Matched = true
+		return match, true, nil
+	}
+	return match, false, nil
+}
+
+// memoryQuota counts the quota taken by user
+type memoryQuota map[string]int
+
+func (q memoryQuota) Take(ctx context.Context, idUser, kind, day string, limit int) (bool, error) {
+	if q[idUser] >= limit {
+		return false, nil
+	}
+	q[idUser]++
+	return true, nil
+}
+
+func (q memoryQuota) Refund(ctx context.Context, idUser, kind, day string) error {
+	q[idUser]--
+	return nil
+}
+
+// discard drops the notifications
+type discard struct{}
+
+func (discard) Notify(ctx context.Context, idUser, idActor primitive.ObjectID, kind string, data interface{}) error {
+	return nil
+}
+
+func TestSuperLikeQuota(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+	conf := &config.Configs{}
+	conf.Match.SuperLikeDailyQuota = 1
+	repo := &pairRepo{matches: map[string]*types.Match{}}
+	quota := memoryQuota{}
+	s := NewService(conf, &config.ErrorMessage{}, repo, quota, discard{}, nil, glog.New())
+	like := types.MatchRequest{UserID: a, TargetUserID: b}
+
+	// a super like that fails doesn't use the quota
+	repo.blocked = true
+	if _, err := s.InsertSuperLike(context.Background(), like); err != ErrBlocked {
+		t.Errorf("InsertSuperLike of a blocked user = %v; expected %v", err, ErrBlocked)
+	}
+	repo.blocked, repo.likeErr = false, errors.New("connection reset")
+	if _, err := s.InsertSuperLike(context.Background(), like); err == nil {
+		t.Error("InsertSuperLike with a failing repository succeeded")
+	}
+	// a super like of yourself is rejected before the quota is taken
+	if _, err := s.InsertSuperLike(context.Background(), types.MatchRequest{UserID: a, TargetUserID: a}); err != ErrSelf {
+		t.Errorf("InsertSuperLike of yourself = %v; expected %v", err, ErrSelf)
+	}
+	if quota[a.Hex()] != 0 {
+		t.Fatalf("quota used by failed super likes = %d; expected 0", quota[a.Hex()])
+	}
+
+	repo.likeErr = nil
+	match, err := s.InsertSuperLike(context.Background(), like)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !match.SuperLike {
+		t.Error("super like not flagged")
+	}
+	if _, err := s.InsertSuperLike(context.Background(), like); err != ErrQuotaExceeded {
+		t.Errorf("InsertSuperLike past the quota = %v; expected %v", err, ErrQuotaExceeded)
+	}
+}
+
+func TestLikeOutsidePreferences(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+	repo := &pairRepo{matches: map[string]*types.Match{}, users: map[string]*types.Candidate{
+		a.Hex(): {UserResGetInfo: types.UserResGetInfo{Gender: "male"}},
+		b.Hex(): {Preferences: &types.Preferences{Genders: []string{"female"}}},
+	}}
+	s := NewService(&config.Configs{}, &config.ErrorMessage{}, repo, memoryQuota{}, discard{}, nil, glog.New())
+
+	if _, err := s.InsertMatch(context.Background(), types.MatchRequest{UserID: a, TargetUserID: b}); err != ErrNotWanted {
+		t.Errorf("InsertMatch outside the preferences = %v; expected %v", err, ErrNotWanted)
+	}
+	if _, err := s.InsertMatch(context.Background(), types.MatchRequest{UserID: b, TargetUserID: a}); err != nil {
+		t.Errorf("InsertMatch inside the preferences = %v; expected nil", err)
+	}
+}
+
+func TestLikeSelf(t *testing.T) {
+	a := primitive.NewObjectID()
+	repo := &pairRepo{matches: map[string]*types.Match{}}
+	s := NewService(&config.Configs{}, &config.ErrorMessage{}, repo, memoryQuota{}, discard{}, nil, glog.New())
+
+	if _, err := s.InsertMatch(context.Background(), types.MatchRequest{UserID: a, TargetUserID: a}); err != ErrSelf {
+		t.Errorf("InsertMatch of yourself = %v; expected %v", err, ErrSelf)
+	}
+	if len(repo.matches) != 0 {
+		t.Errorf("%d matches saved; expected 0", len(repo.matches))
+	}
+}
+
+func TestPairKey(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+	if types.PairKey(a, b) != types.PairKey(b, a) {
+		t.Errorf("PairKey(a, b) = %s; PairKey(b, a) = %s; expected equal;", types.PairKey(a, b), types.PairKey(b, a))
+	}
+}
@@ -2,9 +2,11 @@ package messageservices
 
 import (
 	"context"
+	"time"
 
 	"dating/internal/app/api/types"
 	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
 	"dating/internal/pkg/glog"
 	socket "dating/internal/pkg/socket"
 
@@ -13,42 +15,110 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrNotMember is returned when the user isn't one of the users of the room
+var ErrNotMember = apperr.NewForbidden("", "not a member of the room")
+
 // Repository is an interface of a message repository
 type Repository interface {
 	Insert(ctx context.Context, message types.Message) error
 	FindByIDRoom(ctx context.Context, id string) ([]*types.Message, error)
+	FindMatchByRoomID(ctx context.Context, idRoom string) (*types.Match, error)
+	FindStatus(ctx context.Context, idUser string) (*types.AccountStatus, error)
+}
+
+// Notifier is an interface to notify a user about an event
+type Notifier interface {
+	Notify(ctx context.Context, idUser, idActor primitive.ObjectID, kind string, data interface{}) error
 }
 
 // Service is an message service
 type Service struct {
-	conf   *config.Configs
-	em     *config.ErrorMessage
-	repo   Repository
-	logger glog.Logger
+	conf     *config.Configs
+	em       *config.ErrorMessage
+	repo     Repository
+	notifier Notifier
+	logger   glog.Logger
 }
 
 // NewService returns a new message service
-func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, l glog.Logger) *Service {
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, n Notifier, l glog.Logger) *Service {
 	return &Service{
-		conf:   c,
-		em:     e,
-		repo:   r,
-		logger: l,
+		conf:     c,
+		em:       e,
+		repo:     r,
+		notifier: n,
+		logger:   l,
 	}
 }
 
-// method help join client into room message server
-func (s *Service) ServeWs(wsServer *socket.WsServer, conn *websocket.Conn, idRoom string) {
+// notifyingRepository stores a message then notifies the other member of the room
+type notifyingRepository struct {
+	s *Service
+}
 
-	saveMessagesChan := socket.NewSaveMessageChan(s.repo)
-	idRoomHex, error := primitive.ObjectIDFromHex(idRoom)
+func (r notifyingRepository) Insert(ctx context.Context, message types.Message) error {
+	if err := r.s.repo.Insert(ctx, message); err != nil {
+		return err
+	}
+	r.s.notifyNewMessage(ctx, message)
+	return nil
+}
 
+func (s *Service) notifyNewMessage(ctx context.Context, message types.Message) {
+	match, err := s.repo.FindMatchByRoomID(ctx, message.RoomID.Hex())
+	if err != nil {
+		s.logger.Errorf("Can't find match of room %s %v", message.RoomID.Hex(), err)
+		return
+	}
+	recipient := match.UserID
+	if recipient == message.SenderID {
+		recipient = match.TargetUserID
+	}
+	s.notifier.Notify(ctx, recipient, message.SenderID, types.NotificationNewMessage, message)
+}
+
+// method help join client into room message server, idUser is the user of the token and
+// idRoom is empty for a connection which only receives notifications of the user
+func (s *Service) ServeWs(wsServer *socket.WsServer, conn *websocket.Conn, idUser, idRoom string) {
+
+	saveMessagesChan := socket.NewSaveMessageChan(notifyingRepository{s: s})
+
+	idUserHex, error := primitive.ObjectIDFromHex(idUser)
 	if error != nil {
-		s.logger.Errorf("Id room incorrect,it isn't ObjectIdHex ", error)
+		s.logger.Errorf("Id user incorrect,it isn't ObjectIdHex ", error)
+		conn.Close()
 		return
 	}
+	// paused users keep chatting with their matches, other statuses don't
+	status, error := s.repo.FindStatus(context.Background(), idUser)
+	if error != nil || status == nil || !types.CanChat(status.At(time.Now())) {
+		s.logger.Errorf("User %s can't chat %v", idUser, error)
+		conn.Close()
+		return
+	}
+
+	var idRoomHex primitive.ObjectID
+	if idRoom != "" {
+		if idRoomHex, error = primitive.ObjectIDFromHex(idRoom); error != nil {
+			s.logger.Errorf("Id room incorrect,it isn't ObjectIdHex ", error)
+			conn.Close()
+			return
+		}
+		// the room lives as long as the match, e.g. it ends when a user blocks the other
+		match, error := s.repo.FindMatchByRoomID(context.Background(), idRoom)
+		if error != nil || !match.Matched {
+			s.logger.Errorf("Room %s is closed %v", idRoom, error)
+			conn.Close()
+			return
+		}
+		if match.UserID != idUserHex && match.TargetUserID != idUserHex {
+			s.logger.Errorf("User %s is not a member of room %s", idUser, idRoom)
+			conn.Close()
+			return
+		}
+	}
 
-	client := socket.NewClient(conn, wsServer, idRoomHex, saveMessagesChan)
+	client := socket.NewClient(conn, wsServer, idUserHex, idRoomHex, saveMessagesChan)
 
 	go client.Write(s.logger)
 	go client.Read(s.logger)
@@ -57,8 +127,13 @@ func (s *Service) ServeWs(wsServer *socket.WsServer, conn *websocket.Conn, idRoo
 
 }
 
-// method help join client into room message server
-func (s *Service) GetMessagesByIdRoom(ctx context.Context, id string) ([]types.Message, error) {
+// method help get the messages of a room, only its users read them
+func (s *Service) GetMessagesByIdRoom(ctx context.Context, idUser, id string) ([]types.Message, error) {
+	match, err := s.repo.FindMatchByRoomID(ctx, id)
+	if err != nil || (match.UserID.Hex() != idUser && match.TargetUserID.Hex() != idUser) {
+		s.logger.Infof("User %s is not a member of room %s %v", idUser, id, err)
+		return nil, ErrNotMember
+	}
 
 	listMessages, err := s.repo.FindByIDRoom(ctx, id)
 	if err != nil {
@@ -0,0 +1,152 @@
+package notificationservices
+
+import (
+	"context"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/i18n"
+	"dating/internal/pkg/notify"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Repository is an interface of a notification repository
+type Repository interface {
+	Insert(ctx context.Context, notification types.Notification) error
+	FindByUserID(ctx context.Context, idUser string, unreadOnly bool, skip, limit int64) ([]*types.Notification, error)
+	CountUnread(ctx context.Context, idUser string) (int64, error)
+	MarkRead(ctx context.Context, idUser, id string) error
+	MarkAllRead(ctx context.Context, idUser string) error
+	FindLocale(ctx context.Context, idUser primitive.ObjectID) (string, error)
+}
+
+// Service is a notification service
+type Service struct {
+	conf      *config.Configs
+	em        *config.ErrorMessage
+	repo      Repository
+	templates *i18n.Catalog
+	channels  []notify.Channel
+	logger    glog.Logger
+}
+
+// NewService returns a new notification service, every notification is stored
+// in the inbox then delivered through all channels. Its title and body are the
+// templates notification.<type> in the locale of the recipient
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, t *i18n.Catalog, l glog.Logger, channels ...notify.Channel) *Service {
+	return &Service{
+		conf:      c,
+		em:        e,
+		repo:      r,
+		templates: t,
+		channels:  channels,
+		logger:    l,
+	}
+}
+
+// Notify stores a notification in the inbox of idUser and delivers it
+func (s *Service) Notify(ctx context.Context, idUser, idActor primitive.ObjectID, kind string, data interface{}) error {
+	notification := types.Notification{
+		ID:       primitive.NewObjectID(),
+		UserID:   idUser,
+		ActorID:  idActor,
+		Type:     kind,
+		Data:     data,
+		Read:     false,
+		CreateAt: time.Now(),
+	}
+	s.localize(ctx, &notification)
+
+	if err := s.repo.Insert(ctx, notification); err != nil {
+		s.logger.Errorf("Can't insert notification %v", err)
+		return errors.Wrap(err, "Can't insert notification")
+	}
+
+	// delivery must not slow down the request which triggered the event
+	go s.deliver(notification)
+	return nil
+}
+
+// localize sets the title and the body of a notification in the locale of its recipient, a
+// notification without them is still delivered with its type and data
+func (s *Service) localize(ctx context.Context, notification *types.Notification) {
+	locale, err := s.repo.FindLocale(ctx, notification.UserID)
+	if err != nil {
+		s.logger.Errorf("Can't find locale of %s %v", notification.UserID.Hex(), err)
+	}
+	key := "notification." + notification.Type
+	if notification.Title, err = s.templates.Render(key+".title", notification.Data, locale); err != nil {
+		s.logger.Errorf("Can't render title of %s %v", notification.Type, err)
+	}
+	if notification.Body, err = s.templates.Render(key+".body", notification.Data, locale); err != nil {
+		s.logger.Errorf("Can't render body of %s %v", notification.Type, err)
+	}
+}
+
+func (s *Service) deliver(notification types.Notification) {
+	for _, c := range s.channels {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := c.Send(ctx, notification); err != nil {
+			s.logger.Errorf("Failed to deliver notification %s by %s, err: %v", notification.ID.Hex(), c.Name(), err)
+		}
+		cancel()
+	}
+}
+
+// Get notifications in the inbox of a user, newest first
+func (s *Service) GetNotifications(ctx context.Context, idUser, page, size, unread string) (*types.GetListNotificationsResponse, error) {
+
+	pageInt, sizeInt, err := types.ParsePage(page, size, defaultPageSize, maxPageSize)
+	if err != nil {
+		return nil, err
+	}
+	unreadOnly := unread == "true"
+
+	list, err := s.repo.FindByUserID(ctx, idUser, unreadOnly, int64((pageInt-1)*sizeInt), int64(sizeInt))
+	if err != nil {
+		s.logger.Errorf("Failed when get notifications %v", err)
+		return nil, errors.Wrap(err, "Failed when get notifications")
+	}
+
+	count, err := s.repo.CountUnread(ctx, idUser)
+	if err != nil {
+		s.logger.Errorf("Failed when count unread notifications %v", err)
+		return nil, errors.Wrap(err, "Failed when count unread notifications")
+	}
+
+	notifications := []types.Notification{}
+	for _, n := range list {
+		notifications = append(notifications, *n)
+	}
+	return &types.GetListNotificationsResponse{
+		Unread:        count,
+		Notifications: notifications,
+	}, nil
+}
+
+// Mark a notification as read
+func (s *Service) MarkRead(ctx context.Context, idUser, id string) error {
+	if err := s.repo.MarkRead(ctx, idUser, id); err != nil {
+		s.logger.Errorf("Failed when mark notification %s read %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// Mark all notifications as read
+func (s *Service) MarkAllRead(ctx context.Context, idUser string) error {
+	if err := s.repo.MarkAllRead(ctx, idUser); err != nil {
+		s.logger.Errorf("Failed when mark all notifications read %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package phoneservices
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/i18n"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrPhoneExists is returned when another user verified the phone
+	ErrPhoneExists = apperr.NewConflict("invalid_value.phone_exists", "phone exists")
+	// ErrInvalidCode is returned when the code is wrong, expired or none was sent
+	ErrInvalidCode = apperr.NewValidation("invalid_value.invalid_phone_code", "invalid code")
+	// ErrTooManyAttempts is returned when the code was attempted too many times, a new one
+	// must be sent
+	ErrTooManyAttempts = apperr.New(apperr.TooManyRequests, "", "too many attempts")
+	// ErrResendTooSoon is returned when a code was sent less than the resend interval ago
+	ErrResendTooSoon = apperr.New(apperr.TooManyRequests, "", "resend too soon")
+	// ErrNotFound is returned when the user has no phone
+	ErrNotFound = apperr.NewNotFound("not found")
+	// ErrSendFailed is returned when the SMS provider failed to send the code
+	ErrSendFailed = apperr.New(apperr.BadGateway, "", "send failed")
+)
+
+// Repository is an interface of a phone repository
+type Repository interface {
+	FindPhoneOwner(ctx context.Context, phone string) (*primitive.ObjectID, error)
+	FindVerification(ctx context.Context, idUser primitive.ObjectID) (*types.PhoneVerification, error)
+	SaveVerification(ctx context.Context, verification types.PhoneVerification) error
+	Attempt(ctx context.Context, idUser primitive.ObjectID, maxAttempts int, now time.Time) (*types.PhoneVerification, error)
+	DeleteVerification(ctx context.Context, idUser primitive.ObjectID) error
+	SetPhone(ctx context.Context, idUser primitive.ObjectID, phone string, now time.Time) error
+	RemovePhone(ctx context.Context, idUser primitive.ObjectID) (bool, error)
+}
+
+// SMSProvider is an interface of a text message gateway
+type SMSProvider interface {
+	Send(ctx context.Context, to, message string) error
+}
+
+// Service is a phone service
+type Service struct {
+	conf      *config.Configs
+	em        *config.ErrorMessage
+	repo      Repository
+	sms       SMSProvider
+	templates *i18n.Catalog
+	logger    glog.Logger
+}
+
+// NewService returns a new phone service, the text of the code is the template sms.phone_code
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, sms SMSProvider, t *i18n.Catalog, l glog.Logger) *Service {
+	return &Service{
+		conf:      c,
+		em:        e,
+		repo:      r,
+		sms:       sms,
+		templates: t,
+		logger:    l,
+	}
+}
+
+// SendCode sends a code to the phone a user registers, the phone is verified with it
+func (s *Service) SendCode(ctx context.Context, idUser, phone string) (*types.PhoneVerificationResponse, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.repo.FindPhoneOwner(ctx, phone)
+	if err != nil {
+		s.logger.Errorf("Failed when find owner of phone %v", err)
+		return nil, errors.Wrap(err, "Failed when find owner of phone")
+	}
+	if owner != nil && *owner != userID {
+		s.logger.Infof("Phone of %s is verified by another user", idUser)
+		return nil, ErrPhoneExists
+	}
+
+	now := time.Now()
+	previous, err := s.repo.FindVerification(ctx, userID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		s.logger.Errorf("Failed when find code of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when find code")
+	}
+	if err == nil && now.Before(previous.SentAt.Add(s.conf.Phone.ResendInterval)) {
+		return nil, ErrResendTooSoon
+	}
+
+	code, err := newCode(s.conf.Phone.CodeLength)
+	if err != nil {
+		return nil, err
+	}
+	verification := types.PhoneVerification{
+		UserID:    userID,
+		Phone:     phone,
+		CodeHash:  hashCode(userID, code),
+		SentAt:    now,
+		ExpiresAt: now.Add(s.conf.Phone.CodeTTL),
+	}
+	if err := s.repo.SaveVerification(ctx, verification); err != nil {
+		s.logger.Errorf("Failed when save code of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when save code")
+	}
+
+	message, err := s.templates.Render("sms.phone_code", map[string]interface{}{
+		"code":    code,
+		"minutes": int(s.conf.Phone.CodeTTL.Minutes()),
+	}, i18n.FromContext(ctx)...)
+	if err != nil {
+		s.logger.Errorf("Can't render code of %s %v", idUser, err)
+		message = fmt.Sprintf("Your Dating verification code is %s.", code)
+	}
+	if err := s.sms.Send(ctx, phone, message); err != nil {
+		s.logger.Errorf("Failed when send code to %s %v", idUser, err)
+		// the code never arrived, it mustn't hold back a new one
+		if err := s.repo.DeleteVerification(ctx, userID); err != nil {
+			s.logger.Errorf("Failed when delete code of %s %v", idUser, err)
+		}
+		return nil, errors.Wrap(ErrSendFailed, err.Error())
+	}
+
+	s.logger.Infof("Phone code sent %s", idUser)
+	return &types.PhoneVerificationResponse{
+		Phone:     phone,
+		ExpiresAt: verification.ExpiresAt,
+		ResendAt:  now.Add(s.conf.Phone.ResendInterval),
+	}, nil
+}
+
+// Verify verifies the phone of a user with the code sent to it, each code is attempted
+// a limited number of times
+func (s *Service) Verify(ctx context.Context, idUser, code string) error {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	verification, err := s.repo.Attempt(ctx, userID, s.conf.Phone.MaxAttempts, now)
+	if err == mongo.ErrNoDocuments {
+		return s.whyNotValid(ctx, userID, now)
+	}
+	if err != nil {
+		s.logger.Errorf("Failed when attempt code of %s %v", idUser, err)
+		return errors.Wrap(err, "Failed when attempt code")
+	}
+	if subtle.ConstantTimeCompare([]byte(verification.CodeHash), []byte(hashCode(userID, code))) != 1 {
+		s.logger.Infof("Wrong phone code of %s, attempt %d", idUser, verification.Attempts)
+		return ErrInvalidCode
+	}
+
+	if err := s.repo.SetPhone(ctx, userID, verification.Phone, now); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			s.logger.Infof("Phone of %s was verified by another user", idUser)
+			return ErrPhoneExists
+		}
+		s.logger.Errorf("Failed when set phone of %s %v", idUser, err)
+		return errors.Wrap(err, "Failed when set phone")
+	}
+	if err := s.repo.DeleteVerification(ctx, userID); err != nil {
+		s.logger.Errorf("Failed when delete code of %s %v", idUser, err)
+	}
+	s.logger.Infof("Phone verified %s", idUser)
+	return nil
+}
+
+// whyNotValid tells why no code of a user can be attempted
+func (s *Service) whyNotValid(ctx context.Context, userID primitive.ObjectID, now time.Time) error {
+	verification, err := s.repo.FindVerification(ctx, userID)
+	if err == mongo.ErrNoDocuments {
+		return ErrInvalidCode
+	}
+	if err != nil {
+		s.logger.Errorf("Failed when find code of %s %v", userID.Hex(), err)
+		return errors.Wrap(err, "Failed when find code")
+	}
+	if now.Before(verification.ExpiresAt) && verification.Attempts >= s.conf.Phone.MaxAttempts {
+		s.logger.Infof("Too many attempts at phone code of %s", userID.Hex())
+		return ErrTooManyAttempts
+	}
+	return ErrInvalidCode
+}
+
+// RemovePhone removes the phone of a user, with its badge
+func (s *Service) RemovePhone(ctx context.Context, idUser string) error {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return err
+	}
+	ok, err := s.repo.RemovePhone(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Failed when remove phone of %s %v", idUser, err)
+		return errors.Wrap(err, "Failed when remove phone")
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	s.logger.Infof("Phone removed %s", idUser)
+	return nil
+}
+
+// newCode returns a random code of length digits
+func newCode(length int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+// hashCode hashes the code of a user, the codes aren't stored
+func hashCode(userID primitive.ObjectID, code string) string {
+	sum := sha256.Sum256([]byte(userID.Hex() + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,141 @@
+package phoneservices
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/i18n"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryRepo keeps the codes and the phones in memory
+type memoryRepo struct {
+	verifications map[primitive.ObjectID]types.PhoneVerification
+	phones        map[string]primitive.ObjectID
+}
+
+func (r *memoryRepo) FindPhoneOwner(ctx context.Context, phone string) (*primitive.ObjectID, error) {
+	if owner, ok := r.phones[phone]; ok {
+		return &owner, nil
+	}
+	return nil, nil
+}
+
+func (r *memoryRepo) FindVerification(ctx context.Context, idUser primitive.ObjectID) (*types.PhoneVerification, error) {
+	verification, ok := r.verifications[idUser]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &verification, nil
+}
+
+func (r *memoryRepo) SaveVerification(ctx context.Context, verification types.PhoneVerification) error {
+	r.verifications[verification.UserID] = verification
+	return nil
+}
+
+func (r *memoryRepo) Attempt(ctx context.Context, idUser primitive.ObjectID, maxAttempts int, now time.Time) (*types.PhoneVerification, error) {
+	verification, ok := r.verifications[idUser]
+	if !ok || verification.Attempts >= maxAttempts || !now.Before(verification.ExpiresAt) {
+		return nil, mongo.ErrNoDocuments
+	}
+	verification.Attempts++
+	r.verifications[idUser] = verification
+	return &verification, nil
+}
+
+func (r *memoryRepo) DeleteVerification(ctx context.Context, idUser primitive.ObjectID) error {
+	delete(r.verifications, idUser)
+	return nil
+}
+
+func (r *memoryRepo) SetPhone(ctx context.Context, idUser primitive.ObjectID, phone string, now time.Time) error {
+	r.phones[phone] = idUser
+	return nil
+}
+
+func (r *memoryRepo) RemovePhone(ctx context.Context, idUser primitive.ObjectID) (bool, error) {
+	for phone, owner := range r.phones {
+		if owner == idUser {
+			delete(r.phones, phone)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// outbox keeps the last message sent
+type outbox struct {
+	to, message string
+}
+
+func (o *outbox) Send(ctx context.Context, to, message string) error {
+	o.to, o.message = to, message
+	return nil
+}
+
+func (o *outbox) code() string {
+	return regexp.MustCompile(`\d{6}`).FindString(o.message)
+}
+
+func TestVerify(t *testing.T) {
+	conf := &config.Configs{}
+	conf.Phone = config.Phone{CodeLength: 6, CodeTTL: time.Minute, MaxAttempts: 2, ResendInterval: time.Minute}
+	repo := &memoryRepo{verifications: map[primitive.ObjectID]types.PhoneVerification{}, phones: map[string]primitive.ObjectID{}}
+	sms := &outbox{}
+	templates := i18n.New(i18n.DefaultLocale)
+	templates.Set("en", "sms.phone_code", "Your code is {{.code}}.")
+	templates.Set("vi", "sms.phone_code", "Ma cua ban la {{.code}}.")
+	s := NewService(conf, &config.ErrorMessage{}, repo, sms, templates, glog.New())
+	ctx := i18n.NewContext(context.Background(), []string{"vi-VN"})
+	user, other := primitive.NewObjectID(), primitive.NewObjectID()
+
+	if _, err := s.SendCode(ctx, user.Hex(), "+84901234567"); err != nil {
+		t.Fatal(err)
+	}
+	if sms.to != "+84901234567" || sms.message != "Ma cua ban la "+sms.code()+"." {
+		t.Fatalf("sent %q to %s; expected a code in vi to +84901234567", sms.message, sms.to)
+	}
+	if _, err := s.SendCode(ctx, user.Hex(), "+84901234567"); err != ErrResendTooSoon {
+		t.Errorf("SendCode again = %v; expected %v", err, ErrResendTooSoon)
+	}
+
+	// the code is locked once attempted too many times
+	code := sms.code()
+	for i := 0; i < conf.Phone.MaxAttempts; i++ {
+		if err := s.Verify(ctx, user.Hex(), "x"+code); err != ErrInvalidCode {
+			t.Errorf("Verify with a wrong code = %v; expected %v", err, ErrInvalidCode)
+		}
+	}
+	if err := s.Verify(ctx, user.Hex(), code); err != ErrTooManyAttempts {
+		t.Errorf("Verify after too many attempts = %v; expected %v", err, ErrTooManyAttempts)
+	}
+
+	// a new code, once the resend interval passed
+	verification := repo.verifications[user]
+	verification.SentAt = verification.SentAt.Add(-conf.Phone.ResendInterval)
+	repo.verifications[user] = verification
+	if _, err := s.SendCode(ctx, user.Hex(), "+84901234567"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(ctx, user.Hex(), sms.code()); err != nil {
+		t.Fatalf("Verify = %v", err)
+	}
+	if repo.phones["+84901234567"] != user {
+		t.Errorf("phone of %v; expected %v", repo.phones["+84901234567"], user)
+	}
+	if err := s.Verify(ctx, user.Hex(), sms.code()); err != ErrInvalidCode {
+		t.Errorf("Verify with a used code = %v; expected %v", err, ErrInvalidCode)
+	}
+
+	if _, err := s.SendCode(ctx, other.Hex(), "+84901234567"); err != ErrPhoneExists {
+		t.Errorf("SendCode to the phone of another user = %v; expected %v", err, ErrPhoneExists)
+	}
+}
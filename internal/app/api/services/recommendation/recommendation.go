@@ -0,0 +1,127 @@
+package recommendationservices
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/glog"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Repository is an interface of a recommendation repository
+type Repository interface {
+	FindCandidate(ctx context.Context, idUser string) (*types.Candidate, error)
+	FindCandidates(ctx context.Context, idUser string, ps types.PagingNSorting, limit int) ([]*types.Candidate, error)
+	TouchLastActive(ctx context.Context, idUser string) error
+}
+
+// Service is a recommendation service, it ranks the users passing the
+// discovery filters by how good they are for the caller
+type Service struct {
+	conf   *config.Configs
+	em     *config.ErrorMessage
+	repo   Repository
+	logger glog.Logger
+}
+
+// NewService returns a new recommendation service
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, l glog.Logger) *Service {
+	return &Service{
+		conf:   c,
+		em:     e,
+		repo:   r,
+		logger: l,
+	}
+}
+
+// Get the ranked feed of idUser, the score breakdown is only set in debug mode
+func (s *Service) GetRecommendations(ctx context.Context, idUser, page, size, minAge, maxAge, gender, interests string, debug bool) (*types.GetRecommendationsResponse, error) {
+
+	pageInt, sizeInt, err := types.ParsePage(page, size, defaultPageSize, maxPageSize)
+	if err != nil {
+		return nil, err
+	}
+	me, err := s.repo.FindCandidate(ctx, idUser)
+	if err != nil {
+		s.logger.Errorf("Can't find user %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Can't find user")
+	}
+
+	// filters that weren't sent come from the saved preferences
+	minAge, maxAge, gender = me.Preferences.Defaults(minAge, maxAge, gender)
+	var ps types.PagingNSorting
+	if err := ps.Init("", "", minAge, maxAge, gender, interests); err != nil {
+		s.logger.Errorf("Failed url parameters when get recommendations %v", err)
+		return nil, errors.Wrap(err, "Failed url parameters when get recommendations")
+	}
+	ps.Filter.Viewer = me
+	if me.Preferences != nil {
+		ps.Filter.MaxDistance = me.Preferences.MaxDistance
+	}
+	if err := s.repo.TouchLastActive(ctx, idUser); err != nil {
+		s.logger.Errorf("Can't touch last active of %s %v", idUser, err)
+	}
+
+	candidates, err := s.repo.FindCandidates(ctx, idUser, ps, s.conf.Recommendation.CandidatePool)
+	if err != nil {
+		s.logger.Errorf("Failed when find candidates %v", err)
+		return nil, errors.Wrap(err, "Failed when find candidates")
+	}
+
+	ranked := s.rank(me, candidates, debug)
+
+	res := types.GetRecommendationsResponse{Recommendations: []types.Recommendation{}}
+	res.CurrentPage = pageInt
+	res.MaxItemsPerPage = sizeInt
+	res.TotalItems = len(ranked)
+	res.TotalPages = (len(ranked) + sizeInt - 1) / sizeInt
+	res.Filter = ps.Filter
+
+	if start := (pageInt - 1) * sizeInt; start < len(ranked) {
+		end := start + sizeInt
+		if end > len(ranked) {
+			end = len(ranked)
+		}
+		res.Recommendations = append(res.Recommendations, ranked[start:end]...)
+	}
+
+	s.logger.Infof("Ranked %d candidates for %s", len(ranked), idUser)
+	return &res, nil
+}
+
+// rank scores the candidates for me, best first and by id on a tie so pages are stable
+func (s *Service) rank(me *types.Candidate, candidates []*types.Candidate, debug bool) []types.Recommendation {
+
+	sc := scorer{conf: s.conf.Recommendation, now: time.Now()}
+
+	ranked := make([]types.Recommendation, 0, len(candidates))
+	for _, c := range candidates {
+		score, distance, breakdown := sc.score(me, c)
+		r := types.Recommendation{
+			UserResGetInfo: c.UserResGetInfo,
+			Score:          score,
+			DistanceKm:     distance,
+		}
+		if debug {
+			r.Breakdown = &breakdown
+		}
+		ranked = append(ranked, r)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].ID.Hex() < ranked[j].ID.Hex()
+	})
+	return ranked
+}
@@ -0,0 +1,172 @@
+package recommendationservices
+
+import (
+	"math"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+)
+
+const earthRadiusKm = 6371
+
+// scorer scores how good a candidate is for a user, every component is in [0, 1]
+// and the score is their weighted mean
+type scorer struct {
+	conf config.Recommendation
+	now  time.Time
+}
+
+// score returns the score of candidate c for user me, the distance between
+// them when both locations are known and the score of each component
+func (sc scorer) score(me, c *types.Candidate) (float64, *float64, types.ScoreBreakdown) {
+
+	var distance *float64
+	if me.Location != nil && c.Location != nil {
+		d := haversine(*me.Location, *c.Location)
+		distance = &d
+	}
+
+	c.SharedInterests = shared(me.Interests, c.Interests)
+
+	breakdown := types.ScoreBreakdown{
+		Preference:   (sc.fit(me.Preferences, c, distance) + sc.fit(c.Preferences, me, distance)) / 2,
+		Interests:    ratio(float64(c.SharedInterests), float64(len(me.Interests))),
+		Distance:     sc.distance(distance),
+		Recency:      sc.recency(c.LastActiveAt),
+		Completeness: completeness(c),
+	}
+
+	w := sc.conf.Weights
+	total := w.Preference + w.Interests + w.Distance + w.Recency + w.Completeness
+	if total == 0 {
+		return 0, distance, breakdown
+	}
+	score := (w.Preference*breakdown.Preference +
+		w.Interests*breakdown.Interests +
+		w.Distance*breakdown.Distance +
+		w.Recency*breakdown.Recency +
+		w.Completeness*breakdown.Completeness) / total
+
+	return score, distance, breakdown
+}
+
+// fit returns the share of the preferences p that user u satisfies, no preferences fit anyone
+func (sc scorer) fit(p *types.Preferences, u *types.Candidate, distance *float64) float64 {
+	if p == nil {
+		return 1
+	}
+
+	met, criteria := 0, 0
+
+	if p.MinAge > 0 || p.MaxAge > 0 {
+		criteria++
+		age := u.Age(sc.now)
+		if age >= p.MinAge && (p.MaxAge == 0 || age <= p.MaxAge) {
+			met++
+		}
+	}
+
+	if len(p.Genders) > 0 {
+		criteria++
+		for _, gender := range p.Genders {
+			if gender == u.Gender {
+				met++
+				break
+			}
+		}
+	}
+
+	if p.MaxDistance > 0 && distance != nil {
+		criteria++
+		if *distance <= float64(p.MaxDistance) {
+			met++
+		}
+	}
+
+	if criteria == 0 {
+		return 1
+	}
+	return float64(met) / float64(criteria)
+}
+
+// distance scores 1 next door down to 0 at the max distance, unknown distances score 0
+func (sc scorer) distance(distance *float64) float64 {
+	if distance == nil || sc.conf.MaxDistanceKm <= 0 {
+		return 0
+	}
+	return math.Max(0, 1-*distance/sc.conf.MaxDistanceKm)
+}
+
+// recency scores 1 for a user active now, halving every half life
+func (sc scorer) recency(lastActiveAt time.Time) float64 {
+	if lastActiveAt.IsZero() || sc.conf.RecencyHalfLife <= 0 {
+		return 0
+	}
+	since := sc.now.Sub(lastActiveAt)
+	if since < 0 {
+		return 1
+	}
+	return math.Pow(0.5, float64(since)/float64(sc.conf.RecencyHalfLife))
+}
+
+// completeness returns the share of the profile fields the candidate filled
+func completeness(c *types.Candidate) float64 {
+	filled := []bool{
+		c.Name != "",
+		!c.Birthday.IsZero(),
+		c.Gender != "",
+		c.Country != "",
+		c.About != "",
+		c.Relationship != "",
+		c.LookingFor != "",
+		len(c.Media) > 0,
+		len(c.Interests) > 0,
+		c.Height > 0,
+		c.Education != "",
+		c.Job != "",
+		len(c.Languages) > 0,
+		len(c.Prompts) > 0,
+		c.Location != nil,
+	}
+	n := 0
+	for _, ok := range filled {
+		if ok {
+			n++
+		}
+	}
+	return float64(n) / float64(len(filled))
+}
+
+// shared returns the number of interests in both lists
+func shared(a, b []string) int {
+	set := make(map[string]bool, len(a))
+	for _, v := range a {
+		set[v] = true
+	}
+	n := 0
+	for _, v := range b {
+		if set[v] {
+			n++
+			delete(set, v)
+		}
+	}
+	return n
+}
+
+// ratio returns a / b capped to 1, 0 when b is 0
+func ratio(a, b float64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return math.Min(1, a/b)
+}
+
+// haversine returns the great circle distance between two locations in km
+func haversine(a, b types.Location) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,121 @@
+package recommendationservices
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var now = time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func testScorer() scorer {
+	return scorer{
+		conf: config.Recommendation{
+			MaxDistanceKm:   100,
+			RecencyHalfLife: 24 * time.Hour,
+			Weights: config.RecommendationWeights{
+				Preference:   3,
+				Interests:    2,
+				Distance:     2,
+				Recency:      1,
+				Completeness: 1,
+			},
+		},
+		now: now,
+	}
+}
+
+func candidate(gender string, age int) *types.Candidate {
+	c := &types.Candidate{}
+	c.ID = primitive.NewObjectID()
+	c.Gender = gender
+	c.Birthday = now.AddDate(-age, 0, -1)
+	return c
+}
+
+func TestScoreBreakdown(t *testing.T) {
+	me := candidate("Male", 30)
+	me.Interests = []string{"hiking", "coffee", "chess", "movies"}
+	me.Location = &types.Location{Lng: 106.70, Lat: 10.77}
+	me.Preferences = &types.Preferences{MinAge: 25, MaxAge: 35, Genders: []string{"Female"}}
+
+	c := candidate("Female", 28)
+	c.Interests = []string{"coffee", "movies", "yoga"}
+	c.Location = &types.Location{Lng: 106.70, Lat: 10.77}
+	c.LastActiveAt = now.Add(-24 * time.Hour)
+	c.Preferences = &types.Preferences{MinAge: 20, MaxAge: 25, Genders: []string{"Male"}}
+
+	score, distance, b := testScorer().score(me, c)
+
+	if distance == nil || *distance > 0.001 {
+		t.Fatalf("distance = %v; expected 0", distance)
+	}
+	if c.SharedInterests != 2 {
+		t.Errorf("SharedInterests = %d; expected 2", c.SharedInterests)
+	}
+	// me fits all of mine, c only the gender of hers
+	expected := types.ScoreBreakdown{
+		Preference:   (1 + 0.5) / 2,
+		Interests:    0.5,
+		Distance:     1,
+		Recency:      0.5,
+		Completeness: 4.0 / 15, // birthday, gender, interests, location
+	}
+	if b != expected {
+		t.Errorf("breakdown = %+v; expected %+v", b, expected)
+	}
+	weighted := (3*expected.Preference + 2*expected.Interests + 2*expected.Distance + expected.Recency + expected.Completeness) / 9
+	if math.Abs(score-weighted) > 1e-9 {
+		t.Errorf("score = %f; expected %f", score, weighted)
+	}
+}
+
+func TestScoreUnknownDistanceAndActivity(t *testing.T) {
+	me := candidate("Male", 30)
+	c := candidate("Female", 28)
+
+	_, distance, b := testScorer().score(me, c)
+
+	if distance != nil {
+		t.Errorf("distance = %v; expected nil", *distance)
+	}
+	if b.Distance != 0 || b.Recency != 0 || b.Interests != 0 {
+		t.Errorf("breakdown = %+v; expected no distance, recency and interests", b)
+	}
+	if b.Preference != 1 {
+		t.Errorf("Preference = %f; expected 1 without preferences", b.Preference)
+	}
+}
+
+func TestRankOrder(t *testing.T) {
+	s := &Service{conf: &config.Configs{Recommendation: testScorer().conf}}
+	me := candidate("Male", 30)
+	me.Interests = []string{"hiking"}
+
+	idle := candidate("Female", 28)
+	active := candidate("Female", 28)
+	active.LastActiveAt = time.Now()
+	active.Interests = []string{"hiking"}
+
+	ranked := s.rank(me, []*types.Candidate{idle, active}, false)
+
+	if len(ranked) != 2 || ranked[0].ID != active.ID {
+		t.Fatalf("ranked = %+v; expected the active user first", ranked)
+	}
+	if ranked[0].Breakdown != nil {
+		t.Errorf("Breakdown = %+v; expected none out of debug mode", ranked[0].Breakdown)
+	}
+}
+
+func TestHaversine(t *testing.T) {
+	// Ho Chi Minh City to Ha Noi
+	d := haversine(types.Location{Lng: 106.6297, Lat: 10.8231}, types.Location{Lng: 105.8342, Lat: 21.0278})
+	if d < 1130 || d > 1150 {
+		t.Errorf("distance = %f; expected about 1140 km", d)
+	}
+}
@@ -0,0 +1,295 @@
+package twofactorservices
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/jwt"
+	"dating/internal/pkg/totp"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// ErrInvalidCode is returned when a code is neither a valid TOTP code nor an unused
+	// recovery code, or the token of the login isn't valid
+	ErrInvalidCode = apperr.NewValidation("invalid_value.invalid_two_factor_code", "invalid code")
+	// ErrConflict is returned when enrolling with two-factor authentication enabled, or
+	// confirming or using it while it isn't enabled
+	ErrConflict = apperr.NewConflict("invalid_value.two_factor_conflict", "two-factor authentication conflict")
+	// ErrAccountSuspended is returned when a suspended user completes a login
+	ErrAccountSuspended = apperr.NewForbidden("invalid_value.account_suspended", "account is suspended")
+	// ErrAccountBanned is returned when a banned user completes a login
+	ErrAccountBanned = apperr.NewForbidden("invalid_value.account_banned", "account is banned")
+)
+
+// Repository is an interface of a two-factor repository
+type Repository interface {
+	FindByID(ctx context.Context, idUser primitive.ObjectID) (*types.User, error)
+	SetPending(ctx context.Context, idUser primitive.ObjectID, secret string) (bool, error)
+	Enable(ctx context.Context, idUser primitive.ObjectID, secret string, step int64, recoveryCodes []string, now time.Time) (bool, error)
+	UseStep(ctx context.Context, idUser primitive.ObjectID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, idUser primitive.ObjectID, hash string) (bool, error)
+	SetRecoveryCodes(ctx context.Context, idUser primitive.ObjectID, recoveryCodes []string) (bool, error)
+	Disable(ctx context.Context, idUser primitive.ObjectID) (bool, error)
+}
+
+// Service is a two-factor service
+type Service struct {
+	conf   *config.Configs
+	em     *config.ErrorMessage
+	repo   Repository
+	logger glog.Logger
+}
+
+// NewService returns a new two-factor service
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, l glog.Logger) *Service {
+	return &Service{
+		conf:   c,
+		em:     e,
+		repo:   r,
+		logger: l,
+	}
+}
+
+// GetStatus tells if two-factor authentication of a user is enabled
+func (s *Service) GetStatus(ctx context.Context, idUser string) (*types.TwoFactorStatus, error) {
+	user, err := s.findUser(ctx, idUser)
+	if err != nil {
+		return nil, err
+	}
+	status := &types.TwoFactorStatus{
+		Enabled:  user.TwoFactorEnabled(),
+		Required: s.conf.TwoFactor.Enforced(user.Role),
+	}
+	if status.Enabled {
+		status.EnabledAt = user.TwoFactor.EnabledAt
+		status.RecoveryCodesLeft = len(user.TwoFactor.RecoveryCodes)
+	}
+	return status, nil
+}
+
+// Enroll starts an enrollment with a new secret, it is enabled once confirmed with a code
+func (s *Service) Enroll(ctx context.Context, idUser string) (*types.TwoFactorEnrollment, error) {
+	user, err := s.findUser(ctx, idUser)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.SetPending(ctx, user.ID, secret)
+	if err != nil {
+		s.logger.Errorf("Failed when start enrollment of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when start enrollment")
+	}
+	if !ok {
+		return nil, ErrConflict
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Name
+	}
+	s.logger.Infof("Two-factor enrollment started %s", idUser)
+	return &types.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.conf.TwoFactor.Issuer, account, secret),
+	}, nil
+}
+
+// Confirm enables a pending enrollment with a code of the authenticator app and returns the
+// recovery codes
+func (s *Service) Confirm(ctx context.Context, idUser, code string) (*types.RecoveryCodesResponse, error) {
+	user, err := s.findUser(ctx, idUser)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactor == nil || user.TwoFactor.Enabled {
+		return nil, ErrConflict
+	}
+	step, ok := totp.Validate(user.TwoFactor.Secret, code, time.Now(), s.conf.TwoFactor.Skew)
+	if !ok {
+		s.logger.Infof("Invalid code confirming two-factor of %s", idUser)
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	ok, err = s.repo.Enable(ctx, user.ID, user.TwoFactor.Secret, step, hashes, time.Now())
+	if err != nil {
+		s.logger.Errorf("Failed when enable two-factor of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when enable two-factor")
+	}
+	if !ok {
+		// enrolled again or confirmed at the same time
+		return nil, ErrInvalidCode
+	}
+	s.logger.Infof("Two-factor enabled %s", idUser)
+	return &types.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable disables two-factor authentication of a user who proves having the second factor
+func (s *Service) Disable(ctx context.Context, idUser, code string) error {
+	user, err := s.findUser(ctx, idUser)
+	if err != nil {
+		return err
+	}
+	if err := s.verify(ctx, user, code); err != nil {
+		return err
+	}
+	if _, err := s.repo.Disable(ctx, user.ID); err != nil {
+		s.logger.Errorf("Failed when disable two-factor of %s %v", idUser, err)
+		return errors.Wrap(err, "Failed when disable two-factor")
+	}
+	s.logger.Infof("Two-factor disabled %s", idUser)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user who proves having the second factor
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, idUser, code string) (*types.RecoveryCodesResponse, error) {
+	user, err := s.findUser(ctx, idUser)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verify(ctx, user, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	ok, err := s.repo.SetRecoveryCodes(ctx, user.ID, hashes)
+	if err != nil {
+		s.logger.Errorf("Failed when set recovery codes of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when set recovery codes")
+	}
+	if !ok {
+		return nil, ErrConflict
+	}
+	s.logger.Infof("Recovery codes regenerated %s", idUser)
+	return &types.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// Login completes a login waiting for its second factor and mints the token of the user
+func (s *Service) Login(ctx context.Context, login types.TwoFactorLogin) (*types.UserResponseSignUp, error) {
+	idUser, err := jwt.IsPurposeAuthorized(login.Token, types.PurposeTwoFactor)
+	if err != nil {
+		s.logger.Infof("Two-factor login with an invalid token")
+		return nil, ErrInvalidCode
+	}
+	user, err := s.findUser(ctx, idUser)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verify(ctx, user, login.Code); err != nil {
+		return nil, err
+	}
+
+	status := user.AccountStatus().At(time.Now())
+	switch status {
+	case types.StatusSuspended:
+		s.logger.Infof("Suspended user tried to login %s", idUser)
+		return nil, ErrAccountSuspended
+	case types.StatusBanned:
+		s.logger.Infof("Banned user tried to login %s", idUser)
+		return nil, ErrAccountBanned
+	}
+
+	role := user.Role
+	if role == "" {
+		role = types.RoleUser
+	}
+	token, err := jwt.GenToken(types.UserFieldInToken{
+		ID:    user.ID,
+		Name:  user.Name,
+		Email: user.Email,
+		Role:  role,
+		MFA:   true}, s.conf.Jwt.Duration)
+	if err != nil {
+		s.logger.Errorf("Can not gen token", err)
+		return nil, errors.Wrap(err, "Can't gen token")
+	}
+	s.logger.Infof("Two-factor login completed %s", idUser)
+	return &types.UserResponseSignUp{
+		Name:   user.Name,
+		Email:  user.Email,
+		Token:  token,
+		Status: status}, nil
+}
+
+// verify uses a TOTP code or a recovery code of a user, each is used once
+func (s *Service) verify(ctx context.Context, user *types.User, code string) error {
+	if !user.TwoFactorEnabled() {
+		return ErrConflict
+	}
+
+	var ok bool
+	var err error
+	if step, valid := totp.Validate(user.TwoFactor.Secret, code, time.Now(), s.conf.TwoFactor.Skew); valid {
+		ok, err = s.repo.UseStep(ctx, user.ID, step)
+	} else {
+		ok, err = s.repo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code))
+		if ok {
+			s.logger.Infof("Recovery code used %s", user.ID.Hex())
+		}
+	}
+	if err != nil {
+		s.logger.Errorf("Failed when use code of %s %v", user.ID.Hex(), err)
+		return errors.Wrap(err, "Failed when use code")
+	}
+	if !ok {
+		s.logger.Infof("Invalid two-factor code of %s", user.ID.Hex())
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func (s *Service) findUser(ctx context.Context, idUser string) (*types.User, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Failed when find user %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when find user")
+	}
+	return user, nil
+}
+
+// newRecoveryCodes returns new recovery codes, formatted xxxxx-xxxxx, and their hashes
+func (s *Service) newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, s.conf.TwoFactor.RecoveryCodes)
+	hashes := make([]string, len(codes))
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code, whatever its case, dash and spaces
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
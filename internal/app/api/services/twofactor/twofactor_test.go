@@ -0,0 +1,132 @@
+package twofactorservices
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/jwt"
+	"dating/internal/pkg/totp"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryRepo keeps a user in memory
+type memoryRepo struct {
+	user types.User
+}
+
+func (r *memoryRepo) FindByID(ctx context.Context, idUser primitive.ObjectID) (*types.User, error) {
+	if idUser != r.user.ID {
+		return nil, mongo.ErrNoDocuments
+	}
+	user := r.user
+	return &user, nil
+}
+
+func (r *memoryRepo) SetPending(ctx context.Context, idUser primitive.ObjectID, secret string) (bool, error) {
+	if r.user.TwoFactorEnabled() {
+		return false, nil
+	}
+	r.user.TwoFactor = &types.TwoFactor{Secret: secret, RecoveryCodes: []string{}}
+	return true, nil
+}
+
+func (r *memoryRepo) Enable(ctx context.Context, idUser primitive.ObjectID, secret string, step int64, recoveryCodes []string, now time.Time) (bool, error) {
+	tf := r.user.TwoFactor
+	if tf == nil || tf.Enabled || tf.Secret != secret || tf.LastStep >= step {
+		return false, nil
+	}
+	tf.Enabled, tf.EnabledAt, tf.LastStep, tf.RecoveryCodes = true, &now, step, recoveryCodes
+	return true, nil
+}
+
+func (r *memoryRepo) UseStep(ctx context.Context, idUser primitive.ObjectID, step int64) (bool, error) {
+	if !r.user.TwoFactorEnabled() || r.user.TwoFactor.LastStep >= step {
+		return false, nil
+	}
+	r.user.TwoFactor.LastStep = step
+	return true, nil
+}
+
+func (r *memoryRepo) UseRecoveryCode(ctx context.Context, idUser primitive.ObjectID, hash string) (bool, error) {
+	if !r.user.TwoFactorEnabled() {
+		return false, nil
+	}
+	codes := r.user.TwoFactor.RecoveryCodes
+	for i, code := range codes {
+		if code == hash {
+			r.user.TwoFactor.RecoveryCodes = append(codes[:i:i], codes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepo) SetRecoveryCodes(ctx context.Context, idUser primitive.ObjectID, recoveryCodes []string) (bool, error) {
+	if !r.user.TwoFactorEnabled() {
+		return false, nil
+	}
+	r.user.TwoFactor.RecoveryCodes = recoveryCodes
+	return true, nil
+}
+
+func (r *memoryRepo) Disable(ctx context.Context, idUser primitive.ObjectID) (bool, error) {
+	enabled := r.user.TwoFactorEnabled()
+	r.user.TwoFactor = nil
+	return enabled, nil
+}
+
+func TestEnrollAndLogin(t *testing.T) {
+	conf := &config.Configs{}
+	conf.Jwt.Duration = time.Hour
+	conf.TwoFactor = config.TwoFactor{Issuer: "Dating", Skew: 1, TokenTTL: time.Minute, RecoveryCodes: 2}
+	repo := &memoryRepo{user: types.User{ID: primitive.NewObjectID(), Email: "lan@example.com", Status: types.StatusActive}}
+	s := NewService(conf, &config.ErrorMessage{}, repo, glog.New())
+	ctx := context.Background()
+	idUser := repo.user.ID.Hex()
+
+	enrollment, err := s.Enroll(ctx, idUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Confirm(ctx, idUser, "12345x"); err != ErrInvalidCode {
+		t.Errorf("Confirm with a wrong code = %v; expected %v", err, ErrInvalidCode)
+	}
+	code, _ := totp.Code(enrollment.Secret, totp.Step(time.Now()))
+	recovery, err := s.Confirm(ctx, idUser, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovery.RecoveryCodes) != 2 {
+		t.Fatalf("%d recovery codes; expected 2", len(recovery.RecoveryCodes))
+	}
+	if _, err := s.Enroll(ctx, idUser); err != ErrConflict {
+		t.Errorf("Enroll once enabled = %v; expected %v", err, ErrConflict)
+	}
+
+	token, _ := jwt.GenPurposeToken(repo.user.ID, types.PurposeTwoFactor, time.Minute)
+	if _, err := s.Login(ctx, types.TwoFactorLogin{Token: token, Code: code}); err != ErrInvalidCode {
+		t.Errorf("Login with a used code = %v; expected %v", err, ErrInvalidCode)
+	}
+	login, err := s.Login(ctx, types.TwoFactorLogin{Token: token, Code: recovery.RecoveryCodes[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := jwt.IsAuthorized(login.Token); err != nil || claims["mfa"] != true {
+		t.Errorf("claims of the login = %v, %v; expected mfa", claims, err)
+	}
+	if _, err := s.Login(ctx, types.TwoFactorLogin{Token: token, Code: recovery.RecoveryCodes[0]}); err != ErrInvalidCode {
+		t.Errorf("Login with a used recovery code = %v; expected %v", err, ErrInvalidCode)
+	}
+	if _, err := s.Login(ctx, types.TwoFactorLogin{Token: login.Token, Code: recovery.RecoveryCodes[1]}); err != ErrInvalidCode {
+		t.Errorf("Login with an access token = %v; expected %v", err, ErrInvalidCode)
+	}
+	if _, err := jwt.IsAuthorized(token); err == nil {
+		t.Errorf("two-factor token authorized as an access token")
+	}
+}
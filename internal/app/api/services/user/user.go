@@ -3,45 +3,104 @@ package userservices
 import (
 	"context"
 	"strconv"
+	"strings"
 	"time"
 
 	"dating/internal/app/api/types"
 	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/cache"
 	"dating/internal/pkg/glog"
 	"dating/internal/pkg/jwt"
+	"dating/internal/pkg/media"
 
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+var (
+	// ErrAccountSuspended is returned when a suspended user logs in
+	ErrAccountSuspended = apperr.NewForbidden("invalid_value.account_suspended", "account is suspended")
+	// ErrAccountBanned is returned when a banned user logs in
+	ErrAccountBanned = apperr.NewForbidden("invalid_value.account_banned", "account is banned")
+	// ErrInvalidField is returned when a patch names a field that can't be updated or removed
+	ErrInvalidField = apperr.NewValidation("", "invalid field")
+	// ErrPreconditionFailed is returned when the user was modified since the version the client has
+	ErrPreconditionFailed = apperr.New(apperr.PreconditionFailed, "", "precondition failed")
+	// ErrEmailExists is returned when signing up with the email of an account
+	ErrEmailExists = apperr.NewConflict("invalid_value.email_exists", "email exists")
+	// ErrIncorrectLogin is returned when the email or the password is wrong
+	ErrIncorrectLogin = apperr.New(apperr.Unauthorized, "invalid_value.incorrect_password_email", "incorrect password or email")
+)
+
+// patchable lists the fields a patch may update with the value a removed field is reset to,
+// nil when the field is required and can't be removed
+var patchable = map[string]interface{}{
+	"name":         nil,
+	"birthday":     nil,
+	"gender":       nil,
+	"country":      nil,
+	"relationship": "",
+	"looking_for":  "",
+	"media":        []string{},
+	"sex":          "",
+	"hobby":        []string{},
+	"interests":    []string{},
+	"about":        "",
+	"timezone":     "",
+	"locale":       "",
+	"location":     (*types.Location)(nil),
+	"height":       0,
+	"education":    "",
+	"job":          "",
+	"languages":    []string{},
+	"prompts":      []types.Prompt{},
+}
+
 // Repository is an interface of a user repository
 type Repository interface {
 	FindByID(ctx context.Context, id string) (*types.UserResGetInfo, error)
+	FindProfileByID(ctx context.Context, id string) (*types.UserProfile, error)
+	PatchUserByID(ctx context.Context, id string, set map[string]interface{}, updatedAt *time.Time) (*types.UserProfile, error)
 	FindByEmail(ctx context.Context, email string) (*types.User, error)
 	Insert(ctx context.Context, User types.User) error
 	UpdateUserByID(ctx context.Context, User types.User) error
-	GetListUsers(ctx context.Context, ps types.PagingNSorting) ([]*types.UserResGetInfo, error)
-	CountUser(ctx context.Context, ps types.PagingNSorting) (int64, error)
+	GetListUsers(ctx context.Context, idUser string, ps types.PagingNSorting) ([]*types.UserResGetInfo, []interface{}, error)
+	CountUser(ctx context.Context, idUser string, ps types.PagingNSorting) (int64, error)
 	GetListlikedInfo(ctx context.Context, idUser string) ([]*types.UserResGetInfo, error)
 	GetListMatchedInfo(ctx context.Context, idUser string) ([]*types.UserResGetInfo, error)
-	DisableUserByID(ctx context.Context, idUser string, disable bool) error
+	LiftSuspension(ctx context.Context, idUser string, now time.Time) (bool, error)
+	TouchLastActive(ctx context.Context, idUser string) error
+	FindViewer(ctx context.Context, idUser string) (*types.Candidate, error)
+	UpdatePreferences(ctx context.Context, idUser string, preferences types.Preferences) error
+}
+
+// Interests is an interface of the interests catalogue
+type Interests interface {
+	Check(ids []string) error
+	Known(ids []string) error
 }
 
 // Service is an user service
 type Service struct {
-	conf   *config.Configs
-	em     *config.ErrorMessage
-	repo   Repository
-	logger glog.Logger
+	conf      *config.Configs
+	em        *config.ErrorMessage
+	repo      Repository
+	interests Interests
+	counts    *cache.TTL
+	logger    glog.Logger
 }
 
 // NewService returns a new user service
-func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, l glog.Logger) *Service {
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, i Interests, l glog.Logger) *Service {
 	return &Service{
-		conf:   c,
-		em:     e,
-		repo:   r,
-		logger: l,
+		conf:      c,
+		em:        e,
+		repo:      r,
+		interests: i,
+		counts:    cache.New(c.Discovery.CountCacheTTL),
+		logger:    l,
 	}
 }
 
@@ -50,7 +109,7 @@ func (s *Service) SignUp(ctx context.Context, UserSignUp types.UserSignUp) (*typ
 
 	if _, err := s.repo.FindByEmail(ctx, UserSignUp.Email); err == nil {
 		s.logger.Errorf("Email email exits", err)
-		return nil, errors.Wrap(errors.New("Email email exits"), "Email exits, can't insert user")
+		return nil, ErrEmailExists
 	}
 
 	UserSignUp.Password, _ = jwt.HashPassword(UserSignUp.Password)
@@ -60,7 +119,8 @@ func (s *Service) SignUp(ctx context.Context, UserSignUp types.UserSignUp) (*typ
 		Name:     UserSignUp.Name,
 		Email:    UserSignUp.Email,
 		Password: UserSignUp.Password,
-		Disable:  false,
+		Status:   types.StatusActive,
+		Role:     types.RoleUser,
 		Media:    []string{},
 		Hobby:    []string{},
 		CreateAt: time.Now(),
@@ -76,6 +136,7 @@ func (s *Service) SignUp(ctx context.Context, UserSignUp types.UserSignUp) (*typ
 		ID:    user.ID,
 		Name:  user.Name,
 		Email: user.Email,
+		Role:  user.Role,
 	}, s.conf.Jwt.Duration)
 
 	if err != nil {
@@ -85,9 +146,10 @@ func (s *Service) SignUp(ctx context.Context, UserSignUp types.UserSignUp) (*typ
 	s.logger.Infof("Register completed", UserSignUp)
 
 	return &types.UserResponseSignUp{
-		Name:  UserSignUp.Name,
-		Email: UserSignUp.Email,
-		Token: tokenString}, nil
+		Name:   UserSignUp.Name,
+		Email:  UserSignUp.Email,
+		Token:  tokenString,
+		Status: user.Status}, nil
 
 }
 
@@ -97,19 +159,63 @@ func (s *Service) Login(ctx context.Context, UserLogin types.UserLogin) (*types.
 	user, err := s.repo.FindByEmail(ctx, UserLogin.Email)
 	if err != nil {
 		s.logger.Errorf("Not found email exits", err)
-		return nil, errors.Wrap(errors.New("Not found email exits"), "Email not exists, can't find user")
+		return nil, ErrIncorrectLogin
 	}
 
 	if !jwt.IsCorrectPassword(UserLogin.Password, user.Password) {
 		s.logger.Errorf("Password incorrect", UserLogin.Email)
-		return nil, errors.Wrap(errors.New("Password isn't like password from database"), "Password incorrect")
+		return nil, ErrIncorrectLogin
+	}
+
+	// paused users and users pending deletion login, e.g. to resume or restore their account
+	now := time.Now()
+	status := user.AccountStatus().At(now)
+	switch status {
+	case types.StatusSuspended:
+		s.logger.Infof("Suspended user tried to login %s", user.Email)
+		return nil, ErrAccountSuspended
+	case types.StatusBanned:
+		s.logger.Infof("Banned user tried to login %s", user.Email)
+		return nil, ErrAccountBanned
+	}
+	if user.Status == types.StatusSuspended {
+		// a suspension with an expiry is lifted at the first login after it
+		if _, err := s.repo.LiftSuspension(ctx, user.ID.Hex(), now); err != nil {
+			s.logger.Errorf("Can't lift expired suspension %v", err)
+			return nil, errors.Wrap(err, "Can't lift expired suspension")
+		}
+	}
+
+	// the login waits for the second factor, exchanged with the token at /login/2fa
+	if user.TwoFactorEnabled() {
+		token, err := jwt.GenPurposeToken(user.ID, types.PurposeTwoFactor, s.conf.TwoFactor.TokenTTL)
+		if err != nil {
+			s.logger.Errorf("Can not gen two-factor token", err)
+			return nil, errors.Wrap(err, "Can't gen two-factor token")
+		}
+		s.logger.Infof("Login waits for two-factor %s", user.Email)
+		return &types.UserResponseSignUp{
+			Name:           user.Name,
+			Email:          user.Email,
+			Status:         status,
+			TwoFactorToken: token}, nil
+	}
+
+	if err := s.repo.TouchLastActive(ctx, user.ID.Hex()); err != nil {
+		s.logger.Errorf("Can't touch last active %v", err)
+	}
+
+	role := user.Role
+	if role == "" {
+		role = types.RoleUser
 	}
 
 	var tokenString string
 	tokenString, error := jwt.GenToken(types.UserFieldInToken{
 		ID:    user.ID,
 		Name:  user.Name,
-		Email: user.Email}, s.conf.Jwt.Duration)
+		Email: user.Email,
+		Role:  role}, s.conf.Jwt.Duration)
 
 	if error != nil {
 		s.logger.Errorf("Can not gen token", error)
@@ -117,9 +223,11 @@ func (s *Service) Login(ctx context.Context, UserLogin types.UserLogin) (*types.
 	}
 	s.logger.Infof("Login completed ", user.Email)
 	return &types.UserResponseSignUp{
-		Name:  user.Name,
-		Email: user.Email,
-		Token: tokenString}, nil
+		Name:           user.Name,
+		Email:          user.Email,
+		Token:          tokenString,
+		Status:         status,
+		TwoFactorSetup: s.conf.TwoFactor.Enforced(role)}, nil
 }
 
 // Get basic info for a user
@@ -136,9 +244,116 @@ func (s *Service) FindUserById(ctx context.Context, id string) (*types.UserResGe
 	return user, nil
 }
 
+// Get the full profile of the logged in user
+func (s *Service) FindMe(ctx context.Context, id string) (*types.UserProfile, error) {
+
+	user, err := s.repo.FindProfileByID(ctx, id)
+	if err != nil {
+		s.logger.Errorf("Not found id user %v", err)
+		return nil, errors.Wrap(err, "Failed to find id user from database")
+	}
+	s.logger.Infof("Find profile completed %s", id)
+	return user, nil
+}
+
+// Patch the fields of the logged in user, a field listed in fields but not set in the patch is removed.
+// When updatedAt is given the patch is only applied if the user wasn't modified since
+func (s *Service) PatchMe(ctx context.Context, id string, patch types.UserPatch, fields []string, updatedAt *time.Time) (*types.UserProfile, error) {
+
+	values, err := patch.Values()
+	if err != nil {
+		s.logger.Errorf("Can't read patch %v", err)
+		return nil, errors.Wrap(err, "Can't read patch")
+	}
+
+	if err := s.interests.Check(patch.Interests); err != nil {
+		return nil, errors.Wrap(ErrInvalidField, err.Error())
+	}
+	// media are fetched later, e.g. into an export, only the media hosts are trusted
+	for _, link := range patch.Media {
+		if err := media.CheckLink(link, s.conf.Media.Hosts); err != nil {
+			s.logger.Infof("Media %s can't be patched %v", link, err)
+			return nil, errors.Wrap(ErrInvalidField, err.Error())
+		}
+	}
+
+	set := map[string]interface{}{}
+	for _, field := range fields {
+		reset, ok := patchable[field]
+		if !ok {
+			s.logger.Infof("Field %s can't be patched", field)
+			return nil, ErrInvalidField
+		}
+		if value, ok := values[field]; ok {
+			set[field] = value
+			continue
+		}
+		if reset == nil {
+			s.logger.Infof("Required field %s can't be removed", field)
+			return nil, ErrInvalidField
+		}
+		set[field] = reset
+	}
+
+	user, err := s.repo.PatchUserByID(ctx, id, set, updatedAt)
+	if err == mongo.ErrNoDocuments && updatedAt != nil {
+		// tell a stale version apart from a missing user
+		if _, err := s.repo.FindProfileByID(ctx, id); err == nil {
+			s.logger.Infof("User %s was modified since %v", id, updatedAt)
+			return nil, ErrPreconditionFailed
+		}
+	}
+	if err != nil {
+		s.logger.Errorf("Failed when patch user %v", err)
+		return nil, errors.Wrap(err, "Failed when patch user")
+	}
+
+	s.logger.Infof("Patched user %s", id)
+	return user, nil
+}
+
+// Get the discovery preferences of the logged in user, defaults if never saved
+func (s *Service) GetPreferences(ctx context.Context, idUser string) (*types.Preferences, error) {
+
+	viewer, err := s.repo.FindViewer(ctx, idUser)
+	if err != nil {
+		s.logger.Errorf("Can't find user %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Can't find user")
+	}
+	if viewer.Preferences == nil {
+		preferences := types.DefaultPreferences()
+		return &preferences, nil
+	}
+	return viewer.Preferences, nil
+}
+
+// Put the discovery preferences of the logged in user
+func (s *Service) UpdatePreferences(ctx context.Context, idUser string, preferences types.Preferences) (*types.Preferences, error) {
+
+	if preferences.Genders == nil {
+		preferences.Genders = []string{}
+	}
+	if preferences.ShowMe == nil {
+		showMe := true
+		preferences.ShowMe = &showMe
+	}
+
+	if err := s.repo.UpdatePreferences(ctx, idUser, preferences); err != nil {
+		s.logger.Errorf("Failed when update preferences of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when update preferences")
+	}
+
+	s.logger.Infof("Updated preferences of %s", idUser)
+	return &preferences, nil
+}
+
 // Post update info for a user
 func (s *Service) UpdateUserByID(ctx context.Context, user types.User) error {
 
+	if err := s.interests.Check(user.Interests); err != nil {
+		return errors.Wrap(ErrInvalidField, err.Error())
+	}
+
 	err := s.repo.UpdateUserByID(ctx, user)
 
 	if err != nil {
@@ -149,51 +364,105 @@ func (s *Service) UpdateUserByID(ctx context.Context, user types.User) error {
 	return err
 }
 
-// Get list users by page
-func (s *Service) GetListUsers(ctx context.Context, page, size, minAge, maxAge, gender string) (*types.GetListUsersResponse, error) {
-
-	var pagingNSorting types.PagingNSorting
+// Get list users by page or after a cursor for the user idUser
+func (s *Service) GetListUsers(ctx context.Context, idUser string, query types.ListUsersQuery) (*types.GetListUsersResponse, error) {
 
-	if err := pagingNSorting.Init(page, size, minAge, maxAge, gender); err != nil {
-		s.logger.Errorf("Failed url parameters when get list users", err)
-		return nil, errors.Wrap(err, "Failed url parameters when get list users")
+	viewer, err := s.repo.FindViewer(ctx, idUser)
+	if err != nil {
+		s.logger.Errorf("Can't find user %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Can't find user")
 	}
+	// filters that weren't sent come from the saved preferences
+	minAge, maxAge, gender := viewer.Preferences.Defaults(query.MinAge, query.MaxAge, query.Gender)
 
-	var listUsersResponse types.GetListUsersResponse
+	var pagingNSorting types.PagingNSorting
 
-	total, err := s.repo.CountUser(ctx, pagingNSorting)
-	if err != nil {
-		s.logger.Errorf("Failed when get number users", err)
-		return nil, errors.Wrap(err, "Failed when get number users")
+	if err := pagingNSorting.Init(query.Page, query.Size, minAge, maxAge, gender, query.Interests); err != nil {
+		s.logger.Infof("Failed url parameters when get list users %v", err)
+		return nil, err
+	}
+	if err := pagingNSorting.InitSort(query.Sort); err != nil {
+		s.logger.Infof("Invalid sort %v", err)
+		return nil, err
+	}
+	if err := pagingNSorting.InitCursor(query.Cursor); err != nil {
+		s.logger.Infof("Invalid cursor %v", err)
+		return nil, err
+	}
+	if err := s.interests.Known(pagingNSorting.Filter.Interests); err != nil {
+		return nil, errors.Wrap(types.ErrInvalidParameter, err.Error())
+	}
+	if query.PhoneVerified != "" {
+		if pagingNSorting.Filter.PhoneVerified, err = strconv.ParseBool(query.PhoneVerified); err != nil {
+			return nil, errors.Wrap(types.ErrInvalidParameter, err.Error())
+		}
+	}
+	if query.Verified != "" {
+		if pagingNSorting.Filter.Verified, err = strconv.ParseBool(query.Verified); err != nil {
+			return nil, errors.Wrap(types.ErrInvalidParameter, err.Error())
+		}
 	}
-	numberUsers := int(total)
-	listUsersResponse.CurrentPage = pagingNSorting.Page
-	listUsersResponse.MaxItemsPerPage = int(pagingNSorting.Size)
-	listUsersResponse.TotalItems = numberUsers
-	listUsersResponse.TotalPages = int(numberUsers / pagingNSorting.Size)
-	// ex: total: 5, size: 2 => 3 page
-	if numberUsers%pagingNSorting.Size != 0 {
-		listUsersResponse.TotalPages += 1
+	pagingNSorting.Filter.Viewer = viewer
+	if viewer.Preferences != nil {
+		pagingNSorting.Filter.MaxDistance = viewer.Preferences.MaxDistance
 	}
 
-	if pagingNSorting.Size > numberUsers {
-		listUsersResponse.MaxItemsPerPage = numberUsers
+	var listUsersResponse types.GetListUsersResponse
+	listUsersResponse.Sort = pagingNSorting.SortString()
+	listUsersResponse.MaxItemsPerPage = pagingNSorting.Size
+	if pagingNSorting.After == nil {
+		listUsersResponse.CurrentPage = pagingNSorting.Page
 	}
 
-	listUsers, err := s.repo.GetListUsers(ctx, pagingNSorting)
+	// -1 when the client doesn't need the count
+	listUsersResponse.TotalItems, listUsersResponse.TotalPages = -1, -1
+	if query.Count != "false" {
+		key := strings.Join([]string{idUser, minAge, maxAge, gender, query.Interests, strconv.Itoa(pagingNSorting.Filter.MaxDistance),
+			strconv.FormatBool(pagingNSorting.Filter.PhoneVerified), strconv.FormatBool(pagingNSorting.Filter.Verified)}, "|")
+		numberUsers, err := s.countUsers(ctx, idUser, key, pagingNSorting)
+		if err != nil {
+			s.logger.Errorf("Failed when get number users %v", err)
+			return nil, errors.Wrap(err, "Failed when get number users")
+		}
+		listUsersResponse.TotalItems = numberUsers
+		// ex: total: 5, size: 2 => 3 page
+		listUsersResponse.TotalPages = (numberUsers + pagingNSorting.Size - 1) / pagingNSorting.Size
+	}
 
+	listUsers, last, err := s.repo.GetListUsers(ctx, idUser, pagingNSorting)
 	if err != nil {
-		s.logger.Errorf("Failed when get list users by page", err)
+		s.logger.Errorf("Failed when get list users by page %v", err)
 		return nil, errors.Wrap(err, "Failed when get list users by page")
 	}
 
-	listUsersResponse.ListUsers = append(listUsersResponse.ListUsers, listUsers...)
+	// a full page may have a next one
+	if len(listUsers) == pagingNSorting.Size {
+		if listUsersResponse.NextCursor, err = pagingNSorting.Cursor(last); err != nil {
+			s.logger.Errorf("Can't make cursor %v", err)
+			return nil, errors.Wrap(err, "Can't make cursor")
+		}
+	}
+
+	listUsersResponse.ListUsers = append([]*types.UserResGetInfo{}, listUsers...)
 	listUsersResponse.Filter = pagingNSorting.Filter
-	s.logger.Infof("get list users by page is completed, page: ", pagingNSorting)
+	s.logger.Infof("get list users by page is completed, page: %d, size: %d", pagingNSorting.Page, pagingNSorting.Size)
 
 	return &listUsersResponse, nil
 }
 
+// countUsers counts the users of a listing, the count of a filter is reused for a while
+func (s *Service) countUsers(ctx context.Context, idUser, key string, ps types.PagingNSorting) (int, error) {
+	if count, ok := s.counts.Get(key); ok {
+		return count.(int), nil
+	}
+	total, err := s.repo.CountUser(ctx, idUser, ps)
+	if err != nil {
+		return 0, err
+	}
+	s.counts.Set(key, int(total))
+	return int(total), nil
+}
+
 // get list user liked
 func (s *Service) listLiked(ctx context.Context, userID string) ([]types.UserResGetInfo, error) {
 	list, err := s.repo.GetListlikedInfo(ctx, userID)
@@ -242,16 +511,3 @@ func (s *Service) convertPointerArrayToArray(list []*types.UserResGetInfo) []typ
 	}
 	return listUsers
 }
-
-// helps Enable/Disable account
-func (s *Service) DisableUserByID(ctx context.Context, idUser string, disable bool) error {
-
-	if err := s.repo.DisableUserByID(ctx, idUser, disable); err != nil {
-		s.logger.Errorf("Set disable to %d for user %s failed", disable, idUser, err)
-		return err
-	}
-
-	s.logger.Infof("Set disable to %d for user %s completed", disable, idUser)
-	return nil
-
-}
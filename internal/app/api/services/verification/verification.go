@@ -0,0 +1,176 @@
+package verificationservices
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/glog"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrConflict is returned when the photo of the user is verified or a request is pending
+	ErrConflict = apperr.NewConflict("invalid_value.verification_conflict", "verification conflict")
+	// ErrPromptExpired is returned when the selfie is sent without a pose or after it expired
+	ErrPromptExpired = apperr.NewValidation("invalid_value.verification_expired", "prompt expired")
+	// ErrNoPoses is returned when no pose is configured
+	ErrNoPoses = errors.New("no poses configured")
+)
+
+// Repository is an interface of a verification repository
+type Repository interface {
+	IsVerified(ctx context.Context, idUser primitive.ObjectID) (bool, error)
+	FindPrompt(ctx context.Context, idUser primitive.ObjectID) (*types.VerificationPrompt, error)
+	SavePrompt(ctx context.Context, prompt types.VerificationPrompt) error
+	DeletePrompt(ctx context.Context, idUser primitive.ObjectID) error
+	InsertRequest(ctx context.Context, request types.VerificationRequest) error
+	FindLatestRequest(ctx context.Context, idUser primitive.ObjectID) (*types.VerificationRequest, error)
+}
+
+// Service is a photo verification service, moderators review the requests with the admin api
+type Service struct {
+	conf   *config.Configs
+	em     *config.ErrorMessage
+	repo   Repository
+	logger glog.Logger
+}
+
+// NewService returns a new verification service
+func NewService(c *config.Configs, e *config.ErrorMessage, r Repository, l glog.Logger) *Service {
+	return &Service{
+		conf:   c,
+		em:     e,
+		repo:   r,
+		logger: l,
+	}
+}
+
+// GetStatus tells if the photo of a user is verified, with the last request
+func (s *Service) GetStatus(ctx context.Context, idUser string) (*types.VerificationStatus, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := s.repo.IsVerified(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Failed when find verified of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when find verified")
+	}
+	request, err := s.repo.FindLatestRequest(ctx, userID)
+	if err != nil && err != mongo.ErrNoDocuments {
+		s.logger.Errorf("Failed when find request of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when find request")
+	}
+	return &types.VerificationStatus{Verified: verified, Request: request}, nil
+}
+
+// Prompt gives a random pose to a user, the selfie sent next must show it
+func (s *Service) Prompt(ctx context.Context, idUser string) (*types.VerificationPromptResponse, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkConflict(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	poses := s.conf.Verification.Poses
+	if len(poses) == 0 {
+		s.logger.Errorf("No poses configured for the photo verification")
+		return nil, ErrNoPoses
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(poses))))
+	if err != nil {
+		return nil, err
+	}
+	prompt := types.VerificationPrompt{
+		UserID:    userID,
+		Pose:      poses[n.Int64()],
+		ExpiresAt: time.Now().Add(s.conf.Verification.PromptTTL),
+	}
+	if err := s.repo.SavePrompt(ctx, prompt); err != nil {
+		s.logger.Errorf("Failed when save prompt of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when save prompt")
+	}
+
+	return &types.VerificationPromptResponse{
+		Pose:      prompt.Pose,
+		ExpiresAt: prompt.ExpiresAt,
+	}, nil
+}
+
+// Submit queues the selfie of a user taken in the pose of the prompt for review
+func (s *Service) Submit(ctx context.Context, idUser string, req types.VerificationSubmit) (*types.VerificationRequest, error) {
+	userID, err := primitive.ObjectIDFromHex(idUser)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkConflict(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	prompt, err := s.repo.FindPrompt(ctx, userID)
+	if err == mongo.ErrNoDocuments || (err == nil && !now.Before(prompt.ExpiresAt)) {
+		return nil, ErrPromptExpired
+	}
+	if err != nil {
+		s.logger.Errorf("Failed when find prompt of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when find prompt")
+	}
+
+	request := types.VerificationRequest{
+		ID:       primitive.NewObjectID(),
+		UserID:   userID,
+		Pose:     prompt.Pose,
+		Selfie:   req.Selfie,
+		Status:   types.VerificationPending,
+		CreateAt: now,
+	}
+	if err := s.repo.InsertRequest(ctx, request); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrConflict
+		}
+		s.logger.Errorf("Failed when insert request of %s %v", idUser, err)
+		return nil, errors.Wrap(err, "Failed when insert request")
+	}
+	// a pose is good for one selfie
+	if err := s.repo.DeletePrompt(ctx, userID); err != nil {
+		s.logger.Errorf("Failed when delete prompt of %s %v", idUser, err)
+	}
+
+	s.logger.Infof("Verification requested %s", idUser)
+	return &request, nil
+}
+
+// checkConflict returns ErrConflict when the photo of a user is verified or waits for review
+func (s *Service) checkConflict(ctx context.Context, userID primitive.ObjectID) error {
+	verified, err := s.repo.IsVerified(ctx, userID)
+	if err != nil {
+		s.logger.Errorf("Failed when find verified of %s %v", userID.Hex(), err)
+		return errors.Wrap(err, "Failed when find verified")
+	}
+	if verified {
+		return ErrConflict
+	}
+	request, err := s.repo.FindLatestRequest(ctx, userID)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		s.logger.Errorf("Failed when find request of %s %v", userID.Hex(), err)
+		return errors.Wrap(err, "Failed when find request")
+	}
+	if request.Status == types.VerificationPending {
+		return ErrConflict
+	}
+	return nil
+}
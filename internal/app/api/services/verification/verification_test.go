@@ -0,0 +1,117 @@
+package verificationservices
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/glog"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// memoryRepo keeps the prompts and the requests in memory
+type memoryRepo struct {
+	verified map[primitive.ObjectID]bool
+	prompts  map[primitive.ObjectID]types.VerificationPrompt
+	requests []types.VerificationRequest
+}
+
+func (r *memoryRepo) IsVerified(ctx context.Context, idUser primitive.ObjectID) (bool, error) {
+	return r.verified[idUser], nil
+}
+
+func (r *memoryRepo) FindPrompt(ctx context.Context, idUser primitive.ObjectID) (*types.VerificationPrompt, error) {
+	prompt, ok := r.prompts[idUser]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &prompt, nil
+}
+
+func (r *memoryRepo) SavePrompt(ctx context.Context, prompt types.VerificationPrompt) error {
+	r.prompts[prompt.UserID] = prompt
+	return nil
+}
+
+func (r *memoryRepo) DeletePrompt(ctx context.Context, idUser primitive.ObjectID) error {
+	delete(r.prompts, idUser)
+	return nil
+}
+
+func (r *memoryRepo) InsertRequest(ctx context.Context, request types.VerificationRequest) error {
+	r.requests = append(r.requests, request)
+	return nil
+}
+
+func (r *memoryRepo) FindLatestRequest(ctx context.Context, idUser primitive.ObjectID) (*types.VerificationRequest, error) {
+	for i := len(r.requests) - 1; i >= 0; i-- {
+		if r.requests[i].UserID == idUser {
+			request := r.requests[i]
+			return &request, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func TestSubmit(t *testing.T) {
+	conf := &config.Configs{}
+	conf.Verification = config.Verification{Poses: []string{"Give a thumbs up"}, PromptTTL: time.Minute}
+	repo := &memoryRepo{verified: map[primitive.ObjectID]bool{}, prompts: map[primitive.ObjectID]types.VerificationPrompt{}}
+	s := NewService(conf, &config.ErrorMessage{}, repo, glog.New())
+	ctx := context.Background()
+	user := primitive.NewObjectID()
+	selfie := types.VerificationSubmit{Selfie: "media/selfie.jpg"}
+
+	if _, err := s.Submit(ctx, user.Hex(), selfie); err != ErrPromptExpired {
+		t.Errorf("Submit without a prompt = %v; expected %v", err, ErrPromptExpired)
+	}
+
+	// the pose expires
+	if _, err := s.Prompt(ctx, user.Hex()); err != nil {
+		t.Fatal(err)
+	}
+	prompt := repo.prompts[user]
+	prompt.ExpiresAt = time.Now().Add(-time.Second)
+	repo.prompts[user] = prompt
+	if _, err := s.Submit(ctx, user.Hex(), selfie); err != ErrPromptExpired {
+		t.Errorf("Submit after the prompt expired = %v; expected %v", err, ErrPromptExpired)
+	}
+
+	prompted, err := s.Prompt(ctx, user.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := s.Submit(ctx, user.Hex(), selfie)
+	if err != nil {
+		t.Fatalf("Submit = %v", err)
+	}
+	if request.Pose != prompted.Pose || request.Status != types.VerificationPending {
+		t.Errorf("request in pose %q, %s; expected %q, %s", request.Pose, request.Status, prompted.Pose, types.VerificationPending)
+	}
+
+	// one pending request at a time
+	if _, err := s.Prompt(ctx, user.Hex()); err != ErrConflict {
+		t.Errorf("Prompt while pending = %v; expected %v", err, ErrConflict)
+	}
+
+	// a rejected user tries again, a verified one can't
+	repo.requests[0].Status = types.VerificationRejected
+	if _, err := s.Prompt(ctx, user.Hex()); err != nil {
+		t.Errorf("Prompt after rejection = %v", err)
+	}
+	repo.verified[user] = true
+	if _, err := s.Prompt(ctx, user.Hex()); err != ErrConflict {
+		t.Errorf("Prompt once verified = %v; expected %v", err, ErrConflict)
+	}
+	status, err := s.GetStatus(ctx, user.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Verified || status.Request == nil || status.Request.ID != request.ID {
+		t.Errorf("status %+v; expected verified with request %v", status, request.ID)
+	}
+}
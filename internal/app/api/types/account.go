@@ -0,0 +1,83 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	StatusActive          = "active"
+	StatusPaused          = "paused"    // by the user, hidden from discovery but still chatting with matches
+	StatusSuspended       = "suspended" // by a moderator until status_until, until enabled again without it
+	StatusBanned          = "banned"
+	StatusPendingDeletion = "pending_deletion" // erased at delete_at unless restored
+)
+
+// ChatStatuses are the statuses of accounts shown to and chatting with their matches
+var ChatStatuses = []string{StatusActive, StatusPaused}
+
+// AccountStatus is the status of an account, with the role and the locale of the user which
+// are looked up along with it by the authentication of every request
+type AccountStatus struct {
+	Status string     `json:"status" bson:"status"`
+	Until  *time.Time `json:"status_until,omitempty" bson:"status_until,omitempty"`
+	Role   string     `json:"-" bson:"role,omitempty"`
+	Locale string     `json:"-" bson:"locale,omitempty"`
+}
+
+// At returns the status at now, a suspension is over once its until has passed
+func (a AccountStatus) At(now time.Time) string {
+	switch {
+	case a.Status == "":
+		return StatusActive
+	case a.Status == StatusSuspended && a.Until != nil && !now.Before(*a.Until):
+		return StatusActive
+	}
+	return a.Status
+}
+
+// CanSignIn tells if an account with status can login and use its tokens
+func CanSignIn(status string) bool {
+	return status != StatusSuspended && status != StatusBanned
+}
+
+// CanChat tells if an account with status can connect to its rooms
+func CanChat(status string) bool {
+	return status == StatusActive || status == StatusPaused
+}
+
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// Export is a job building the archive of the data of a user
+type Export struct {
+	ID          primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	UserID      primitive.ObjectID `json:"-" bson:"user_id"`
+	Status      string             `json:"status" bson:"status"`
+	DownloadURL string             `json:"download_url,omitempty" bson:"-"` // once ready
+	CreateAt    time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt   time.Time          `json:"expires_at" bson:"expires_at"` // the archive is deleted after
+}
+
+// ExportData is the data of a user in an export archive, media are files next to it
+type ExportData struct {
+	Profile      UserProfile `json:"profile"`
+	Likes        []*Match    `json:"likes"` // given, not matched
+	Matches      []*Match    `json:"matches"`
+	Messages     []*Message  `json:"messages"` // sent by the user
+	Media        []string    `json:"media"`    // files of the archive
+	MissingMedia []string    `json:"missing_media,omitempty"`
+	ExportedAt   time.Time   `json:"exported_at"`
+
+	Verifications      []*VerificationRequest `json:"verifications"` // selfies sent for the photo verification
+	VerificationPrompt *VerificationPrompt    `json:"verification_prompt,omitempty"`
+	PhoneVerification  *PhoneVerification     `json:"phone_verification,omitempty"` // the code itself isn't exported
+}
+
+type DeleteAccountResponse struct {
+	DeleteAt time.Time `json:"delete_at"` // until then the deletion can be cancelled
+}
@@ -0,0 +1,31 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAccountStatusAt(t *testing.T) {
+	now := time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	cases := []struct {
+		name         string
+		status       AccountStatus
+		at           string
+		signIn, chat bool
+	}{
+		{name: "saved before statuses", status: AccountStatus{}, at: StatusActive, signIn: true, chat: true},
+		{name: "paused", status: AccountStatus{Status: StatusPaused}, at: StatusPaused, signIn: true, chat: true},
+		{name: "suspended until later", status: AccountStatus{Status: StatusSuspended, Until: &future}, at: StatusSuspended},
+		{name: "suspended until enabled", status: AccountStatus{Status: StatusSuspended}, at: StatusSuspended},
+		{name: "suspension over", status: AccountStatus{Status: StatusSuspended, Until: &past}, at: StatusActive, signIn: true, chat: true},
+		{name: "banned", status: AccountStatus{Status: StatusBanned, Until: &past}, at: StatusBanned},
+		{name: "pending deletion", status: AccountStatus{Status: StatusPendingDeletion}, at: StatusPendingDeletion, signIn: true},
+	}
+	for _, c := range cases {
+		at := c.status.At(now)
+		if at != c.at || CanSignIn(at) != c.signIn || CanChat(at) != c.chat {
+			t.Errorf("%s: At = %s, CanSignIn %t, CanChat %t; expected %s, %t, %t", c.name, at, CanSignIn(at), CanChat(at), c.at, c.signIn, c.chat)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ModerationDisable = "disable"
+	ModerationBan     = "ban"
+)
+
+// Moderation is the last moderator action on a user account
+type Moderation struct {
+	Action string             `json:"action" bson:"action"`
+	Reason string             `json:"reason" bson:"reason"`
+	Until  *time.Time         `json:"until,omitempty" bson:"until,omitempty"` // nil means no expiry
+	By     primitive.ObjectID `json:"by" bson:"by"`
+	At     time.Time          `json:"at" bson:"at"`
+}
+
+type AuditLog struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	ActorID    primitive.ObjectID `json:"actor_id" bson:"actor_id"`
+	Action     string             `json:"action" bson:"action"`
+	TargetType string             `json:"target_type" bson:"target_type"` // user, message, report
+	TargetID   primitive.ObjectID `json:"target_id" bson:"target_id"`
+	Reason     string             `json:"reason" bson:"reason"`
+	Data       interface{}        `json:"data,omitempty" bson:"data,omitempty"`
+	CreateAt   time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type ModerationRequest struct {
+	Reason string     `json:"reason" validate:"required,max=500"`
+	Until  *time.Time `json:"until"` // disable only, nil means until enabled again
+}
+
+type ReasonRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+type RoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user moderator admin"`
+}
+
+type ReportStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=open reviewed dismissed actioned"`
+	Reason string `json:"reason" validate:"omitempty,max=500"`
+}
+
+type DeleteMediaRequest struct {
+	URL    string `json:"url" validate:"required"`
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+type AdminUserResponse struct {
+	User           User      `json:"user"`
+	RecentMessages []Message `json:"recent_messages"`
+}
@@ -5,9 +5,15 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// PurposeTwoFactor is the purpose of the token of a login waiting for its second factor
+const PurposeTwoFactor = "2fa"
+
 type Claims struct {
-	ID    primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
-	Email string             `json:"email"`
-	Name  string             `json:"name"`
+	ID      primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Email   string             `json:"email"`
+	Name    string             `json:"name"`
+	Role    string             `json:"role"`
+	MFA     bool               `json:"mfa,omitempty"`     // the login was confirmed with a second factor
+	Purpose string             `json:"purpose,omitempty"` // set on tokens which aren't access tokens
 	jwt.StandardClaims
 }
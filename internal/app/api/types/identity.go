@@ -0,0 +1,29 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Identity links a user to its subject at an OpenID Connect provider
+type Identity struct {
+	Provider string    `json:"provider" bson:"provider"`
+	Subject  string    `json:"-" bson:"subject"`
+	Email    string    `json:"email,omitempty" bson:"email,omitempty"` // at the provider
+	LinkedAt time.Time `json:"linked_at" bson:"linked_at"`
+}
+
+// OIDCState is a login started at a provider, taken once by its callback
+type OIDCState struct {
+	ID        string              `bson:"_id"`
+	Provider  string              `bson:"provider"`
+	Nonce     string              `bson:"nonce"`
+	Verifier  string              `bson:"verifier"`          // PKCE code verifier
+	UserID    *primitive.ObjectID `bson:"user_id,omitempty"` // the user linking the provider, nil on login
+	ExpiresAt time.Time           `bson:"expires_at"`
+}
+
+type AuthURLResponse struct {
+	URL string `json:"url"` // open it to login at the provider
+}
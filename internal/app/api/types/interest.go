@@ -0,0 +1,8 @@
+package types
+
+// Interest is an entry of the interests catalogue with its label in the requested language
+type Interest struct {
+	ID       string `json:"_id"`
+	Category string `json:"category"`
+	Label    string `json:"label"`
+}
@@ -6,18 +6,48 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Match is the like of user_id for target_user_id, a pair of users holds a single
+// match keyed by pair_key, the like first made, which becomes matched once liked back
 type Match struct {
 	ID           primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	PairKey      string             `json:"-" bson:"pair_key"`
 	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`
 	TargetUserID primitive.ObjectID `json:"target_user_id" bson:"target_user_id"`
 	Matched      bool               `json:"matched" bson:"matched"`
+	SuperLike    bool               `json:"super_like" bson:"super_like"`
 	CreateAt     time.Time          `json:"created_at" bson:"created_at"`
+	MatchedAt    *time.Time         `json:"matched_at,omitempty" bson:"matched_at,omitempty"`
+	ExpiresAt    *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // set once extended
+	Extended     bool               `json:"extended,omitempty" bson:"extended,omitempty"`
+	Reminded     bool               `json:"-" bson:"reminded,omitempty"`
+	Stale        bool               `json:"stale,omitempty" bson:"stale,omitempty"` // expired without a message
+	Messaged     bool               `json:"-" bson:"messaged,omitempty"`            // a message was sent in the room
+}
+
+// PairKey is the key of the match of two users, the same whoever liked first
+func PairKey(a, b primitive.ObjectID) string {
+	if b.Hex() < a.Hex() {
+		a, b = b, a
+	}
+	return a.Hex() + "_" + b.Hex()
+}
+
+// Deadline is when a match nobody wrote in expires
+func (m Match) Deadline(expireAfter time.Duration) time.Time {
+	if m.ExpiresAt != nil {
+		return *m.ExpiresAt
+	}
+	if m.MatchedAt != nil {
+		return m.MatchedAt.Add(expireAfter)
+	}
+	return m.CreateAt.Add(expireAfter)
 }
 
 type MatchRequest struct {
-	UserID       primitive.ObjectID `json:"user_id" bson:"user_id" validate:"required"`
+	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"` // the caller, set from the token
 	TargetUserID primitive.ObjectID `json:"target_user_id" bson:"target_user_id" validate:"required"`
 	Matched      bool               `json:"matched" bson:"matched"`
+	SuperLike    bool               `json:"-" bson:"-"`
 }
 type UserResGetInfoInRoom struct {
 	ID     primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
@@ -31,3 +61,45 @@ type MatchRoomResponse struct {
 	LastMessage *Message               `json:"last_message" bson:"last_message,omitempty"`
 	CreateAt    time.Time              `json:"created_at" bson:"created_at"`
 }
+
+// MatchSortFields are the fields the matches of a user can be sorted by
+var MatchSortFields = []string{"matched_at", "liked_at", "last_message"}
+
+// LikeSortFields are the fields the likes received by a user can be sorted by
+var LikeSortFields = []string{"liked_at", "super_like"}
+
+// MessagePreview is the beginning of the last message of a room
+type MessagePreview struct {
+	SenderID primitive.ObjectID `json:"sender_id" bson:"sender_id"`
+	Content  string             `json:"content" bson:"content"`
+	CreateAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// MatchItem is a match of a user with the other user of the match, the room id is the match id
+type MatchItem struct {
+	RoomID      primitive.ObjectID `json:"room_id" bson:"_id"`
+	User        UserResGetInfo     `json:"user" bson:"user"`
+	LikedAt     time.Time          `json:"liked_at" bson:"liked_at"`
+	MatchedAt   time.Time          `json:"matched_at" bson:"matched_at"`
+	LastMessage *MessagePreview    `json:"last_message" bson:"last_message,omitempty"`
+	ExpiresAt   *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // until somebody writes
+	Extended    bool               `json:"extended" bson:"extended"`
+	Stale       bool               `json:"stale,omitempty" bson:"stale"`
+}
+
+// LikeItem is a like received by a user who has not liked back yet
+type LikeItem struct {
+	User      UserResGetInfo `json:"user" bson:"user"`
+	SuperLike bool           `json:"super_like" bson:"super_like"`
+	LikedAt   time.Time      `json:"liked_at" bson:"liked_at"`
+}
+
+type GetMatchesResponse struct {
+	Pagination
+	Matches []*MatchItem `json:"matches"`
+}
+
+type GetLikesResponse struct {
+	Pagination
+	Likes []*LikeItem `json:"likes"`
+}
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	NotificationNewMatch   = "new-match"
+	NotificationNewMessage = "new-message"
+	NotificationLikedYou   = "liked-you"
+	NotificationSuperLiked = "super-liked"
+	NotificationReminder   = "match-reminder"
+	NotificationExpired    = "match-expired"
+	NotificationUnmatched  = "unmatched"
+	// NotificationVerified tells the user the review of the photo verification
+	NotificationVerified = "verification-reviewed"
+)
+
+type Notification struct {
+	ID       primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	UserID   primitive.ObjectID `json:"user_id" bson:"user_id"`   // recipient
+	ActorID  primitive.ObjectID `json:"actor_id" bson:"actor_id"` // user who triggered the event
+	Type     string             `json:"type" bson:"type"`
+	Data     interface{}        `json:"data" bson:"data"`
+	Title    string             `json:"title,omitempty" bson:"title,omitempty"` // in the locale of the recipient
+	Body     string             `json:"body,omitempty" bson:"body,omitempty"`
+	Read     bool               `json:"read" bson:"read"`
+	CreateAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type GetListNotificationsResponse struct {
+	Unread        int64          `json:"unread"`
+	Notifications []Notification `json:"notifications"`
+}
@@ -2,21 +2,66 @@
 package types
 
 import (
+	"encoding/base64"
 	"strconv"
 	"strings"
 	"time"
 
+	"dating/internal/pkg/apperr"
+
 	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrInvalidParameter is returned when an url parameter of a listing is invalid
+var ErrInvalidParameter = apperr.NewValidation("", "invalid parameter")
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
 )
 
+// SortFields are the fields a user listing can be sorted by
+var SortFields = []string{"created_at", "updated_at", "last_active", "age", "distance"}
+
 type PagingNSorting struct {
-	Size   int    `json:"size" default:"100"`
-	Page   int    `json:"page" default:"1"`
-	Filter Filter `json:"filter"`
+	Size   int           `json:"size" default:"20"`
+	Page   int           `json:"page" default:"1"`
+	Filter Filter        `json:"filter"`
+	Sort   []SortField   `json:"sort"` // ties are broken by _id
+	After  []interface{} `json:"-"`    // sort key of the last user of the previous page, nil for the first page
+}
+
+// ListUsersQuery holds the url parameters of a user listing
+type ListUsersQuery struct {
+	Page      string
+	Size      string
+	MinAge    string
+	MaxAge    string
+	Gender    string
+	Interests string
+	Sort      string
+	Cursor    string
+	Count     string // "false" skips counting
+	// PhoneVerified "true" lists only users with a verified phone
+	PhoneVerified string
+	// Verified "true" lists only users with a verified photo
+	Verified string
+}
+
+// SortField is a field to sort by, e.g. "-created_at" sorts by created_at descending
+type SortField struct {
+	Field string
+	Desc  bool
 }
 type Filter struct {
-	AgeRange AgeRange `json:"age"`
-	Gender   []string `json:"gender" default:"" bson:"gender,omitempty"`
+	AgeRange      AgeRange   `json:"age"`
+	Gender        []string   `json:"gender" default:"" bson:"gender,omitempty"` // empty is any gender
+	Interests     []string   `json:"interests,omitempty" bson:"interests,omitempty"`
+	MaxDistance   int        `json:"max_distance,omitempty" bson:"-"` // km around the viewer, 0 is no limit
+	PhoneVerified bool       `json:"phone_verified,omitempty" bson:"-"`
+	Verified      bool       `json:"verified,omitempty" bson:"-"`
+	Viewer        *Candidate `json:"-" bson:"-"` // the user browsing, whose preferences the results must fit
 }
 type AgeRange struct {
 	Gte time.Time `json:"gte"`
@@ -28,9 +73,12 @@ type Pagination struct {
 	CurrentPage     int    `json:"currentPage"`
 	MaxItemsPerPage int    `json:"maxItemsPerPage"`
 	Filter          Filter `json:"filter"`
+	Sort            string `json:"sort,omitempty"`
 }
 
-func (ps *PagingNSorting) Init(page, size, minAge, maxAge, genderStr string) error {
+func (ps *PagingNSorting) Init(page, size, minAge, maxAge, genderStr, interests string) error {
+
+	ps.Filter.Interests = interestsInit(interests)
 
 	gender, err := genderInit(genderStr)
 	if err != nil {
@@ -38,59 +86,129 @@ func (ps *PagingNSorting) Init(page, size, minAge, maxAge, genderStr string) err
 	}
 	ps.Filter.Gender = gender
 
-	time, err := convertAgeRangeToDate(minAge, maxAge)
+	ageRange, err := convertAgeRangeToDate(minAge, maxAge)
 	if err != nil {
 		return err
 	}
-	ps.Filter.AgeRange = *time
+	ps.Filter.AgeRange = *ageRange
 
-	if size == "" && page == "" {
-		ps.Size = 100
-		ps.Page = 1
-		return nil
+	ps.Page, ps.Size, err = ParsePage(page, size, DefaultPageSize, MaxPageSize)
+	return err
+}
+
+// InitSort parses a comma separated list of sort fields, a field prefixed by - is descending
+func (ps *PagingNSorting) InitSort(sort string) error {
+	var err error
+	ps.Sort, err = ParseSort(sort, SortFields)
+	return err
+}
+
+// ParseSort parses a comma separated list of sort fields, each one of fields
+func ParseSort(sort string, fields []string) ([]SortField, error) {
+	var list []SortField
+	for _, v := range strings.Split(sort, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		field := SortField{Field: strings.TrimPrefix(v, "-"), Desc: strings.HasPrefix(v, "-")}
+		if !stringInSlice(field.Field, fields) {
+			return nil, errors.Wrapf(ErrInvalidParameter, "sort %s not in arr %v", field.Field, fields)
+		}
+		list = append(list, field)
 	}
+	return list, nil
+}
 
-	sizeInt, err := strconv.ParseInt(size, 10, 64)
-	if err != nil {
-		return err
+// SortString returns the sort in the url parameter form
+func (ps PagingNSorting) SortString() string {
+	list := make([]string, 0, len(ps.Sort))
+	for _, field := range ps.Sort {
+		if field.Desc {
+			list = append(list, "-"+field.Field)
+		} else {
+			list = append(list, field.Field)
+		}
 	}
+	return strings.Join(list, ",")
+}
 
-	if page == "" {
-		page = "1" // default page = 1
+// InitCursor parses the cursor of the next page, it must be made for the same sort
+func (ps *PagingNSorting) InitCursor(cursor string) error {
+	if cursor == "" {
+		return nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return errors.Wrap(ErrInvalidParameter, "invalid cursor")
 	}
+	var c pageCursor
+	if err := bson.UnmarshalExtJSON(raw, true, &c); err != nil || len(c.Key) == 0 {
+		return errors.Wrap(ErrInvalidParameter, "invalid cursor")
+	}
+	if c.Sort != ps.SortString() {
+		return errors.Wrapf(ErrInvalidParameter, "cursor made for sort %q", c.Sort)
+	}
+	ps.After = c.Key
+	return nil
+}
 
-	pageInt, err := strconv.ParseInt(page, 10, 64)
+// Cursor returns the cursor of the page after the user with the sort key
+func (ps PagingNSorting) Cursor(key []interface{}) (string, error) {
+	raw, err := bson.MarshalExtJSON(pageCursor{Sort: ps.SortString(), Key: key}, true, false)
 	if err != nil {
-		return err
+		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
 
-	ps.Page = int(pageInt)
-	ps.Size = int(sizeInt)
+// pageCursor is the position in a listing, the sort key of the last user of a page
+type pageCursor struct {
+	Sort string        `bson:"s"`
+	Key  []interface{} `bson:"k"`
+}
 
-	return nil
+// ParsePage parses page (default 1) and size (default defaultSize, at most maxSize) url parameters
+func ParsePage(page, size string, defaultSize, maxSize int) (int, int, error) {
+	pageInt, sizeInt := 1, defaultSize
+	var err error
+	if page != "" {
+		if pageInt, err = strconv.Atoi(page); err != nil || pageInt < 1 {
+			return 0, 0, errors.Wrapf(ErrInvalidParameter, "invalid page %s", page)
+		}
+	}
+	if size != "" {
+		if sizeInt, err = strconv.Atoi(size); err != nil || sizeInt < 1 || sizeInt > maxSize {
+			return 0, 0, errors.Wrapf(ErrInvalidParameter, "invalid size %s, must be 1 - %d", size, maxSize)
+		}
+	}
+	return pageInt, sizeInt, nil
 }
 
 func genderInit(gender string) ([]string, error) {
-	genderArray := []string{"Male", "Female", "Both"}
-
-	if gender != "" {
-		split := strings.Split(gender, ",")
-		if len(split) > 1 {
-			for _, v := range split {
-				if !stringInSlice(v, genderArray) {
-					return nil, errors.Errorf("gender not in arr {Male, Female, Both}", gender)
-				}
-			}
-			return split, nil
-		}
+	genderArray := []string{"Male", "Female"}
 
-		if !stringInSlice(gender, genderArray) {
-			return nil, errors.Errorf("gender not in arr {Male, Female, Both}", gender)
+	list := []string{}
+	if gender == "" {
+		return list, nil
+	}
+	for _, v := range strings.Split(gender, ",") {
+		if !stringInSlice(v, genderArray) {
+			return nil, errors.Wrapf(ErrInvalidParameter, "gender %s not in arr {Male, Female}", v)
 		}
-		return []string{gender}, nil
+		list = append(list, v)
 	}
+	return list, nil
+}
 
-	return genderArray, nil
+// interestsInit splits a comma separated list of interest ids
+func interestsInit(interests string) []string {
+	list := []string{}
+	for _, v := range strings.Split(interests, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
 }
 
 // convertAgeRangeToDate, ex: 18 year olds (now 2021) -> year birdday 2003 ->
@@ -108,6 +226,9 @@ func convertAgeRangeToDate(minAgeStr, maxAgeStr string) (*AgeRange, error) {
 	if err != nil {
 		return nil, err
 	}
+	if min > max {
+		return nil, errors.Wrapf(ErrInvalidParameter, "minAge %d greater than maxAge %d", min, max)
+	}
 
 	return &AgeRange{
 		Gte: timeNow.AddDate(-int(max+1), 0, 0),
@@ -120,8 +241,8 @@ func convertAgeStr(ageStr string, defaultValue int) (int, error) {
 		return defaultValue, nil
 	} else {
 		value, err := strconv.ParseInt(ageStr, 10, 64)
-		if err != nil {
-			return -1, err
+		if err != nil || value < 0 || value > 150 {
+			return -1, errors.Wrapf(ErrInvalidParameter, "invalid age %s", ageStr)
 		}
 		return int(value), nil
 	}
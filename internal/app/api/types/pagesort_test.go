@@ -0,0 +1,71 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	var ps PagingNSorting
+	if err := ps.InitSort("-last_active,age"); err != nil {
+		t.Fatal(err)
+	}
+	id := primitive.NewObjectID()
+	active := primitive.NewDateTimeFromTime(time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC))
+	cursor, err := ps.Cursor([]interface{}{active, nil, id})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var next PagingNSorting
+	if err := next.InitSort("-last_active,age"); err != nil {
+		t.Fatal(err)
+	}
+	if err := next.InitCursor(cursor); err != nil {
+		t.Fatal(err)
+	}
+	if len(next.After) != 3 || next.After[0] != active || next.After[1] != nil || next.After[2] != id {
+		t.Errorf("After = %#v; expected %#v", next.After, []interface{}{active, nil, id})
+	}
+
+	var other PagingNSorting
+	if err := other.InitCursor(cursor); errors.Cause(err) != ErrInvalidParameter {
+		t.Errorf("InitCursor with another sort = %v; expected %v", err, ErrInvalidParameter)
+	}
+}
+
+func TestInvalidParameters(t *testing.T) {
+	cases := []struct {
+		name                                     string
+		page, size, minAge, maxAge, gender, sort string
+	}{
+		{name: "size zero", size: "0"},
+		{name: "size too big", size: "101"},
+		{name: "page zero", page: "0"},
+		{name: "negative age", minAge: "-1"},
+		{name: "min over max", minAge: "40", maxAge: "30"},
+		{name: "gender both", gender: "Both"},
+		{name: "unknown sort", sort: "email"},
+	}
+	for _, c := range cases {
+		var ps PagingNSorting
+		err := ps.Init(c.page, c.size, c.minAge, c.maxAge, c.gender, "")
+		if err == nil {
+			err = ps.InitSort(c.sort)
+		}
+		if errors.Cause(err) != ErrInvalidParameter {
+			t.Errorf("%s: err = %v; expected %v", c.name, err, ErrInvalidParameter)
+		}
+	}
+
+	var ps PagingNSorting
+	if err := ps.Init("", "", "", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if ps.Page != 1 || ps.Size != DefaultPageSize {
+		t.Errorf("page, size = %d, %d; expected 1, %d", ps.Page, ps.Size, DefaultPageSize)
+	}
+}
@@ -0,0 +1,33 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PhoneVerification is a code sent to the phone a user registers, one per user
+type PhoneVerification struct {
+	UserID    primitive.ObjectID `json:"-" bson:"_id"`
+	Phone     string             `json:"phone" bson:"phone"`
+	CodeHash  string             `json:"-" bson:"code_hash"`
+	Attempts  int                `json:"attempts" bson:"attempts"` // wrong codes entered
+	SentAt    time.Time          `json:"sent_at" bson:"sent_at"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+}
+
+// PhoneRequest registers a phone number, in E.164 format e.g. +84901234567
+type PhoneRequest struct {
+	Phone string `json:"phone" validate:"required,e164"`
+}
+
+// PhoneCode is the code sent to the phone
+type PhoneCode struct {
+	Code string `json:"code" validate:"required,numeric,max=10"`
+}
+
+type PhoneVerificationResponse struct {
+	Phone     string    `json:"phone"`
+	ExpiresAt time.Time `json:"expires_at"`
+	ResendAt  time.Time `json:"resend_at"` // a new code can't be sent before
+}
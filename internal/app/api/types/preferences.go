@@ -0,0 +1,57 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Preferences are who a user wants to see in discovery and whether the user is shown in it
+type Preferences struct {
+	MinAge      int      `json:"min_age" bson:"min_age" validate:"omitempty,min=18,max=100"`
+	MaxAge      int      `json:"max_age" bson:"max_age" validate:"omitempty,min=18,max=100,gtefield=MinAge"`
+	Genders     []string `json:"genders" bson:"genders" validate:"omitempty,unique,dive,oneof=Male Female"`
+	MaxDistance int      `json:"max_distance" bson:"max_distance" validate:"omitempty,min=1,max=500"` // km, 0 is no limit
+	ShowMe      *bool    `json:"show_me" bson:"show_me"`                                              // nil is shown
+}
+
+// DefaultPreferences are the preferences of a user who never saved any
+func DefaultPreferences() Preferences {
+	showMe := true
+	return Preferences{Genders: []string{}, ShowMe: &showMe}
+}
+
+// Defaults fills the discovery url parameters that weren't sent with the saved preferences
+func (p *Preferences) Defaults(minAge, maxAge, gender string) (string, string, string) {
+	if p == nil {
+		return minAge, maxAge, gender
+	}
+	if minAge == "" && p.MinAge > 0 {
+		minAge = strconv.Itoa(p.MinAge)
+	}
+	if maxAge == "" && p.MaxAge > 0 {
+		maxAge = strconv.Itoa(p.MaxAge)
+	}
+	if gender == "" && len(p.Genders) > 0 {
+		gender = strings.Join(p.Genders, ",")
+	}
+	return minAge, maxAge, gender
+}
+
+// Wants tells if the user fits the preferences at now, like the discovery filter an unset
+// preference and an unknown gender or birthday fit
+func (p *Preferences) Wants(u UserResGetInfo, now time.Time) bool {
+	if p == nil {
+		return true
+	}
+	if u.Gender != "" && len(p.Genders) > 0 && !stringInSlice(u.Gender, p.Genders) {
+		return false
+	}
+	if !u.Birthday.IsZero() {
+		age := u.Age(now)
+		if age < p.MinAge || (p.MaxAge > 0 && age > p.MaxAge) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,16 @@
+package types
+
+// Prompt is a profile prompt picked from a fixed list of questions with a free answer
+type Prompt struct {
+	Question string `json:"question" bson:"question" validate:"required,oneof=ideal_first_date two_truths_and_a_lie perfect_sunday green_flag life_goal travel_story unpopular_opinion looking_for_someone_who"`
+	Answer   string `json:"answer" bson:"answer" validate:"required,max=200"`
+}
+
+// Profile holds the structured fields of a user profile
+type Profile struct {
+	Height    int      `json:"height,omitempty" bson:"height,omitempty" validate:"omitempty,min=100,max=250"`
+	Education string   `json:"education,omitempty" bson:"education,omitempty" validate:"omitempty,oneof=high_school vocational college bachelor master doctorate other"`
+	Job       string   `json:"job,omitempty" bson:"job,omitempty" validate:"omitempty,oneof=student engineering healthcare education finance business arts media hospitality legal science government trades self_employed unemployed other"`
+	Languages []string `json:"languages,omitempty" bson:"languages,omitempty" validate:"omitempty,max=5,unique,dive,oneof=en vi fr de es it pt ru ja ko zh th id hi ar"`
+	Prompts   []Prompt `json:"prompts,omitempty" bson:"prompts,omitempty" validate:"omitempty,max=3,unique=Question,dive"`
+}
@@ -0,0 +1,39 @@
+package types
+
+import "time"
+
+// Location is where a user is, longitude first so it can be used as a legacy coordinate pair
+type Location struct {
+	Lng float64 `json:"lng" bson:"lng" validate:"min=-180,max=180"`
+	Lat float64 `json:"lat" bson:"lat" validate:"min=-90,max=90"`
+}
+
+// Candidate is a user as seen by the recommendation engine
+type Candidate struct {
+	UserResGetInfo `bson:",inline"`
+	Location       *Location    `bson:"location,omitempty"`
+	LastActiveAt   time.Time    `bson:"last_active_at"`
+	Preferences    *Preferences `bson:"preferences,omitempty"`
+}
+
+// ScoreBreakdown is the score of each component in [0, 1] before weighting
+type ScoreBreakdown struct {
+	Preference   float64 `json:"preference"`
+	Interests    float64 `json:"interests"`
+	Distance     float64 `json:"distance"`
+	Recency      float64 `json:"recency"`
+	Completeness float64 `json:"completeness"`
+}
+
+// Recommendation is a ranked user of the feed, the breakdown is only set in debug mode
+type Recommendation struct {
+	UserResGetInfo
+	Score      float64         `json:"score"`
+	DistanceKm *float64        `json:"distance_km,omitempty"`
+	Breakdown  *ScoreBreakdown `json:"breakdown,omitempty"`
+}
+
+type GetRecommendationsResponse struct {
+	Pagination
+	Recommendations []Recommendation `json:"recommendations"`
+}
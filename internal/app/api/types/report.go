@@ -0,0 +1,31 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ReportStatusOpen = "open"
+
+	// ReportReasonAutoFlagged is the reason of reports raised by the chat filters
+	ReportReasonAutoFlagged = "auto_flagged"
+)
+
+type Report struct {
+	ID         primitive.ObjectID   `json:"_id" bson:"_id,omitempty"`
+	ReporterID primitive.ObjectID   `json:"reporter_id" bson:"reporter_id"` // zero when raised by the system
+	ReportedID primitive.ObjectID   `json:"reported_id" bson:"reported_id"`
+	Reason     string               `json:"reason" bson:"reason"`
+	Details    string               `json:"details" bson:"details"`
+	MessageIDs []primitive.ObjectID `json:"message_ids" bson:"message_ids"` // evidence
+	Status     string               `json:"status" bson:"status"`
+	CreateAt   time.Time            `json:"created_at" bson:"created_at"`
+}
+
+type ReportRequest struct {
+	Reason     string               `json:"reason" validate:"required,oneof=spam harassment fake_profile inappropriate_content underage scam other"`
+	Details    string               `json:"details" validate:"omitempty,max=1000"`
+	MessageIDs []primitive.ObjectID `json:"message_ids" validate:"omitempty,max=20"`
+}
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"time"
+)
+
+// TwoFactor is the TOTP second factor of a user, it is pending until confirmed with a code
+type TwoFactor struct {
+	Secret        string     `bson:"secret"` // base32 TOTP secret
+	Enabled       bool       `bson:"enabled"`
+	EnabledAt     *time.Time `bson:"enabled_at,omitempty"`
+	LastStep      int64      `bson:"last_step"`      // step of the last code used, codes are used once
+	RecoveryCodes []string   `bson:"recovery_codes"` // sha256 of the unused recovery codes
+}
+
+// TwoFactorEnabled tells if logins of the user need a second factor
+func (u User) TwoFactorEnabled() bool {
+	return u.TwoFactor != nil && u.TwoFactor.Enabled
+}
+
+// TwoFactorEnrollment is a secret to add to an authenticator app, URI is its otpauth URI to show
+// as a QR code
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorStatus tells if two-factor authentication is enabled
+type TwoFactorStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesLeft int        `json:"recovery_codes_left"`
+	Required          bool       `json:"required"` // by the role of the user
+}
+
+// TwoFactorCode is a TOTP code or a recovery code
+type TwoFactorCode struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+// TwoFactorLogin completes a login waiting for its second factor
+type TwoFactorLogin struct {
+	Token string `json:"two_factor_token" validate:"required"`
+	Code  string `json:"code" validate:"required,max=32"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // shown once, each logs in once
+}
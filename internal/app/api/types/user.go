@@ -3,43 +3,134 @@ package types
 import (
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
-	ID           primitive.ObjectID `json:"_id" bson:"_id,omitempty" validate:"required"`
-	Name         string             `json:"name" bson:"name" validate:"required"`
-	Email        string             `json:"email" bson:"email" validate:"omitempty,email"`
-	Birthday     time.Time          `json:"birthday" bson:"birthday" validate:"required"`
-	Relationship string             `json:"relationship" bson:"relationship" validate:"omitempty,max=60" `
-	LookingFor   string             `json:"looking_for" bson:"looking_for" validate:"omitempty,max=60"`
-	Password     string             `json:"password" bson:"password"`
-	Media        []string           `json:"media" bson:"media"` // arr path media
-	Gender       string             `json:"gender" bson:"gender" validate:"required,max=60"`
-	Sex          string             `json:"sex" bson:"sex" validate:"omitempty,max=60"`
-	Country      string             `json:"country" bson:"country" validate:"required,max=60"`
-	Hobby        []string           `json:"hobby" bson:"hobby"`
-	Disable      bool               `json:"disable" bson:"disable"`
-	About        string             `json:"about" bson:"about" validate:"omitempty,max=256"`
-	CreateAt     time.Time          `json:"created_at" bson:"created_at"`
-	UpdateAt     time.Time          `json:"updated_at" bson:"updated_at"`
+	ID           primitive.ObjectID   `json:"_id" bson:"_id,omitempty" validate:"required"`
+	Name         string               `json:"name" bson:"name" validate:"required"`
+	Email        string               `json:"email" bson:"email" validate:"omitempty,email"`
+	Birthday     time.Time            `json:"birthday" bson:"birthday" validate:"required"`
+	Relationship string               `json:"relationship" bson:"relationship" validate:"omitempty,max=60" `
+	LookingFor   string               `json:"looking_for" bson:"looking_for" validate:"omitempty,max=60"`
+	Password     string               `json:"password" bson:"password"`
+	Media        []string             `json:"media" bson:"media"` // arr path media
+	Gender       string               `json:"gender" bson:"gender" validate:"required,max=60"`
+	Sex          string               `json:"sex" bson:"sex" validate:"omitempty,max=60"`
+	Country      string               `json:"country" bson:"country" validate:"required,max=60"`
+	Hobby        []string             `json:"hobby" bson:"hobby"`
+	Interests    []string             `json:"interests" bson:"interests" validate:"omitempty,unique"` // ids of the interests catalogue
+	Status       string               `json:"status" bson:"status"`
+	StatusUntil  *time.Time           `json:"status_until,omitempty" bson:"status_until,omitempty"`
+	Moderation   *Moderation          `json:"moderation,omitempty" bson:"moderation,omitempty"`
+	Role         string               `json:"role" bson:"role" validate:"omitempty,oneof=user moderator admin"`
+	About        string               `json:"about" bson:"about" validate:"omitempty,max=256"`
+	Timezone     string               `json:"timezone" bson:"timezone" validate:"omitempty,timezone"`
+	Locale       string               `json:"locale" bson:"locale,omitempty" validate:"omitempty,oneof=en vi"` // language of the errors and notifications
+	Location     *Location            `json:"location,omitempty" bson:"location,omitempty"`
+	Preferences  *Preferences         `json:"preferences,omitempty" bson:"preferences,omitempty"`
+	LastActiveAt time.Time            `json:"last_active_at" bson:"last_active_at"`
+	DeleteAt     *time.Time           `json:"delete_at,omitempty" bson:"delete_at,omitempty"`
+	Identities   []Identity           `json:"-" bson:"identities,omitempty"`
+	TwoFactor    *TwoFactor           `json:"-" bson:"two_factor,omitempty"`
+	Phone        string               `json:"phone,omitempty" bson:"phone,omitempty"`
+	BlockedUsers []primitive.ObjectID `json:"-" bson:"blocked_users,omitempty"` // users blocked by this user
+	BlockedBy    []primitive.ObjectID `json:"-" bson:"blocked_by,omitempty"`    // users who blocked this user
+	CreateAt     time.Time            `json:"created_at" bson:"created_at"`
+	UpdateAt     time.Time            `json:"updated_at" bson:"updated_at"`
+	Profile      `bson:",inline"`
+}
+
+// AccountStatus returns the status of the account of the user
+func (u User) AccountStatus() AccountStatus {
+	return AccountStatus{Status: u.Status, Until: u.StatusUntil}
 }
 
+// UserResGetInfo is the public profile of a user, it never carries the email
 type UserResGetInfo struct {
-	ID           primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
-	Name         string             `json:"name" bson:"name" validate:"omitempty,max=60"`
-	Email        string             `json:"email" bson:"email"`
-	Birthday     time.Time          `json:"birthday" bson:"birthday"`
-	Relationship string             `json:"relationship" bson:"relationship" validate:"omitempty,max=60"`
-	LookingFor   string             `json:"looking_for" bson:"looking_for" validate:"omitempty,max=60"`
-	Media        []string           `json:"media" bson:"media"` // arr path media
-	Gender       string             `json:"gender" bson:"gender" validate:"omitempty,max=60"`
-	Sex          string             `json:"sex" bson:"sex" validate:"omitempty,max=60"`
-	Country      string             `json:"country" bson:"country" validate:"omitempty,max=60"`
-	Hobby        []string           `json:"hobby" bson:"hobby"`
-	About        string             `json:"about" bson:"about" validate:"omitempty,max=256"`
-	CreateAt     time.Time          `json:"created_at" bson:"created_at"`
-	UpdateAt     time.Time          `json:"updated_at" bson:"updated_at"`
+	ID              primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Name            string             `json:"name" bson:"name" validate:"omitempty,max=60"`
+	Birthday        time.Time          `json:"birthday" bson:"birthday"`
+	Relationship    string             `json:"relationship" bson:"relationship" validate:"omitempty,max=60"`
+	LookingFor      string             `json:"looking_for" bson:"looking_for" validate:"omitempty,max=60"`
+	Media           []string           `json:"media" bson:"media"` // arr path media
+	Gender          string             `json:"gender" bson:"gender" validate:"omitempty,max=60"`
+	Sex             string             `json:"sex" bson:"sex" validate:"omitempty,max=60"`
+	Country         string             `json:"country" bson:"country" validate:"omitempty,max=60"`
+	Hobby           []string           `json:"hobby" bson:"hobby"`
+	Interests       []string           `json:"interests" bson:"interests"`
+	About           string             `json:"about" bson:"about" validate:"omitempty,max=256"`
+	SuperLiked      bool               `json:"super_liked" bson:"super_liked"`           // user super liked the caller
+	SharedInterests int                `json:"shared_interests" bson:"shared_interests"` // interests in common with the caller
+	PhoneVerified   bool               `json:"phone_verified" bson:"phone_verified"`
+	Verified        bool               `json:"verified" bson:"verified"` // photo verified by a moderator
+	CreateAt        time.Time          `json:"created_at" bson:"created_at"`
+	UpdateAt        time.Time          `json:"updated_at" bson:"updated_at"`
+	Profile         `bson:",inline"`
+}
+
+// Age returns the age in years of the user at now
+func (u UserResGetInfo) Age(now time.Time) int {
+	years := now.Year() - u.Birthday.Year()
+	if now.Month() < u.Birthday.Month() || (now.Month() == u.Birthday.Month() && now.Day() < u.Birthday.Day()) {
+		years--
+	}
+	return years
+}
+
+// UserProfile is the full profile of the logged in user, the public fields plus the private ones
+type UserProfile struct {
+	UserResGetInfo `bson:",inline"`
+	Email          string       `json:"email" bson:"email"`
+	Timezone       string       `json:"timezone" bson:"timezone"`
+	Locale         string       `json:"locale,omitempty" bson:"locale,omitempty"`
+	Role           string       `json:"role" bson:"role"`
+	Location       *Location    `json:"location,omitempty" bson:"location,omitempty"`
+	Preferences    *Preferences `json:"preferences,omitempty" bson:"preferences,omitempty"`
+	Status         string       `json:"status" bson:"status"`
+	DeleteAt       *time.Time   `json:"delete_at,omitempty" bson:"delete_at,omitempty"`
+	Phone          string       `json:"phone,omitempty" bson:"phone,omitempty"`
+}
+
+// UserPatch is a partial update of the logged in user, only the fields that were sent are applied
+type UserPatch struct {
+	Name         *string    `json:"name" bson:"name,omitempty" validate:"omitempty,min=1,max=60"`
+	Birthday     *time.Time `json:"birthday" bson:"birthday,omitempty"`
+	Relationship *string    `json:"relationship" bson:"relationship,omitempty" validate:"omitempty,max=60"`
+	LookingFor   *string    `json:"looking_for" bson:"looking_for,omitempty" validate:"omitempty,max=60"`
+	Media        []string   `json:"media" bson:"media,omitempty" validate:"omitempty,max=9,unique"`
+	Gender       *string    `json:"gender" bson:"gender,omitempty" validate:"omitempty,min=1,max=60"`
+	Sex          *string    `json:"sex" bson:"sex,omitempty" validate:"omitempty,max=60"`
+	Country      *string    `json:"country" bson:"country,omitempty" validate:"omitempty,min=1,max=60"`
+	Hobby        []string   `json:"hobby" bson:"hobby,omitempty"`
+	Interests    []string   `json:"interests" bson:"interests,omitempty" validate:"omitempty,unique"`
+	About        *string    `json:"about" bson:"about,omitempty" validate:"omitempty,max=256"`
+	Timezone     *string    `json:"timezone" bson:"timezone,omitempty" validate:"omitempty,timezone"`
+	Locale       *string    `json:"locale" bson:"locale,omitempty" validate:"omitempty,oneof=en vi"`
+	Location     *Location  `json:"location" bson:"location,omitempty"`
+	Height       *int       `json:"height" bson:"height,omitempty" validate:"omitempty,min=100,max=250"`
+	Education    *string    `json:"education" bson:"education,omitempty" validate:"omitempty,oneof=high_school vocational college bachelor master doctorate other"`
+	Job          *string    `json:"job" bson:"job,omitempty" validate:"omitempty,oneof=student engineering healthcare education finance business arts media hospitality legal science government trades self_employed unemployed other"`
+	Languages    []string   `json:"languages" bson:"languages,omitempty" validate:"omitempty,max=5,unique,dive,oneof=en vi fr de es it pt ru ja ko zh th id hi ar"`
+	Prompts      []Prompt   `json:"prompts" bson:"prompts,omitempty" validate:"omitempty,max=3,unique=Question,dive"`
+}
+
+// Values returns the fields of the patch that were set, keyed by their stored name
+func (p UserPatch) Values() (map[string]interface{}, error) {
+	raw, err := bson.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	err = bson.Unmarshal(raw, &values)
+	return values, err
 }
 
 type UserSignUp struct {
@@ -51,16 +142,24 @@ type UserFieldInToken struct {
 	ID    primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
 	Name  string             `json:"name"`
 	Email string             `json:"email"`
+	Role  string             `json:"role"`
+	MFA   bool               `json:"mfa"`
 }
 type UserResponseSignUp struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Token string `json:"token"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Token  string `json:"token,omitempty"`
+	Status string `json:"status"`
+	// TwoFactorToken is set instead of Token when the login waits for its second factor
+	TwoFactorToken string `json:"two_factor_token,omitempty"`
+	// TwoFactorSetup tells the role of the user requires two-factor authentication to be enabled
+	TwoFactorSetup bool `json:"two_factor_setup,omitempty"`
 }
 
 type GetListUsersResponse struct {
 	Pagination
-	ListUsers []*UserResGetInfo `json:"listUsers"`
+	NextCursor string            `json:"nextCursor,omitempty"` // empty on the last page
+	ListUsers  []*UserResGetInfo `json:"listUsers"`
 }
 type UserLogin struct {
 	Email    string `json:"email" bson:"email" validate:"required,email"`
@@ -68,5 +167,6 @@ type UserLogin struct {
 }
 
 type DisableBody struct {
-	Disable *bool `json:"disable" bson:"disable" validate:"required"`
+	Disable *bool  `json:"disable" bson:"disable" validate:"required"`
+	Reason  string `json:"reason" bson:"reason" validate:"omitempty,max=500"`
 }
@@ -0,0 +1,55 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	VerificationPending  = "pending"
+	VerificationApproved = "approved"
+	VerificationRejected = "rejected"
+)
+
+// VerificationPrompt is the pose a user must take in the selfie, one per user
+type VerificationPrompt struct {
+	UserID    primitive.ObjectID `json:"-" bson:"_id"`
+	Pose      string             `json:"pose" bson:"pose"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+}
+
+// VerificationRequest is a selfie waiting for or reviewed by a moderator, a user has at most
+// one pending
+type VerificationRequest struct {
+	ID         primitive.ObjectID  `json:"_id" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	Pose       string              `json:"pose" bson:"pose"`
+	Selfie     string              `json:"selfie" bson:"selfie"` // path of the media
+	Status     string              `json:"status" bson:"status"`
+	Reason     string              `json:"reason,omitempty" bson:"reason,omitempty"` // told to the user when rejected
+	ReviewerID *primitive.ObjectID `json:"reviewer_id,omitempty" bson:"reviewer_id,omitempty"`
+	ReviewAt   *time.Time          `json:"reviewed_at,omitempty" bson:"reviewed_at,omitempty"`
+	CreateAt   time.Time           `json:"created_at" bson:"created_at"`
+}
+
+type VerificationPromptResponse struct {
+	Pose      string    `json:"pose"`
+	ExpiresAt time.Time `json:"expires_at"` // the selfie must be sent before
+}
+
+// VerificationSubmit sends the selfie taken in the pose of the prompt
+type VerificationSubmit struct {
+	Selfie string `json:"selfie" validate:"required,max=2048"`
+}
+
+// VerificationStatus tells if a user is verified, with the last request
+type VerificationStatus struct {
+	Verified bool                 `json:"verified"`
+	Request  *VerificationRequest `json:"request,omitempty"`
+}
+
+type VerificationReviewRequest struct {
+	Status string `json:"status" validate:"required,oneof=approved rejected"`
+	Reason string `json:"reason" validate:"required_if=Status rejected,max=500"`
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"context"
 	"dating/internal/pkg/glog"
+	"dating/internal/pkg/ratelimit"
 	"fmt"
 	"log"
 	"strings"
@@ -54,7 +55,7 @@ func New(path, state string) (*Configs, error) {
 	vn.OnConfigChange(func(e fsnotify.Event) {
 		log.Printf("config file changed: %v", e.Name)
 		if err := conf.binding(vn); err != nil {
-			log.Printf("binding error:", err)
+			log.Printf("binding error: %v", err)
 		}
 		log.Printf("config: %+v", conf)
 	})
@@ -63,7 +64,7 @@ func New(path, state string) (*Configs, error) {
 
 func (c *Configs) binding(v *viper.Viper) error {
 	if err := v.Unmarshal(&c); err != nil {
-		log.Printf("failed to unmarshal config: ", err)
+		log.Printf("failed to unmarshal config: %v", err)
 		return err
 	}
 	return nil
@@ -80,6 +81,185 @@ type (
 		Jwt struct {
 			Duration time.Duration `mapstructure:"duration"`
 		} `mapstructure:"jwt"`
+		Match          Match          `mapstructure:"match"`
+		Notification   Notification   `mapstructure:"notification"`
+		ChatFilter     ChatFilter     `mapstructure:"chat_filter"`
+		RateLimit      RateLimit      `mapstructure:"rate_limit"`
+		Media          Media          `mapstructure:"media"`
+		Interests      Interests      `mapstructure:"interests"`
+		Recommendation Recommendation `mapstructure:"recommendation"`
+		Discovery      Discovery      `mapstructure:"discovery"`
+		Account        Account        `mapstructure:"account"`
+		OIDC           OIDC           `mapstructure:"oidc"`
+		TwoFactor      TwoFactor      `mapstructure:"two_factor"`
+		Phone          Phone          `mapstructure:"phone"`
+		Verification   Verification   `mapstructure:"verification"`
+	}
+
+	// Verification hold configuration of the photo verification, a random pose of Poses is
+	// given to the user who must send a selfie in it before PromptTTL
+	Verification struct {
+		Poses     []string      `mapstructure:"poses"`
+		PromptTTL time.Duration `mapstructure:"prompt_ttl"`
+	}
+
+	// Phone hold configuration of the phone verification, Provider is the SMS provider: log
+	// writes the codes to the log and file appends them to File, for local runs
+	Phone struct {
+		Provider       string        `mapstructure:"provider"`
+		File           string        `mapstructure:"file"`
+		CodeLength     int           `mapstructure:"code_length"`
+		CodeTTL        time.Duration `mapstructure:"code_ttl"`
+		MaxAttempts    int           `mapstructure:"max_attempts"`
+		ResendInterval time.Duration `mapstructure:"resend_interval"`
+	}
+
+	// TwoFactor hold configuration of the TOTP second factor, logins of the EnforcedRoles
+	// without it can't use the admin api, Skew is the number of steps a code stays valid
+	// around the current one
+	TwoFactor struct {
+		Issuer        string        `mapstructure:"issuer"`
+		Skew          int           `mapstructure:"skew"`
+		TokenTTL      time.Duration `mapstructure:"token_ttl"`
+		RecoveryCodes int           `mapstructure:"recovery_codes"`
+		EnforcedRoles []string      `mapstructure:"enforced_roles"`
+	}
+
+	// OIDC hold configuration of the OpenID Connect providers users login with, keyed by the
+	// name in the login path, a provider without a client id is off
+	OIDC struct {
+		StateTTL  time.Duration           `mapstructure:"state_ttl"`
+		Timeout   time.Duration           `mapstructure:"timeout"`
+		Providers map[string]OIDCProvider `mapstructure:"providers"`
+	}
+
+	// OIDCProvider hold the registration of the api at a provider, RedirectURL is the
+	// callback route of the provider, ResponseMode is form_post when it posts the callback
+	OIDCProvider struct {
+		Issuer       string   `mapstructure:"issuer"`
+		ClientID     string   `mapstructure:"client_id"`
+		ClientSecret string   `mapstructure:"client_secret"`
+		RedirectURL  string   `mapstructure:"redirect_url"`
+		Scopes       []string `mapstructure:"scopes"`
+		ResponseMode string   `mapstructure:"response_mode"`
+	}
+
+	// Account hold configuration of data exports, account deletion and statuses, a deleted
+	// account is erased once DeletionGrace has passed
+	Account struct {
+		ExportTTL       time.Duration `mapstructure:"export_ttl"`
+		MediaTimeout    time.Duration `mapstructure:"media_timeout"`
+		MediaMaxBytes   int64         `mapstructure:"media_max_bytes"`
+		DeletionGrace   time.Duration `mapstructure:"deletion_grace"`
+		ErasureInterval time.Duration `mapstructure:"erasure_interval"`
+		StatusCacheTTL  time.Duration `mapstructure:"status_cache_ttl"`
+	}
+
+	// Media hold configuration of the media of the profiles
+	Media struct {
+		Hosts []string `mapstructure:"hosts"`
+	}
+
+	// Discovery hold configuration of the user listing
+	Discovery struct {
+		CountCacheTTL time.Duration `mapstructure:"count_cache_ttl"`
+	}
+
+	// Recommendation hold configuration of the ranked feed, candidates are the
+	// most recently active users passing the filters, ranked by the weighted score
+	Recommendation struct {
+		CandidatePool   int                   `mapstructure:"candidate_pool"`
+		MaxDistanceKm   float64               `mapstructure:"max_distance_km"`
+		RecencyHalfLife time.Duration         `mapstructure:"recency_half_life"`
+		Debug           bool                  `mapstructure:"debug"`
+		Weights         RecommendationWeights `mapstructure:"weights"`
+	}
+
+	// RecommendationWeights hold the weight of each score component
+	RecommendationWeights struct {
+		Preference   float64 `mapstructure:"preference"`
+		Interests    float64 `mapstructure:"interests"`
+		Distance     float64 `mapstructure:"distance"`
+		Recency      float64 `mapstructure:"recency"`
+		Completeness float64 `mapstructure:"completeness"`
+	}
+
+	// Interests hold the interests catalogue users pick from, labels are keyed by language
+	Interests struct {
+		MaxPerUser    int        `mapstructure:"max_per_user"`
+		DefaultLocale string     `mapstructure:"default_locale"`
+		Catalogue     []Interest `mapstructure:"catalogue"`
+	}
+
+	// Interest is an entry of the interests catalogue
+	Interest struct {
+		ID       string            `mapstructure:"id"`
+		Category string            `mapstructure:"category"`
+		Labels   map[string]string `mapstructure:"labels"`
+	}
+
+	// RateLimit hold rate limit configuration, backend is memory or redis,
+	// routes holds the rate of each limited route by name
+	RateLimit struct {
+		Backend    string                    `mapstructure:"backend"`
+		TrustProxy bool                      `mapstructure:"trust_proxy"`
+		Redis      Redis                     `mapstructure:"redis"`
+		Routes     map[string]ratelimit.Rate `mapstructure:"routes"`
+	}
+
+	// Redis hold Redis configuration information
+	Redis struct {
+		Address  string `mapstructure:"address"`
+		Password string `mapstructure:"password"`
+		DB       int    `mapstructure:"db"`
+	}
+
+	// ChatFilter hold configuration of the filters run on chat messages,
+	// actions are one of allow, mask, flag, reject
+	ChatFilter struct {
+		BannedWords       []string `mapstructure:"banned_words"`
+		BannedWordsAction string   `mapstructure:"banned_words_action"`
+		ContactInfo       struct {
+			EarlyMessages int64  `mapstructure:"early_messages"`
+			Action        string `mapstructure:"action"`
+		} `mapstructure:"contact_info"`
+	}
+
+	// Notification hold delivery channels configuration, the socket channel is always on
+	Notification struct {
+		Webhook struct {
+			URL     string        `mapstructure:"url"`
+			Timeout time.Duration `mapstructure:"timeout"`
+		} `mapstructure:"webhook"`
+		Push struct {
+			Enabled bool `mapstructure:"enabled"`
+		} `mapstructure:"push"`
+	}
+
+	// Match hold matching configuration information
+	Match struct {
+		SuperLikeDailyQuota int         `mapstructure:"super_like_daily_quota"`
+		Expiry              MatchExpiry `mapstructure:"expiry"`
+		Unmatch             Unmatch     `mapstructure:"unmatch"`
+	}
+
+	// Unmatch hold what happens to the messages of a room when its match ends, messages is
+	// archive to keep them in the archive for Retention, purge to delete them or keep
+	Unmatch struct {
+		Messages  string        `mapstructure:"messages"`
+		Retention time.Duration `mapstructure:"retention"`
+	}
+
+	// MatchExpiry hold configuration of the matches without any message, a reminder
+	// is sent after ReminderAfter and the match expires after ExpireAfter, action is
+	// expire to remove the match or stale to keep it marked as stale
+	MatchExpiry struct {
+		Enabled       bool          `mapstructure:"enabled"`
+		Interval      time.Duration `mapstructure:"interval"`
+		ReminderAfter time.Duration `mapstructure:"reminder_after"`
+		ExpireAfter   time.Duration `mapstructure:"expire_after"`
+		Extension     time.Duration `mapstructure:"extension"`
+		Action        string        `mapstructure:"action"`
 	}
 
 	// Config hold MongoDB configuration information
@@ -101,6 +281,16 @@ type (
 	}
 )
 
+// Enforced tells if logins with role need a second factor to use the admin api
+func (t TwoFactor) Enforced(role string) bool {
+	for _, enforced := range t.EnforcedRoles {
+		if role == enforced {
+			return true
+		}
+	}
+	return false
+}
+
 // Dial dial to target server with Monotonic mode
 func Dial(conf *MongoDB, logger glog.Logger) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
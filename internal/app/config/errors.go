@@ -1,13 +1,11 @@
 package config
 
 import (
+	"dating/internal/pkg/i18n"
 	"dating/internal/pkg/utils"
 	"fmt"
 	"log"
 	"reflect"
-
-	"github.com/fsnotify/fsnotify"
-	"github.com/spf13/viper"
 )
 
 type ErrorCode struct {
@@ -16,7 +14,7 @@ type ErrorCode struct {
 }
 
 type ErrorMessage struct {
-	vn         *viper.Viper
+	catalog    *i18n.Catalog
 	ConfigPath string
 	Success    ErrorCode
 	Database   struct {
@@ -29,27 +27,41 @@ type ErrorMessage struct {
 		EmailExists            ErrorCode
 		FailedAuthentication   ErrorCode
 		ValidationFailed       ErrorCode
+		QuotaExceeded          ErrorCode
+		PermissionDenied       ErrorCode
+		AccountSuspended       ErrorCode
+		AccountBanned          ErrorCode
+		AccountStatusConflict  ErrorCode
+		InvalidLogin           ErrorCode
+		IdentityConflict       ErrorCode
+		LastLogin              ErrorCode
+		InvalidTwoFactorCode   ErrorCode
+		TwoFactorRequired      ErrorCode
+		TwoFactorConflict      ErrorCode
+		PhoneExists            ErrorCode
+		InvalidPhoneCode       ErrorCode
+		VerificationConflict   ErrorCode
+		VerificationExpired    ErrorCode
+		TooManyRequests        ErrorCode
+		PreconditionFailed     ErrorCode
+		AlreadyExtended        ErrorCode
+		ExportNotReady         ErrorCode
 	}
 }
 
-// Initialization error message
+// Initialization error message, one catalog per locale: errors.en.yml, errors.vi.yml,...
 func (em *ErrorMessage) Init() error {
 	log.Println("initialzing error messages")
-	vn := viper.New()
-	vn.AddConfigPath(em.ConfigPath)
-	vn.SetConfigName("errors")
-
-	if err := vn.ReadInConfig(); err != nil {
+	catalog, err := i18n.Load(em.ConfigPath, "errors", i18n.DefaultLocale)
+	if err != nil {
 		return err
 	}
-	em.vn = vn
+	em.catalog = catalog
 
 	em.mapping("", reflect.ValueOf(em).Elem())
 
-	vn.WatchConfig()
-	vn.OnConfigChange(func(e fsnotify.Event) {
-		log.Println("error messages change: %s", e.Name)
-		em.vn = vn
+	catalog.OnChange(func(file string) {
+		log.Printf("error messages change: %s", file)
 		em.mapping("", reflect.ValueOf(em).Elem())
 	})
 
@@ -80,9 +92,18 @@ func (em ErrorMessage) mapping(name string, v reflect.Value) {
 
 // ErrorCode method helps to get the value of error
 func (em ErrorMessage) ErrorCode(name string) ErrorCode {
+	return em.LocalizedErrorCode(name)
+}
+
+// LocalizedErrorCode method helps to get the value of error with its message in the first of
+// locales there is a translation in, the code is the same in every locale
+func (em ErrorMessage) LocalizedErrorCode(name string, locales ...string) ErrorCode {
+	if em.catalog == nil {
+		return ErrorCode{}
+	}
 	rtn := ErrorCode{
-		Code:    em.vn.GetString(fmt.Sprintf("error.%s.code", name)),
-		Message: em.vn.GetString(fmt.Sprintf("error.%s.message", name)),
+		Code:    em.catalog.Get(fmt.Sprintf("error.%s.code", name)),
+		Message: em.catalog.Get(fmt.Sprintf("error.%s.message", name), locales...),
 	}
 	return rtn
 }
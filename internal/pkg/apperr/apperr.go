@@ -0,0 +1,162 @@
+// Package apperr holds the typed errors of the domain, each one knows its HTTP status and the
+// key of its code in configs/errors.en.yml, handlers respond them with respond.Fail
+package apperr
+
+import (
+	"encoding/hex"
+	"net/http"
+
+	"dating/internal/pkg/utils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Kind is the class of an error, it decides the HTTP status
+type Kind int
+
+const (
+	Internal Kind = iota
+	Validation
+	Unauthorized
+	Forbidden
+	NotFound
+	Conflict
+	PreconditionFailed
+	TooManyRequests
+	BadGateway
+)
+
+// Keys of the generic codes in configs/errors.en.yml, used when an error has no key of its own
+const (
+	KeyInternal           = "invalid_value.request"
+	KeyValidation         = "invalid_value.validation_failed"
+	KeyUnauthorized       = "invalid_value.failed_authentication"
+	KeyForbidden          = "invalid_value.permission_denied"
+	KeyNotFound           = "database.data_not_found"
+	KeyPreconditionFailed = "invalid_value.precondition_failed"
+	KeyTooManyRequests    = "invalid_value.too_many_requests"
+)
+
+var (
+	statuses = map[Kind]int{
+		Internal:           http.StatusInternalServerError,
+		Validation:         http.StatusBadRequest,
+		Unauthorized:       http.StatusUnauthorized,
+		Forbidden:          http.StatusForbidden,
+		NotFound:           http.StatusNotFound,
+		Conflict:           http.StatusConflict,
+		PreconditionFailed: http.StatusPreconditionFailed,
+		TooManyRequests:    http.StatusTooManyRequests,
+		BadGateway:         http.StatusBadGateway,
+	}
+	keys = map[Kind]string{
+		Internal:           KeyInternal,
+		Validation:         KeyValidation,
+		Unauthorized:       KeyUnauthorized,
+		Forbidden:          KeyForbidden,
+		NotFound:           KeyNotFound,
+		PreconditionFailed: KeyPreconditionFailed,
+		TooManyRequests:    KeyTooManyRequests,
+	}
+)
+
+// Status returns the HTTP status of the kind
+func (k Kind) Status() int {
+	if status, ok := statuses[k]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// FieldError tells why a field of a request is invalid
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+}
+
+// Error is an error of the domain, Key is the path of its code in configs/errors.en.yml, e.g.
+// invalid_value.phone_exists
+type Error struct {
+	Kind   Kind
+	Key    string
+	Msg    string
+	Fields []FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+// Status returns the HTTP status of the error
+func (e *Error) Status() int {
+	return e.Kind.Status()
+}
+
+// New returns an error of kind, an empty key falls back to the generic code of the kind
+func New(kind Kind, key, msg string) *Error {
+	if key == "" {
+		key = keys[kind]
+	}
+	if key == "" {
+		key = KeyInternal
+	}
+	return &Error{Kind: kind, Key: key, Msg: msg}
+}
+
+// NewNotFound returns a not found error with the generic code
+func NewNotFound(msg string) *Error {
+	return New(NotFound, "", msg)
+}
+
+// NewConflict returns a conflict error with the code at key
+func NewConflict(key, msg string) *Error {
+	return New(Conflict, key, msg)
+}
+
+// NewForbidden returns a forbidden error with the code at key
+func NewForbidden(key, msg string) *Error {
+	return New(Forbidden, key, msg)
+}
+
+// NewValidation returns a validation error with the code at key
+func NewValidation(key, msg string) *Error {
+	return New(Validation, key, msg)
+}
+
+// FromValidator returns a validation error with the fields the validator rejected, the names
+// of the fields are snake cased like the JSON of the requests
+func FromValidator(err error) *Error {
+	e := New(Validation, "", err.Error())
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		for _, fe := range invalid {
+			e.Fields = append(e.Fields, FieldError{
+				Field: utils.Underscore(fe.Field()),
+				Rule:  fe.Tag(),
+				Param: fe.Param(),
+			})
+		}
+	}
+	return e
+}
+
+// From returns the typed error behind err, errors wrapped with errors.Wrap are unwrapped.
+// A missing document is a not found error and an invalid id a validation error, any other
+// error is internal
+func From(err error) *Error {
+	cause := errors.Cause(err)
+	if e, ok := cause.(*Error); ok {
+		return e
+	}
+	if cause == mongo.ErrNoDocuments {
+		return NewNotFound(cause.Error())
+	}
+	if _, ok := cause.(hex.InvalidByteError); ok || cause == primitive.ErrInvalidHex {
+		return NewValidation("", cause.Error())
+	}
+	return New(Internal, "", err.Error())
+}
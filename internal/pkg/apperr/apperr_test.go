@@ -0,0 +1,54 @@
+package apperr
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestFrom(t *testing.T) {
+	_, invalidID := primitive.ObjectIDFromHex("not-an-id")
+	conflict := NewConflict("invalid_value.phone_exists", "phone already used")
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		key    string
+	}{
+		{"typed", conflict, http.StatusConflict, "invalid_value.phone_exists"},
+		{"wrapped", errors.Wrap(conflict, "verify phone"), http.StatusConflict, "invalid_value.phone_exists"},
+		{"no documents", errors.Wrap(mongo.ErrNoDocuments, "find user"), http.StatusNotFound, KeyNotFound},
+		{"invalid id", invalidID, http.StatusBadRequest, KeyValidation},
+		{"internal", errors.New("connection reset"), http.StatusInternalServerError, KeyInternal},
+	}
+	for _, test := range tests {
+		e := From(test.err)
+		if e.Status() != test.status || e.Key != test.key {
+			t.Errorf("From(%s) = %d %s; expected %d %s", test.name, e.Status(), e.Key, test.status, test.key)
+		}
+	}
+}
+
+func TestFromValidator(t *testing.T) {
+	req := struct {
+		DisplayName string `validate:"required"`
+		Bio         string `validate:"max=3"`
+	}{Bio: "too long"}
+	e := FromValidator(validator.New().Struct(req))
+	if e.Status() != http.StatusBadRequest {
+		t.Fatalf("status = %d; expected %d", e.Status(), http.StatusBadRequest)
+	}
+	expected := []FieldError{{Field: "display_name", Rule: "required"}, {Field: "bio", Rule: "max", Param: "3"}}
+	if len(e.Fields) != len(expected) {
+		t.Fatalf("fields = %v; expected %v", e.Fields, expected)
+	}
+	for i := range expected {
+		if e.Fields[i] != expected[i] {
+			t.Errorf("fields[%d] = %v; expected %v", i, e.Fields[i], expected[i])
+		}
+	}
+}
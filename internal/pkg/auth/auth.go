@@ -1,11 +1,19 @@
 package auth
 
 import (
+	"context"
+	"dating/internal/app/api/types"
 	"dating/internal/pkg/jwt"
 	"net/http"
 	"strings"
 )
 
+// claimsKey is the context key of the claims of an authorized request
+const claimsKey = "claims"
+
+// roleKey is the context key of the current role of the authorized user
+const roleKey = "role"
+
 // get token from Header
 func ExtractToken(r *http.Request) string {
 	tokenHeader := r.Header.Get("Authorization")
@@ -26,3 +34,46 @@ func IsAuthorized(tokenpath string) (map[string]interface{}, error) {
 	claimMap, err := jwt.IsAuthorized(tokenpath)
 	return claimMap, err
 }
+
+// NewContext returns a copy of ctx carrying the claims of the authorized user
+func NewContext(ctx context.Context, claims map[string]interface{}) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// ClaimsFromContext get claims of the authorized user, nil if the request is not authorized
+func ClaimsFromContext(ctx context.Context) map[string]interface{} {
+	claims, _ := ctx.Value(claimsKey).(map[string]interface{})
+	return claims
+}
+
+// UserIDFromContext get id (hex) of the authorized user, empty if the request is not authorized
+func UserIDFromContext(ctx context.Context) string {
+	return UserID(ClaimsFromContext(ctx))
+}
+
+// UserID get id (hex) of the user from claims
+func UserID(claims map[string]interface{}) string {
+	id, _ := claims["_id"].(string)
+	return id
+}
+
+// NewRoleContext returns a copy of ctx carrying the role the authorized user has now, which
+// may differ from the role claimed by its token
+func NewRoleContext(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleKey, role)
+}
+
+// RoleFromContext get the current role of the authorized user, users saved before roles existed are users
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey).(string)
+	if role == "" {
+		return types.RoleUser
+	}
+	return role
+}
+
+// MFAFromContext tells if the login of the authorized user was confirmed with a second factor
+func MFAFromContext(ctx context.Context) bool {
+	mfa, _ := ClaimsFromContext(ctx)["mfa"].(bool)
+	return mfa
+}
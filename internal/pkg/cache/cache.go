@@ -0,0 +1,62 @@
+// Package cache is a small in memory cache whose entries expire after a ttl
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// maxEntries bounds the cache, expired entries are swept when it is reached
+const maxEntries = 10000
+
+type entry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// TTL is an in memory cache, safe for concurrent use
+type TTL struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]entry
+}
+
+// New returns a cache whose entries expire after ttl
+func New(ttl time.Duration) *TTL {
+	return &TTL{
+		ttl:     ttl,
+		entries: map[string]entry{},
+	}
+}
+
+// Get returns the value of key, false when missing or expired
+func (c *TTL) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Set stores value for key until the ttl elapses
+func (c *TTL) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= maxEntries {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		// still full of live entries, start over rather than grow
+		if len(c.entries) >= maxEntries {
+			c.entries = map[string]entry{}
+		}
+	}
+	c.entries[key] = entry{value: value, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,219 @@
+package chatfilter
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"dating/internal/app/api/types"
+	"dating/internal/pkg/ratelimit"
+
+	"github.com/pkg/errors"
+)
+
+// Verdict is the decision of a filter on a message, a higher verdict wins in a chain
+type Verdict int
+
+const (
+	Allow Verdict = iota
+	Mask
+	Flag
+	Reject
+)
+
+// ParseVerdict parses a verdict from config, unknown values reject
+func ParseVerdict(s string) Verdict {
+	switch strings.ToLower(s) {
+	case "allow":
+		return Allow
+	case "mask":
+		return Mask
+	case "flag":
+		return Flag
+	}
+	return Reject
+}
+
+// Result is the outcome of a filter, Content is the masked content when Verdict is Mask
+type Result struct {
+	Verdict Verdict
+	Content string
+	Reason  string
+}
+
+// Filter is an interface of a message filter
+type Filter interface {
+	Filter(ctx context.Context, message *types.Message) (Result, error)
+}
+
+// Flagger is an interface to send a message to the moderator review queue
+type Flagger interface {
+	Flag(ctx context.Context, message types.Message, reason string) error
+}
+
+// Chain runs filters in order, masks are applied for the next filters,
+// it stops at the first reject
+type Chain struct {
+	filters []Filter
+	flagger Flagger
+}
+
+// NewChain returns a new chain of filters
+func NewChain(flagger Flagger, filters ...Filter) *Chain {
+	return &Chain{
+		filters: filters,
+		flagger: flagger,
+	}
+}
+
+// Run runs the chain on message, content of message is masked in place,
+// the returned result is the strongest verdict
+func (c *Chain) Run(ctx context.Context, message *types.Message) (Result, error) {
+	final := Result{Verdict: Allow, Content: message.Content}
+	var reasons []string
+	for _, f := range c.filters {
+		result, err := f.Filter(ctx, message)
+		if err != nil {
+			return final, err
+		}
+		switch result.Verdict {
+		case Mask:
+			message.Content = result.Content
+		case Flag:
+			reasons = append(reasons, result.Reason)
+		case Reject:
+			return result, nil
+		}
+		if result.Verdict > final.Verdict {
+			final.Verdict = result.Verdict
+			final.Reason = result.Reason
+		}
+	}
+	final.Content = message.Content
+
+	if len(reasons) > 0 && c.flagger != nil {
+		if err := c.flagger.Flag(ctx, *message, strings.Join(reasons, ", ")); err != nil {
+			return final, errors.Wrap(err, "failed to flag message")
+		}
+	}
+	return final, nil
+}
+
+// BannedWords finds banned words, as whole words and case insensitive
+type BannedWords struct {
+	pattern *regexp.Regexp
+	verdict Verdict
+}
+
+// NewBannedWords returns a new banned words filter, verdict is applied when a word is found
+func NewBannedWords(words []string, verdict Verdict) *BannedWords {
+	f := &BannedWords{verdict: verdict}
+	var quoted []string
+	for _, w := range words {
+		if w = strings.TrimSpace(w); w != "" {
+			quoted = append(quoted, regexp.QuoteMeta(w))
+		}
+	}
+	if len(quoted) > 0 {
+		f.pattern = regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+	}
+	return f
+}
+
+// Filter message
+func (f *BannedWords) Filter(ctx context.Context, message *types.Message) (Result, error) {
+	if f.pattern == nil || !f.pattern.MatchString(message.Content) {
+		return Result{Verdict: Allow}, nil
+	}
+	return Result{
+		Verdict: f.verdict,
+		Content: f.pattern.ReplaceAllStringFunc(message.Content, maskString),
+		Reason:  "banned word",
+	}, nil
+}
+
+var (
+	linkPattern  = regexp.MustCompile(`(?i)\b((https?://|www\.)\S+|[a-z0-9-]+\.(com|net|org|io|me|co|ly|gg|link|app)\b\S*)`)
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s().-]{7,}\d`)
+)
+
+// MessageCounter is an interface to count the messages of a room
+type MessageCounter interface {
+	CountByRoomID(ctx context.Context, idRoom string) (int64, error)
+}
+
+// ContactInfo finds links and phone numbers while a conversation is young,
+// to keep scammers from moving the victim to another channel
+type ContactInfo struct {
+	counter       MessageCounter
+	earlyMessages int64
+	verdict       Verdict
+}
+
+// NewContactInfo returns a new contact info filter, a conversation is early until
+// its room holds earlyMessages messages
+func NewContactInfo(counter MessageCounter, earlyMessages int64, verdict Verdict) *ContactInfo {
+	return &ContactInfo{
+		counter:       counter,
+		earlyMessages: earlyMessages,
+		verdict:       verdict,
+	}
+}
+
+// Filter message
+func (f *ContactInfo) Filter(ctx context.Context, message *types.Message) (Result, error) {
+	hasLink := linkPattern.MatchString(message.Content)
+	hasPhone := phonePattern.MatchString(message.Content)
+	if !hasLink && !hasPhone {
+		return Result{Verdict: Allow}, nil
+	}
+
+	count, err := f.counter.CountByRoomID(ctx, message.RoomID.Hex())
+	if err != nil {
+		return Result{}, errors.Wrap(err, "failed to count messages of room")
+	}
+	if count >= f.earlyMessages {
+		return Result{Verdict: Allow}, nil
+	}
+
+	content := linkPattern.ReplaceAllStringFunc(message.Content, maskString)
+	content = phonePattern.ReplaceAllStringFunc(content, maskString)
+	return Result{
+		Verdict: f.verdict,
+		Content: content,
+		Reason:  "contact info in early conversation",
+	}, nil
+}
+
+// RateLimit rejects messages of a sender above rate
+type RateLimit struct {
+	store ratelimit.Store
+	rate  ratelimit.Rate
+}
+
+// NewRateLimit returns a new rate limit filter
+func NewRateLimit(store ratelimit.Store, rate ratelimit.Rate) *RateLimit {
+	return &RateLimit{
+		store: store,
+		rate:  rate,
+	}
+}
+
+// Filter message, a zero limit disables the filter
+func (f *RateLimit) Filter(ctx context.Context, message *types.Message) (Result, error) {
+	if f.rate.Limit <= 0 || f.rate.Per <= 0 {
+		return Result{Verdict: Allow}, nil
+	}
+	result, err := f.store.Take(ctx, "send_message:user:"+message.SenderID.Hex(), f.rate)
+	if err != nil {
+		return Result{}, errors.Wrap(err, "failed to take rate limit token")
+	}
+	if !result.Allowed {
+		return Result{Verdict: Reject, Reason: "too many messages"}, nil
+	}
+	return Result{Verdict: Allow}, nil
+}
+
+func maskString(s string) string {
+	return strings.Repeat("*", len([]rune(s)))
+}
@@ -0,0 +1,99 @@
+package chatfilter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/pkg/ratelimit"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type counter int64
+
+func (c counter) CountByRoomID(ctx context.Context, idRoom string) (int64, error) {
+	return int64(c), nil
+}
+
+type flagger struct {
+	reasons []string
+}
+
+func (f *flagger) Flag(ctx context.Context, message types.Message, reason string) error {
+	f.reasons = append(f.reasons, reason)
+	return nil
+}
+
+func TestBannedWordsMask(t *testing.T) {
+	chain := NewChain(nil, NewBannedWords([]string{"darn"}, Mask))
+	message := &types.Message{Content: "Darn it, darnation"}
+	result, err := chain.Run(context.Background(), message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verdict != Mask {
+		t.Errorf("Verdict = %d; expected %d;", result.Verdict, Mask)
+	}
+	if message.Content != "**** it, darnation" {
+		t.Errorf("Content = %q; expected %q;", message.Content, "**** it, darnation")
+	}
+}
+
+func TestContactInfoEarlyConversation(t *testing.T) {
+	message := types.Message{Content: "call me +84 912 345 678 or see www.example.com"}
+
+	f := &flagger{}
+	early := message
+	result, err := NewChain(f, NewContactInfo(counter(3), 20, Reject)).Run(context.Background(), &early)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verdict != Reject {
+		t.Errorf("early Verdict = %d; expected %d;", result.Verdict, Reject)
+	}
+
+	late := message
+	result, err = NewChain(f, NewContactInfo(counter(20), 20, Reject)).Run(context.Background(), &late)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verdict != Allow {
+		t.Errorf("late Verdict = %d; expected %d;", result.Verdict, Allow)
+	}
+}
+
+func TestFlagGoesToReview(t *testing.T) {
+	f := &flagger{}
+	chain := NewChain(f, NewBannedWords([]string{"scam"}, Flag))
+	message := &types.Message{Content: "not a scam"}
+	result, err := chain.Run(context.Background(), message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verdict != Flag || len(f.reasons) != 1 {
+		t.Errorf("Verdict = %d, flagged %d; expected %d, 1;", result.Verdict, len(f.reasons), Flag)
+	}
+	if message.Content != "not a scam" {
+		t.Errorf("flagged content changed to %q", message.Content)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	chain := NewChain(nil, NewRateLimit(ratelimit.NewMemory(), ratelimit.Rate{Limit: 2, Per: time.Minute}))
+	sender := primitive.NewObjectID()
+	for i, expected := range []Verdict{Allow, Allow, Reject} {
+		result, err := chain.Run(context.Background(), &types.Message{SenderID: sender, Content: "hi"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Verdict != expected {
+			t.Errorf("message %d Verdict = %d; expected %d;", i, result.Verdict, expected)
+		}
+	}
+	result, _ := chain.Run(context.Background(), &types.Message{SenderID: primitive.NewObjectID()})
+	if result.Verdict != Allow {
+		t.Errorf("other sender Verdict = %d; expected %d;", result.Verdict, Allow)
+	}
+}
@@ -0,0 +1,184 @@
+// Package i18n holds the localized catalogs of the texts of the API, the error messages and
+// the templates of the notifications and the text messages, one file per locale, e.g.
+// errors.en.yml and errors.vi.yml
+package i18n
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// DefaultLocale is the locale every catalog has, the last one of the fallback chains
+const DefaultLocale = "en"
+
+// Catalog is a set of texts keyed by locale then by key, e.g. error.invalid_value.request.message
+type Catalog struct {
+	defaultLocale string
+	locales       map[string]*viper.Viper
+}
+
+// New returns an empty catalog
+func New(defaultLocale string) *Catalog {
+	return &Catalog{
+		defaultLocale: normalize(defaultLocale),
+		locales:       map[string]*viper.Viper{},
+	}
+}
+
+// Load reads the files name.<locale>.yml of path, the file of the default locale is required.
+// The files are watched, a change applies without restart
+func Load(path, name, defaultLocale string) (*Catalog, error) {
+	files, err := filepath.Glob(filepath.Join(path, name+".*.yml"))
+	if err != nil {
+		return nil, err
+	}
+	c := New(defaultLocale)
+	for _, file := range files {
+		locale := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), name+"."), ".yml")
+		vn := viper.New()
+		vn.SetConfigFile(file)
+		if err := vn.ReadInConfig(); err != nil {
+			return nil, errors.Wrapf(err, "read %s", file)
+		}
+		vn.WatchConfig()
+		c.locales[normalize(locale)] = vn
+	}
+	if _, ok := c.locales[c.defaultLocale]; !ok {
+		return nil, fmt.Errorf("no %s.%s.yml in %s", name, c.defaultLocale, path)
+	}
+	return c, nil
+}
+
+// OnChange calls fn once a file of the catalog changed
+func (c *Catalog) OnChange(fn func(file string)) {
+	for _, vn := range c.locales {
+		vn.OnConfigChange(func(e fsnotify.Event) {
+			fn(e.Name)
+		})
+	}
+}
+
+// Set sets the text of key in locale
+func (c *Catalog) Set(locale, key, text string) {
+	locale = normalize(locale)
+	if _, ok := c.locales[locale]; !ok {
+		c.locales[locale] = viper.New()
+	}
+	c.locales[locale].Set(key, text)
+}
+
+// Get returns the text of key in the first of locales the catalog has it in, falling back to
+// the default locale, empty when no locale has it
+func (c *Catalog) Get(key string, locales ...string) string {
+	if c == nil {
+		return ""
+	}
+	for _, locale := range Chain(locales, c.defaultLocale) {
+		if vn, ok := c.locales[locale]; ok && vn.IsSet(key) {
+			return vn.GetString(key)
+		}
+	}
+	return ""
+}
+
+// Render executes the text/template of key with data, the template is chosen like Get
+func (c *Catalog) Render(key string, data interface{}, locales ...string) (string, error) {
+	text := c.Get(key, locales...)
+	if text == "" {
+		return "", fmt.Errorf("no template %s", key)
+	}
+	tmpl, err := template.New(key).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse template %s", key)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", errors.Wrapf(err, "execute template %s", key)
+	}
+	return b.String(), nil
+}
+
+// Chain returns the locales to try in order, every locale is followed by its language, e.g.
+// vi-VN by vi, and the chain ends with defaultLocale
+func Chain(locales []string, defaultLocale string) []string {
+	chain := []string{}
+	seen := map[string]bool{}
+	add := func(locale string) {
+		if locale != "" && locale != "*" && !seen[locale] {
+			seen[locale] = true
+			chain = append(chain, locale)
+		}
+	}
+	for _, locale := range locales {
+		locale = normalize(locale)
+		add(locale)
+		add(strings.Split(locale, "-")[0])
+	}
+	add(normalize(defaultLocale))
+	return chain
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header by preference, e.g.
+// [vi-vn vi en] for "en;q=0.5, vi-VN, vi;q=0.9"
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+	list := []weighted{}
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		locale := normalize(params[0])
+		if locale == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		list = append(list, weighted{locale: locale, q: q})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].q > list[j].q
+	})
+	locales := make([]string, 0, len(list))
+	for _, w := range list {
+		locales = append(locales, w.locale)
+	}
+	return locales
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the locales of the request by preference
+func NewContext(ctx context.Context, locales []string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locales)
+}
+
+// FromContext returns the locales carried by ctx, nil when there is none
+func FromContext(ctx context.Context) []string {
+	locales, _ := ctx.Value(contextKey{}).([]string)
+	return locales
+}
+
+func normalize(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -0,0 +1,60 @@
+package i18n
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header  string
+		locales []string
+	}{
+		{"", []string{}},
+		{"vi", []string{"vi"}},
+		{"en;q=0.5, vi-VN, vi;q=0.9", []string{"vi-vn", "vi", "en"}},
+		{"fr;q=0, *;q=0.1, en_US", []string{"en-us", "*"}},
+	}
+	for _, test := range tests {
+		if locales := ParseAcceptLanguage(test.header); !reflect.DeepEqual(locales, test.locales) {
+			t.Errorf("ParseAcceptLanguage(%q) = %v; expected %v", test.header, locales, test.locales)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	chain := Chain([]string{"vi-VN", "*", "en-GB", "vi"}, "en")
+	expected := []string{"vi-vn", "vi", "en-gb", "en"}
+	if !reflect.DeepEqual(chain, expected) {
+		t.Errorf("Chain = %v; expected %v", chain, expected)
+	}
+}
+
+func TestGet(t *testing.T) {
+	c := New(DefaultLocale)
+	c.Set("en", "error.request.message", "Try again later")
+	c.Set("en", "error.request.code", "102")
+	c.Set("vi", "error.request.message", "Vui lòng thử lại sau")
+
+	tests := []struct {
+		key     string
+		locales []string
+		text    string
+	}{
+		{"error.request.message", []string{"vi-VN"}, "Vui lòng thử lại sau"},
+		{"error.request.message", []string{"fr", "vi"}, "Vui lòng thử lại sau"},
+		{"error.request.message", []string{"fr"}, "Try again later"},
+		{"error.request.code", []string{"vi"}, "102"},
+		{"error.unknown.message", []string{"vi"}, ""},
+	}
+	for _, test := range tests {
+		if text := c.Get(test.key, test.locales...); text != test.text {
+			t.Errorf("Get(%s, %v) = %q; expected %q", test.key, test.locales, text, test.text)
+		}
+	}
+
+	c.Set("vi", "sms.code", "Ma cua ban la {{.code}}")
+	if text, err := c.Render("sms.code", map[string]string{"code": "123456"}, "vi"); err != nil || text != "Ma cua ban la 123456" {
+		t.Errorf("Render = %q, %v; expected %q", text, err, "Ma cua ban la 123456")
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -20,6 +21,8 @@ func GenToken(user types.UserFieldInToken, duration time.Duration) (string, erro
 		ID:    user.ID,
 		Email: user.Email,
 		Name:  user.Name,
+		Role:  user.Role,
+		MFA:   user.MFA,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(duration).Unix(),
 		},
@@ -27,7 +30,46 @@ func GenToken(user types.UserFieldInToken, duration time.Duration) (string, erro
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtKey)
 }
+
+// Generate token for a step of a login, e.g. the second factor, it isn't an access token
+func GenPurposeToken(id primitive.ObjectID, purpose string, duration time.Duration) (string, error) {
+	claims := &types.Claims{
+		ID:      id,
+		Purpose: purpose,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(duration).Unix(),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtKey)
+}
+
+// Authorize a token for a step of a login, it returns the id (hex) of the user
+func IsPurposeAuthorized(tokenpath, purpose string) (string, error) {
+	claims, err := parse(tokenpath)
+	if err != nil {
+		return "", err
+	}
+	id, _ := claims["_id"].(string)
+	if claims["purpose"] != purpose || id == "" {
+		return "", errors.New("Can't authorized token")
+	}
+	return id, nil
+}
+
+// Authorize an access token, tokens for a step of a login are rejected
 func IsAuthorized(tokenpath string) (map[string]interface{}, error) {
+	claims, err := parse(tokenpath)
+	if err != nil {
+		return nil, err
+	}
+	if purpose, ok := claims["purpose"]; ok && purpose != "" {
+		return nil, errors.New("Can't authorized token")
+	}
+	return claims, nil
+}
+
+func parse(tokenpath string) (map[string]interface{}, error) {
 
 	token, err := jwt.Parse(tokenpath, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
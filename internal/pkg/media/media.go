@@ -0,0 +1,33 @@
+// Package media checks the links of the media of the profiles, a media is served over https
+// by one of the configured media hosts
+package media
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Check checks a media is served over https by one of hosts
+func Check(u *url.URL, hosts []string) error {
+	if u.Scheme != "https" {
+		return errors.Errorf("media scheme %q not allowed", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range hosts {
+		if host == strings.ToLower(h) {
+			return nil
+		}
+	}
+	return errors.Errorf("media host %q not allowed", host)
+}
+
+// CheckLink checks the link of a media like Check
+func CheckLink(link string, hosts []string) error {
+	u, err := url.Parse(link)
+	if err != nil {
+		return errors.Wrapf(err, "media %q", link)
+	}
+	return Check(u, hosts)
+}
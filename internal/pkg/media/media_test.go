@@ -0,0 +1,24 @@
+package media
+
+import "testing"
+
+func TestCheckLink(t *testing.T) {
+	hosts := []string{"media.example.com"}
+	tests := []struct {
+		link string
+		ok   bool
+	}{
+		{"https://media.example.com/a.jpg", true},
+		{"https://MEDIA.example.com:443/a.jpg", true},
+		{"http://media.example.com/a.jpg", false},
+		{"https://evil.example.com/a.jpg", false},
+		{"https://media.example.com.evil.com/a.jpg", false},
+		{"file:///etc/passwd", false},
+		{"://", false},
+	}
+	for _, test := range tests {
+		if err := CheckLink(test.link, hosts); (err == nil) != test.ok {
+			t.Errorf("CheckLink(%q) = %v; expected ok %v", test.link, err, test.ok)
+		}
+	}
+}
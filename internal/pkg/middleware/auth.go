@@ -1,32 +1,91 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"time"
 
+	"dating/internal/app/api/types"
 	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
 	"dating/internal/pkg/auth"
+	"dating/internal/pkg/cache"
 	"dating/internal/pkg/glog"
+	"dating/internal/pkg/i18n"
 	"dating/internal/pkg/respond"
 )
 
-func Auth(h http.HandlerFunc, em *config.ErrorMessage) http.HandlerFunc {
+var (
+	errUnauthorized     = apperr.New(apperr.Unauthorized, "", "not authorized")
+	errAccountSuspended = apperr.NewForbidden("invalid_value.account_suspended", "account is suspended")
+	errAccountBanned    = apperr.NewForbidden("invalid_value.account_banned", "account is banned")
+)
+
+// AccountStatuses is an interface to find the status of an account, nil when the user doesn't exist
+type AccountStatuses interface {
+	FindStatus(ctx context.Context, idUser string) (*types.AccountStatus, error)
+}
+
+// Auth authenticates the token of the request and rejects suspended and banned accounts,
+// statuses are cached for ttl so a suspension or a role change applies to issued tokens within ttl
+func Auth(statuses AccountStatuses, ttl time.Duration) func(http.HandlerFunc, *config.ErrorMessage) http.HandlerFunc {
 	logger := glog.New().WithField("package", "middleware")
+	cached := cache.New(ttl)
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenpath := auth.ExtractToken(r)
-		if tokenpath == "" {
-			logger.Infof("The request does not contain token")
-			respond.JSON(w, http.StatusUnauthorized, &em.InvalidValue.FailedAuthentication)
-			return
+	findStatus := func(ctx context.Context, idUser string) (*types.AccountStatus, error) {
+		if status, ok := cached.Get(idUser); ok {
+			return status.(*types.AccountStatus), nil
 		}
-		_, err := auth.IsAuthorized(tokenpath)
-
+		status, err := statuses.FindStatus(ctx, idUser)
 		if err != nil {
-			logger.Errorf("Not authorized, error: ", err)
-			respond.JSON(w, http.StatusUnauthorized, &em.InvalidValue.FailedAuthentication)
-			return
+			return nil, err
 		}
+		cached.Set(idUser, status)
+		return status, nil
+	}
+
+	return func(h http.HandlerFunc, em *config.ErrorMessage) http.HandlerFunc {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenpath := auth.ExtractToken(r)
+			if tokenpath == "" {
+				logger.Infof("The request does not contain token")
+				respond.Fail(w, r, em, errUnauthorized)
+				return
+			}
+			claims, err := auth.IsAuthorized(tokenpath)
+
+			if err != nil {
+				logger.Errorf("Not authorized, error: %v", err)
+				respond.Fail(w, r, em, errUnauthorized)
+				return
+			}
+
+			idUser := auth.UserID(claims)
+			status, err := findStatus(r.Context(), idUser)
+			if err != nil {
+				logger.Errorf("Failed to find status of %s, error: %v", idUser, err)
+				respond.Fail(w, r, em, err)
+				return
+			}
+			if status == nil {
+				logger.Infof("The user %s of the token does not exist", idUser)
+				respond.Fail(w, r, em, errUnauthorized)
+				return
+			}
+			switch status.At(time.Now()) {
+			case types.StatusSuspended:
+				respond.Fail(w, r, em, errAccountSuspended)
+				return
+			case types.StatusBanned:
+				respond.Fail(w, r, em, errAccountBanned)
+				return
+			}
 
-		h.ServeHTTP(w, r)
-	})
+			ctx := auth.NewRoleContext(auth.NewContext(r.Context(), claims), status.Role)
+			if status.Locale != "" {
+				ctx = i18n.NewContext(ctx, append([]string{status.Locale}, i18n.FromContext(ctx)...))
+			}
+			h.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"dating/internal/pkg/i18n"
+)
+
+// Locale puts the locales of the Accept-Language header in the context of the request, Auth
+// puts the locale saved by the user before them
+func Locale(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locales := i18n.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+		r = r.WithContext(i18n.NewContext(r.Context(), locales))
+		h.ServeHTTP(w, r)
+	})
+}
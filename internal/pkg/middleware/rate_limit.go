@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/auth"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/ratelimit"
+	"dating/internal/pkg/respond"
+)
+
+var errTooManyRequests = apperr.New(apperr.TooManyRequests, "", "rate limit exceeded")
+
+// RateLimit limits requests of the route name to rate, keyed by the user id of the token
+// or by the client ip for anonymous requests, so it must run after Auth on protected routes.
+// A rate with a zero limit disables the limit
+func RateLimit(store ratelimit.Store, name string, rate ratelimit.Rate, trustProxy bool) func(http.HandlerFunc, *config.ErrorMessage) http.HandlerFunc {
+	logger := glog.New().WithField("package", "middleware")
+
+	return func(h http.HandlerFunc, em *config.ErrorMessage) http.HandlerFunc {
+		if rate.Limit <= 0 || rate.Per <= 0 {
+			return h
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := "ip:" + ClientIP(r, trustProxy)
+			if id := auth.UserIDFromContext(r.Context()); id != "" {
+				key = "user:" + id
+			}
+
+			result, err := store.Take(r.Context(), name+":"+key, rate)
+			if err != nil {
+				// fail open, an outage of the store must not take the api down
+				logger.Errorf("Failed to take rate limit token, err: %v", err)
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+				logger.Infof("Rate limit exceeded on %s by %s", name, key)
+				respond.Fail(w, r, em, errTooManyRequests)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns ip of the client, the first X-Forwarded-For entry is only trusted behind a proxy
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+
+	"dating/internal/app/api/types"
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/auth"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/respond"
+)
+
+var (
+	errPermissionDenied  = apperr.NewForbidden("", "permission denied")
+	errTwoFactorRequired = apperr.NewForbidden("invalid_value.two_factor_required", "two-factor authentication required")
+)
+
+// Moderator allows only moderators and admins, it must run after Auth
+func Moderator(h http.HandlerFunc, em *config.ErrorMessage) http.HandlerFunc {
+	return requireRole(h, em, types.RoleModerator, types.RoleAdmin)
+}
+
+// Admin allows only admins, it must run after Auth
+func Admin(h http.HandlerFunc, em *config.ErrorMessage) http.HandlerFunc {
+	return requireRole(h, em, types.RoleAdmin)
+}
+
+func requireRole(h http.HandlerFunc, em *config.ErrorMessage, roles ...string) http.HandlerFunc {
+	logger := glog.New().WithField("package", "middleware")
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := auth.RoleFromContext(r.Context())
+		for _, allowed := range roles {
+			if role == allowed {
+				h.ServeHTTP(w, r)
+				return
+			}
+		}
+		logger.Infof("Role %s is not allowed to %s %s", role, r.Method, r.URL.Path)
+		respond.Fail(w, r, em, errPermissionDenied)
+	})
+}
+
+// TwoFactor allows the enforced roles only with a login confirmed with a second factor, other
+// roles pass, it must run after Auth
+func TwoFactor(conf config.TwoFactor) func(http.HandlerFunc, *config.ErrorMessage) http.HandlerFunc {
+	logger := glog.New().WithField("package", "middleware")
+
+	return func(h http.HandlerFunc, em *config.ErrorMessage) http.HandlerFunc {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := auth.RoleFromContext(r.Context())
+			if conf.Enforced(role) && !auth.MFAFromContext(r.Context()) {
+				logger.Infof("Role %s needs two-factor authentication to %s %s", role, r.Method, r.URL.Path)
+				respond.Fail(w, r, em, errTwoFactorRequired)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"dating/internal/app/api/types"
+	"dating/internal/pkg/glog"
+	"dating/internal/pkg/socket"
+
+	"github.com/pkg/errors"
+)
+
+// Channel is an interface of a notification delivery channel
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, notification types.Notification) error
+}
+
+// Socket delivers notifications in real-time to the open socket connections of the recipient
+type Socket struct {
+	wsServer *socket.WsServer
+}
+
+// NewSocket returns a new socket channel
+func NewSocket(ws *socket.WsServer) *Socket {
+	return &Socket{wsServer: ws}
+}
+
+// Name of the channel
+func (c *Socket) Name() string {
+	return "socket"
+}
+
+// Send push the notification to the socket hub
+func (c *Socket) Send(ctx context.Context, notification types.Notification) error {
+	c.wsServer.NotifyUser(notification.UserID, &socket.MessageSocket{
+		Action:  notification.Type,
+		Payload: notification,
+	})
+	return nil
+}
+
+// Webhook delivers notifications by POSTing them as JSON to an URL
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns a new webhook channel
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	return &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name of the channel
+func (c *Webhook) Name() string {
+	return "webhook"
+}
+
+// Send post the notification to the webhook
+func (c *Webhook) Send(ctx context.Context, notification types.Notification) error {
+	b, err := json.Marshal(notification)
+	if err != nil {
+		return errors.Wrap(err, "json marshal failed")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Push is a stub of a mobile push provider, it only logs notifications
+// until a real provider (FCM, APNs,...) is plugged in
+type Push struct {
+	logger glog.Logger
+}
+
+// NewPush returns a new push channel
+func NewPush(l glog.Logger) *Push {
+	return &Push{logger: l}
+}
+
+// Name of the channel
+func (c *Push) Name() string {
+	return "push"
+}
+
+// Send log the notification
+func (c *Push) Send(ctx context.Context, notification types.Notification) error {
+	c.logger.Infof("push %s %q to user %s", notification.Type, notification.Title, notification.UserID.Hex())
+	return nil
+}
@@ -0,0 +1,342 @@
+// Package oidc is an OpenID Connect relying party, users login at a provider with the
+// authorization code flow protected by PKCE and the id token of the provider is verified
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrInvalidToken is returned when an id token isn't valid for the provider
+	ErrInvalidToken = errors.New("invalid id token")
+)
+
+// Config is the registration of the client at a provider
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	ResponseMode string // empty for the query, form_post when the provider posts the callback
+}
+
+// Claims are the claims of a verified id token identifying the user
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// discovery is the provider metadata served at /.well-known/openid-configuration
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider is an OpenID Connect provider, its metadata and keys are fetched on first use
+type Provider struct {
+	conf   Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *discovery
+	keys     map[string]interface{}
+}
+
+// New returns a provider, client is used to reach the provider
+func New(conf Config, client *http.Client) *Provider {
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{
+		conf:   conf,
+		client: client,
+	}
+}
+
+// AuthCodeURL returns the URL of the provider the user logins at, the provider redirects
+// back to the redirect url with the code and state
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.conf.ClientID},
+		"redirect_uri":          {p.conf.RedirectURL},
+		"scope":                 {strings.Join(p.conf.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	if p.conf.ResponseMode != "" {
+		query.Set("response_mode", p.conf.ResponseMode)
+	}
+	sep := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return metadata.AuthorizationEndpoint + sep + query.Encode(), nil
+}
+
+// Exchange trades the code of a callback for the id token of the user
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.conf.RedirectURL},
+		"client_id":     {p.conf.ClientID},
+		"client_secret": {p.conf.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := p.do(req, &token); err != nil {
+		return "", errors.Wrap(err, "token exchange failed")
+	}
+	if token.IDToken == "" {
+		return "", errors.New("token response without id token")
+	}
+	return token.IDToken, nil
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an id token
+func (p *Provider) Verify(ctx context.Context, raw, nonce string) (*Claims, error) {
+	token, err := jwt.Parse(raw, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, errors.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, err.Error())
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	switch {
+	case !claims.VerifyIssuer(p.conf.Issuer, true):
+		return nil, errors.Wrap(ErrInvalidToken, "issuer")
+	case !hasAudience(claims["aud"], p.conf.ClientID):
+		return nil, errors.Wrap(ErrInvalidToken, "audience")
+	case !claims.VerifyExpiresAt(time.Now().Unix(), true):
+		return nil, errors.Wrap(ErrInvalidToken, "expiry")
+	case claims["nonce"] != nonce:
+		return nil, errors.Wrap(ErrInvalidToken, "nonce")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.Wrap(ErrInvalidToken, "subject")
+	}
+	email, _ := claims["email"].(string)
+	name, _ := claims["name"].(string)
+	return &Claims{
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: isTrue(claims["email_verified"]),
+		Name:          name,
+	}, nil
+}
+
+// discover fetches the metadata of the provider, kept once fetched
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.conf.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var metadata discovery
+	if err := p.do(req, &metadata); err != nil {
+		return nil, errors.Wrap(err, "discovery failed")
+	}
+	if metadata.Issuer != p.conf.Issuer {
+		return nil, errors.Errorf("discovery issuer %s, expected %s", metadata.Issuer, p.conf.Issuer)
+	}
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+// key returns the signing key kid, the keys are fetched again when kid is unknown as
+// providers rotate them
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadata.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.do(req, &set); err != nil {
+		return nil, errors.Wrap(err, "keys fetch failed")
+	}
+	keys := map[string]interface{}{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, errors.Errorf("unknown key %q", kid)
+}
+
+func (p *Provider) do(req *http.Request, v interface{}) error {
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %d", req.URL.Path, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// jsonWebKey is a public key of a JWK set, RSA or elliptic curve
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, errors.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, errors.Errorf("unsupported key type %s", k.Kty)
+}
+
+// hasAudience tells if the aud claim, a string or a list, holds clientID
+func hasAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isTrue reads a boolean claim, some providers send it as a string
+func isTrue(claim interface{}) bool {
+	switch claim := claim.(type) {
+	case bool:
+		return claim
+	case string:
+		return claim == "true"
+	}
+	return false
+}
+
+// NewRandom returns a random url safe string, e.g. a state, a nonce or a PKCE verifier
+func NewRandom() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Challenge is the S256 PKCE challenge of verifier
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,105 @@
+package oidc_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"dating/internal/pkg/oidc"
+	"dating/internal/pkg/oidc/oidctest"
+
+	"github.com/pkg/errors"
+)
+
+func TestCodeFlow(t *testing.T) {
+	issuer, err := oidctest.NewIssuer("dating", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer issuer.Close()
+	issuer.SignIn(oidctest.User{Subject: "42", Email: "lan@example.com", EmailVerified: true, Name: "Lan"})
+
+	ctx := context.Background()
+	p := oidc.New(issuer.Config("https://dating.example.com/auth/mock/callback"), http.DefaultClient)
+	authURL, err := p.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback := authorize(t, authURL)
+	if callback.Get("state") != "state" {
+		t.Fatalf("state = %q; expected %q", callback.Get("state"), "state")
+	}
+
+	if _, err := p.Exchange(ctx, callback.Get("code"), "other verifier"); err == nil {
+		t.Fatal("Exchange with another verifier succeeded")
+	}
+	callback = authorize(t, authURL)
+	idToken, err := p.Exchange(ctx, callback.Get("code"), "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := p.Verify(ctx, idToken, "nonce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := oidc.Claims{Subject: "42", Email: "lan@example.com", EmailVerified: true, Name: "Lan"}
+	if *claims != expected {
+		t.Errorf("claims = %+v; expected %+v", *claims, expected)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	issuer, err := oidctest.NewIssuer("dating", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer issuer.Close()
+	other, err := oidctest.NewIssuer("dating", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	user := oidctest.User{Subject: "42"}
+	p := oidc.New(issuer.Config("https://dating.example.com/auth/mock/callback"), http.DefaultClient)
+	cases := []struct {
+		name   string
+		issuer *oidctest.Issuer
+		aud    string
+		nonce  string
+		ttl    time.Duration
+	}{
+		{name: "other nonce", issuer: issuer, aud: "dating", nonce: "other", ttl: time.Hour},
+		{name: "other audience", issuer: issuer, aud: "other", nonce: "nonce", ttl: time.Hour},
+		{name: "expired", issuer: issuer, aud: "dating", nonce: "nonce", ttl: -time.Minute},
+		{name: "other issuer", issuer: other, aud: "dating", nonce: "nonce", ttl: time.Hour},
+	}
+	for _, c := range cases {
+		idToken, err := c.issuer.IDToken(user, c.aud, c.nonce, c.ttl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Verify(context.Background(), idToken, "nonce"); errors.Cause(err) != oidc.ErrInvalidToken {
+			t.Errorf("%s: err = %v; expected %v", c.name, err, oidc.ErrInvalidToken)
+		}
+	}
+}
+
+// authorize follows the authorization URL and returns the query of the callback
+func authorize(t *testing.T, authURL string) url.Values {
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	res, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	location, err := res.Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return location.Query()
+}
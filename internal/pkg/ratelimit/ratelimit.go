@@ -0,0 +1,163 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Rate is a token bucket of Limit tokens refilled at Limit tokens per Per
+type Rate struct {
+	Limit int           `mapstructure:"limit"`
+	Per   time.Duration `mapstructure:"per"`
+}
+
+// Result is the state of a bucket after a take
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next token, zero when allowed
+}
+
+// Store is an interface of a token bucket store
+type Store interface {
+	Take(ctx context.Context, key string, rate Rate) (Result, error)
+}
+
+// take applies a take on a bucket holding tokens, elapsed since its last update
+func take(tokens float64, elapsed time.Duration, rate Rate) (float64, Result) {
+	perToken := float64(rate.Per) / float64(rate.Limit)
+	tokens = math.Min(float64(rate.Limit), tokens+float64(elapsed)/perToken)
+
+	result := Result{Limit: rate.Limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+	result.Remaining = int(tokens)
+	result.Reset = time.Duration((float64(rate.Limit) - tokens) * perToken)
+	return tokens, result
+}
+
+// Memory is an in-memory store, buckets are local to the process
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	per    time.Duration
+}
+
+// NewMemory returns a new in-memory store
+func NewMemory() *Memory {
+	return &Memory{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take takes a token from the bucket of key
+func (m *Memory) Take(ctx context.Context, key string, rate Rate) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rate.Limit), last: now, per: rate.Per}
+		m.buckets[key] = b
+	}
+	var result Result
+	b.tokens, result = take(b.tokens, now.Sub(b.last), rate)
+	b.last = now
+	return result, nil
+}
+
+// sweep drops buckets which are full again, at most once a minute
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if now.Sub(b.last) >= b.per {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+// takeScript is the token bucket in redis, KEYS[1] is the bucket,
+// ARGV are limit, milliseconds per token and now in milliseconds.
+// It returns allowed (0/1) and the tokens left as a string
+var takeScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local per_token = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(bucket[1]) or limit
+local last = tonumber(bucket[2]) or now
+tokens = math.min(limit, tokens + math.max(0, now - last) / per_token)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(limit * per_token))
+return {allowed, tostring(tokens)}
+`)
+
+// Redis is a store shared by every replica
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis returns a new redis store, keys are prefixed with prefix
+func NewRedis(client *redis.Client, prefix string) *Redis {
+	return &Redis{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Take takes a token from the bucket of key
+func (r *Redis) Take(ctx context.Context, key string, rate Rate) (Result, error) {
+	perToken := float64(rate.Per.Milliseconds()) / float64(rate.Limit)
+	values, err := takeScript.Run(ctx, r.client, []string{r.prefix + key},
+		rate.Limit, perToken, time.Now().UnixNano()/int64(time.Millisecond)).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	tokens := 0.0
+	if s, ok := values[1].(string); ok {
+		tokens, _ = strconv.ParseFloat(s, 64)
+	}
+
+	result := Result{
+		Allowed:   allowed == 1,
+		Limit:     rate.Limit,
+		Remaining: int(tokens),
+		Reset:     time.Duration((float64(rate.Limit) - tokens) * perToken * float64(time.Millisecond)),
+	}
+	if !result.Allowed {
+		result.RetryAfter = time.Duration((1 - tokens) * perToken * float64(time.Millisecond))
+	}
+	return result, nil
+}
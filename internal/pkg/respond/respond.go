@@ -4,9 +4,24 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"dating/internal/app/config"
+	"dating/internal/pkg/apperr"
+	"dating/internal/pkg/i18n"
+
 	"github.com/pkg/errors"
 )
 
+// requestIDKey is the context key of the request id set by middleware.RequestID
+const requestIDKey = "request_id"
+
+// ErrorResponse is the envelope of every error, Code and Message come from configs/errors.<locale>.yml
+type ErrorResponse struct {
+	Code      string              `json:"code"`
+	Message   string              `json:"message"`
+	RequestID string              `json:"request_id,omitempty"`
+	Fields    []apperr.FieldError `json:"fields,omitempty"` // invalid fields of the request
+}
+
 // JSON write status and JSON data to http response writer
 func JSON(w http.ResponseWriter, status int, data interface{}) {
 	b, err := json.Marshal(data)
@@ -23,3 +38,19 @@ func JSON(w http.ResponseWriter, status int, data interface{}) {
 func Error(w http.ResponseWriter, err error, status int) {
 	http.Error(w, err.Error(), status)
 }
+
+// Fail writes err in the error envelope, the status and the code are the ones of the typed
+// error behind err, see apperr.From. The message is in the language of the request
+func Fail(w http.ResponseWriter, r *http.Request, em *config.ErrorMessage, err error) {
+	e := apperr.From(err)
+	code := em.LocalizedErrorCode(e.Key, i18n.FromContext(r.Context())...)
+	res := ErrorResponse{
+		Code:    code.Code,
+		Message: code.Message,
+		Fields:  e.Fields,
+	}
+	if requestID, ok := r.Context().Value(requestIDKey).(string); ok {
+		res.RequestID = requestID
+	}
+	JSON(w, e.Status(), res)
+}
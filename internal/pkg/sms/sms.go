@@ -0,0 +1,56 @@
+// Package sms sends text messages, the stubs here write them out for local runs instead of
+// reaching a gateway
+package sms
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"dating/internal/pkg/glog"
+)
+
+// Log writes the messages to the log
+type Log struct {
+	logger glog.Logger
+}
+
+// NewLog returns a provider writing the messages to the log
+func NewLog(l glog.Logger) *Log {
+	return &Log{logger: l}
+}
+
+// Send logs the message
+func (p *Log) Send(ctx context.Context, to, message string) error {
+	p.logger.Infof("SMS to %s: %s", to, message)
+	return nil
+}
+
+// File appends the messages to a file, one per line
+type File struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFile returns a provider appending the messages to the file at path
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Send appends the message to the file
+func (p *File) Send(ctx context.Context, to, message string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), to, message); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
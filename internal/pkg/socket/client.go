@@ -1,7 +1,12 @@
 package socket
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"dating/internal/app/api/types"
+	"dating/internal/pkg/chatfilter"
 	"dating/internal/pkg/glog"
 
 	"github.com/gorilla/websocket"
@@ -10,21 +15,26 @@ import (
 
 type Client struct {
 	ID       primitive.ObjectID
+	UserID   primitive.ObjectID // user of the token of the connection
 	RoomId   primitive.ObjectID
 	wsServer *WsServer
 	conn     *websocket.Conn
 	send     chan *MessageSocket
+	done     chan struct{} // closed once the connection ends, stops Write
+	once     sync.Once
 	save     *chan SaveMessage
 	rooms    map[*RoomSocket]bool
 }
 
-func NewClient(conn *websocket.Conn, wsServer *WsServer, idRoom primitive.ObjectID, sm *chan SaveMessage) *Client {
+func NewClient(conn *websocket.Conn, wsServer *WsServer, idUser, idRoom primitive.ObjectID, sm *chan SaveMessage) *Client {
 	return &Client{
 		ID:       primitive.NewObjectID(),
+		UserID:   idUser,
 		RoomId:   idRoom,
 		conn:     conn,
 		wsServer: wsServer,
 		send:     make(chan *MessageSocket),
+		done:     make(chan struct{}),
 		rooms:    make(map[*RoomSocket]bool),
 		save:     sm,
 	}
@@ -32,7 +42,9 @@ func NewClient(conn *websocket.Conn, wsServer *WsServer, idRoom primitive.Object
 }
 
 func (c *Client) Read(logger glog.Logger) {
-	defer c.conn.Close()
+	defer func() {
+		c.wsServer.Unregister <- c
+	}()
 	for {
 		var mgs *MessageSocket
 		err := c.conn.ReadJSON(&mgs)
@@ -45,16 +57,39 @@ func (c *Client) Read(logger glog.Logger) {
 }
 
 func (c *Client) Write(logger glog.Logger) {
-	defer c.conn.Close()
-	for msg := range c.send {
-		err := c.conn.WriteJSON(msg)
-		if err != nil {
-			logger.Errorf("Failed when write message from room %d, client %d", c.RoomId, c.ID, msg, err)
+	defer c.close()
+	for {
+		select {
+		case msg := <-c.send:
+			err := c.conn.WriteJSON(msg)
+			if err != nil {
+				logger.Errorf("Failed when write message from room %d, client %d", c.RoomId, c.ID, msg, err)
+				return
+			}
+		case <-c.done:
 			return
 		}
 	}
 }
 
+// close ends the connection and stops Write, it can be called more than once
+func (c *Client) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// deliver sends a message to Write, it returns false once the connection ended
+func (c *Client) deliver(msg *MessageSocket) bool {
+	select {
+	case c.send <- msg:
+		return true
+	case <-c.done:
+		return false
+	}
+}
+
 func (client *Client) handleNewMessage(jsonMessage *MessageSocket) {
 	roomID := client.RoomId
 	jsonMessage.RoomID = roomID
@@ -65,8 +100,18 @@ func (client *Client) handleNewMessage(jsonMessage *MessageSocket) {
 		if room := client.wsServer.findRoomByID(roomID); room != nil {
 
 			jsonMessage.ID = primitive.NewObjectID()
+			// a client can only send as the user of its token
+			jsonMessage.SenderID = client.UserID
+			if !client.filterMessage(jsonMessage) {
+				return
+			}
 
-			room.broadcast <- jsonMessage
+			// the room may be closed meanwhile
+			select {
+			case room.broadcast <- jsonMessage:
+			case <-room.stop:
+				return
+			}
 			sm := &SaveMessage{
 				message: &types.Message{
 					ID:          jsonMessage.ID,
@@ -89,6 +134,34 @@ func (client *Client) handleNewMessage(jsonMessage *MessageSocket) {
 
 }
 
+// filterMessage runs the filters of the hub on the message, content may be masked.
+// It returns false and sends an error frame back to the sender when the message is rejected
+func (client *Client) filterMessage(jsonMessage *MessageSocket) bool {
+	if client.wsServer.filter == nil {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := client.wsServer.filter.Run(ctx, &jsonMessage.Message)
+	if err != nil {
+		glog.New().WithField("package", "socket-client").Errorf("Failed when filter message %v", err)
+		client.deliver(&MessageSocket{
+			Action:  ErrorAction,
+			Payload: ErrorFrame{Code: "message_failed", Message: "message could not be sent, please try again"},
+		})
+		return false
+	}
+	if result.Verdict == chatfilter.Reject {
+		client.deliver(&MessageSocket{
+			Action:  ErrorAction,
+			Payload: ErrorFrame{Code: "message_rejected", Message: result.Reason},
+		})
+		return false
+	}
+	return true
+}
+
 func (client *Client) handleLeaveRoomMessage(message MessageSocket) {
 	room := client.wsServer.findRoomByID(message.RoomID)
 	if room == nil {
@@ -99,7 +172,10 @@ func (client *Client) handleLeaveRoomMessage(message MessageSocket) {
 		delete(client.rooms, room)
 	}
 
-	room.unregister <- client
+	select {
+	case room.unregister <- client:
+	case <-room.stop:
+	}
 }
 func (client *Client) handleJoinRoomMessage(message MessageSocket) {
 	roomID := message.RoomID
@@ -122,7 +198,10 @@ func (client *Client) joinRoom(roomID primitive.ObjectID, sender *Client) {
 	if !client.isInRoom(room) {
 
 		client.rooms[room] = true
-		room.register <- client
+		select {
+		case room.register <- client:
+		case <-room.stop:
+		}
 
 	}
 
@@ -8,7 +8,7 @@ import (
 )
 
 type SaveMessage struct {
-	message *types.Message `json:"message"`
+	message *types.Message
 }
 type Repository interface {
 	Insert(ctx context.Context, message types.Message) error
@@ -1,12 +1,30 @@
 package socket
 
-import "dating/internal/app/api/types"
+import (
+	"dating/internal/app/api/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 const SendMessageAction = "send-message"
 const JoinRoomAction = "join-room"
 const LeaveRoomAction = "leave-room"
+const ErrorAction = "error"
 
 type MessageSocket struct {
 	Action string `json:"action"`
 	types.Message
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+// ErrorFrame is the payload of an error message sent back to the sender
+type ErrorFrame struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// UserMessage is a message delivered to every connection of a user
+type UserMessage struct {
+	UserID  primitive.ObjectID
+	Message *MessageSocket
 }
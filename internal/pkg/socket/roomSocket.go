@@ -10,6 +10,7 @@ type RoomSocket struct {
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan *MessageSocket
+	stop       chan struct{}
 	Private    bool `json:"private"`
 }
 
@@ -21,6 +22,7 @@ func NewRoom(id primitive.ObjectID, private bool) *RoomSocket {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan *MessageSocket),
+		stop:       make(chan struct{}),
 		Private:    private,
 	}
 }
@@ -38,6 +40,10 @@ func (room *RoomSocket) RunRoomSocket() {
 
 		case message := <-room.broadcast:
 			room.broadcastToClientsInRoom(message)
+
+		case <-room.stop:
+			room.closeClientsInRoom()
+			return
 		}
 
 	}
@@ -55,7 +61,15 @@ func (room *RoomSocket) unregisterClientInRoom(client *Client) {
 
 func (room *RoomSocket) broadcastToClientsInRoom(message *MessageSocket) {
 	for client := range room.clients {
-		client.send <- message
+		client.deliver(message)
+	}
+}
+
+// closeClientsInRoom force-closes the connection of every client in the room
+func (room *RoomSocket) closeClientsInRoom() {
+	for client := range room.clients {
+		client.close()
+		delete(room.clients, client)
 	}
 }
 
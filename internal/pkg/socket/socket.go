@@ -1,24 +1,45 @@
 package socket
 
 import (
+	"context"
+	"sync"
+
+	"dating/internal/app/api/types"
+	"dating/internal/pkg/chatfilter"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// MessageFilter is an interface of the filters run on a message before broadcast
+type MessageFilter interface {
+	Run(ctx context.Context, message *types.Message) (chatfilter.Result, error)
+}
+
 type WsServer struct {
 	Clients    map[*Client]bool
 	Register   chan *Client
 	Unregister chan *Client
 	Broadcast  chan *MessageSocket
 	Rooms      map[*RoomSocket]bool
+	roomsMu    sync.Mutex // Rooms is shared by the hub and the client goroutines
+	notify     chan *UserMessage
+	closeRoom  chan primitive.ObjectID
+	disconnect chan primitive.ObjectID
+	filter     MessageFilter
 }
 
-func NewWebsocketServer() *WsServer {
+// NewWebsocketServer returns a new hub, filter is run on every message before broadcast
+func NewWebsocketServer(filter MessageFilter) *WsServer {
 	return &WsServer{
 		Clients:    make(map[*Client]bool),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 		Broadcast:  make(chan *MessageSocket),
 		Rooms:      make(map[*RoomSocket]bool),
+		notify:     make(chan *UserMessage, 256),
+		closeRoom:  make(chan primitive.ObjectID),
+		disconnect: make(chan primitive.ObjectID),
+		filter:     filter,
 	}
 }
 
@@ -34,12 +55,27 @@ func (server *WsServer) Run() {
 
 		case message := <-server.Broadcast:
 			server.broadcastToClients(message)
+
+		case message := <-server.notify:
+			server.notifyClientsOfUser(message)
+
+		case id := <-server.closeRoom:
+			server.deleteRoom(id)
+
+		case userID := <-server.disconnect:
+			server.disconnectClientsOfUser(userID)
 		}
 
 	}
 }
 
 func (server *WsServer) registerClient(client *Client) {
+	// the connection may have ended before it registered
+	select {
+	case <-client.done:
+		return
+	default:
+	}
 	server.Clients[client] = true
 }
 
@@ -47,15 +83,79 @@ func (server *WsServer) unregisterClient(client *Client) {
 	if _, ok := server.Clients[client]; ok {
 		delete(server.Clients, client)
 	}
+	client.close()
 }
 
 func (server *WsServer) broadcastToClients(messageSocket *MessageSocket) {
 	for client := range server.Clients {
-		client.send <- messageSocket
+		client.deliver(messageSocket)
+	}
+}
+
+// NotifyUser sends a message to every connection of the user
+func (server *WsServer) NotifyUser(userID primitive.ObjectID, message *MessageSocket) {
+	server.notify <- &UserMessage{UserID: userID, Message: message}
+}
+
+func (server *WsServer) notifyClientsOfUser(message *UserMessage) {
+	for client := range server.Clients {
+		if client.UserID != message.UserID {
+			continue
+		}
+		// never block the hub on a client that stopped reading
+		select {
+		case client.send <- message.Message:
+		default:
+		}
+	}
+}
+
+// CloseRoom closes the room and the connections of its clients, e.g. when a match ended
+func (server *WsServer) CloseRoom(id primitive.ObjectID) {
+	server.closeRoom <- id
+}
+
+func (server *WsServer) deleteRoom(id primitive.ObjectID) {
+	server.roomsMu.Lock()
+	room := server.findRoomLocked(id)
+	if room != nil {
+		delete(server.Rooms, room)
+	}
+	server.roomsMu.Unlock()
+
+	for client := range server.Clients {
+		if client.RoomId == id {
+			client.close()
+			delete(server.Clients, client)
+		}
+	}
+	if room != nil {
+		close(room.stop)
+	}
+}
+
+// DisconnectUser closes every connection of the user, e.g. when the account is suspended
+func (server *WsServer) DisconnectUser(userID primitive.ObjectID) {
+	server.disconnect <- userID
+}
+
+func (server *WsServer) disconnectClientsOfUser(userID primitive.ObjectID) {
+	for client := range server.Clients {
+		if client.UserID == userID {
+			client.close()
+			delete(server.Clients, client)
+		}
 	}
 }
 
 func (server *WsServer) findRoomByID(ID primitive.ObjectID) *RoomSocket {
+	server.roomsMu.Lock()
+	defer server.roomsMu.Unlock()
+	return server.findRoomLocked(ID)
+}
+
+// findRoomLocked finds a room, roomsMu must be held
+func (server *WsServer) findRoomLocked(ID primitive.ObjectID) *RoomSocket {
 	var foundRoom *RoomSocket
 
 	for room := range server.Rooms {
@@ -68,7 +168,13 @@ func (server *WsServer) findRoomByID(ID primitive.ObjectID) *RoomSocket {
 	return foundRoom
 }
 
+// createRoom returns the room of id, created unless another client created it meanwhile
 func (server *WsServer) createRoom(id primitive.ObjectID, private bool) *RoomSocket {
+	server.roomsMu.Lock()
+	defer server.roomsMu.Unlock()
+	if room := server.findRoomLocked(id); room != nil {
+		return room
+	}
 	room := NewRoom(id, private)
 	go room.RunRoomSocket()
 	server.Rooms[room] = true
@@ -0,0 +1,48 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// secret is the SHA1 secret of the RFC 6238 test vectors
+var secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// the last 6 digits of the 8 digits RFC 6238 vectors
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, test := range tests {
+		code, err := Code(secret, Step(time.Unix(test.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != test.code {
+			t.Errorf("Code at %d = %s; expected %s", test.unix, code, test.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	code, _ := Code(secret, Step(now)-1)
+
+	if step, ok := Validate(secret, code, now, 1); !ok || step != Step(now)-1 {
+		t.Errorf("Validate of the previous step = %d, %v; expected %d, true", step, ok, Step(now)-1)
+	}
+	if _, ok := Validate(secret, code, now, 0); ok {
+		t.Errorf("Validate of the previous step without skew = true; expected false")
+	}
+	if _, ok := Validate(secret, "12345", now, 1); ok {
+		t.Errorf("Validate of a short code = true; expected false")
+	}
+}
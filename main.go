@@ -13,6 +13,7 @@ import (
 	envconfig "dating/internal/pkg/config/env"
 	"dating/internal/pkg/glog"
 	"dating/internal/pkg/health"
+	"dating/internal/pkg/i18n"
 )
 
 func main() {
@@ -26,6 +27,12 @@ func main() {
 		logger.Errorf("failed to load error messages, err: %v", err)
 	}
 
+	// texts of the notifications and the text messages
+	templates, err := i18n.Load(*configPath, "templates", i18n.DefaultLocale)
+	if err != nil {
+		logger.Errorf("failed to load templates, err: %v", err)
+	}
+
 	// configs
 	conf, err := config.New(*configPath, *stage)
 	if err != nil {
@@ -43,7 +50,7 @@ func main() {
 		conf.Database.Mongo.Database = mongoConf.Database
 	}
 	logger.Infof("initializing HTTP routing...")
-	router, err := api.Init(conf, em)
+	router, err := api.Init(conf, em, templates)
 	if err != nil {
 		logger.Panicf("failed to init routing, err: %v", err)
 	}